@@ -0,0 +1,158 @@
+// Package baseline keeps named reference runs, e.g. "mesa-24-llvmpipe", and gates new runs
+// against them with a tolerance per test.
+package baseline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"moddergltest/rundoc"
+)
+
+// Tolerances used when a baseline does not set its own
+const (
+	DefaultScoreTolerance = 5.0  // percent the score may drop
+	DefaultP99Tolerance   = 10.0 // percent the p99 frame time may rise
+)
+
+// Tolerance is how much worse than the baseline a test may get, in percent
+type Tolerance struct {
+	ScorePct        float64 `json:"score_pct"`
+	P99FrameTimePct float64 `json:"p99_frame_time_pct"`
+}
+
+// Baseline is a reference run under a name
+type Baseline struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	// Applies to every test without an entry in Tests, and to the total score
+	Tolerance Tolerance            `json:"tolerance"`
+	Tests     map[string]Tolerance `json:"tests,omitempty"`
+	Document  *rundoc.Document     `json:"document"`
+}
+
+// Store is a directory with one <name>.json file per baseline
+type Store struct {
+	Dir string
+}
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// New makes a baseline of a run with the default tolerances
+func New(name string, doc *rundoc.Document) (*Baseline, error) {
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid baseline name %q, use letters, digits, '.', '_' and '-'", name)
+	}
+	return &Baseline{
+		Name:      name,
+		CreatedAt: time.Now(),
+		Tolerance: Tolerance{ScorePct: DefaultScoreTolerance, P99FrameTimePct: DefaultP99Tolerance},
+		Document:  doc,
+	}, nil
+}
+
+// ToleranceFor is the tolerance of a test, or the baseline default
+func (b *Baseline) ToleranceFor(test string) Tolerance {
+	if tolerance, ok := b.Tests[test]; ok {
+		return tolerance
+	}
+	return b.Tolerance
+}
+
+// SetTolerance overrides the tolerance of one test, "default" changes the baseline default
+func (b *Baseline) SetTolerance(test string, tolerance Tolerance) {
+	if test == "default" {
+		b.Tolerance = tolerance
+		return
+	}
+	if b.Tests == nil {
+		b.Tests = make(map[string]Tolerance)
+	}
+	b.Tests[test] = tolerance
+}
+
+// Default is the store in the user config directory
+func Default() (*Store, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Store{Dir: filepath.Join(dir, "GLTest", "baselines")}, nil
+}
+
+// Save stores a baseline, replacing one with the same name
+func (s *Store) Save(b *Baseline) error {
+	if !namePattern.MatchString(b.Name) {
+		return fmt.Errorf("invalid baseline name %q", b.Name)
+	}
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := s.path(b.Name) + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path(b.Name))
+}
+
+// Load reads a baseline by name
+func (s *Store) Load(name string) (*Baseline, error) {
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid baseline name %q", name)
+	}
+	data, err := os.ReadFile(s.path(name))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("baseline %q not found", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	b := &Baseline{}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("Error reading baseline %s: %v", name, err)
+	}
+	if b.Document == nil {
+		return nil, fmt.Errorf("baseline %s has no run", name)
+	}
+	return b, nil
+}
+
+// List returns the names of the stored baselines, sorted
+func (s *Store) List() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(s.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(paths))
+	for _, path := range paths {
+		names = append(names, strings.TrimSuffix(filepath.Base(path), ".json"))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Delete removes a baseline
+func (s *Store) Delete(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid baseline name %q", name)
+	}
+	err := os.Remove(s.path(name))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("baseline %q not found", name)
+	}
+	return err
+}
+
+func (s *Store) path(name string) string {
+	return filepath.Join(s.Dir, name+".json")
+}
@@ -0,0 +1,143 @@
+package baseline
+
+import (
+	"fmt"
+	"math"
+
+	"moddergltest/rundoc"
+)
+
+// Metrics checked by the gate
+const (
+	MetricScore        = "score"
+	MetricP99FrameTime = "p99_frame_time_ms"
+)
+
+// Check is one metric of one test against the baseline. Score may drop and frame time may
+// rise by at most the tolerance.
+type Check struct {
+	Test         string  `json:"test"`
+	Metric       string  `json:"metric"`
+	Baseline     float64 `json:"baseline"`
+	Value        float64 `json:"value"`
+	ChangePct    float64 `json:"change_pct"`
+	TolerancePct float64 `json:"tolerance_pct"`
+	Missing      bool    `json:"missing,omitempty"`    // the test is not in the run
+	Incomplete   string  `json:"incomplete,omitempty"` // the test stopped early in the run, which fails the check
+	Passed       bool    `json:"passed"`
+}
+
+// Report is the outcome of gating a run against a baseline
+type Report struct {
+	Baseline string           `json:"baseline"`
+	Run      *rundoc.Document `json:"-"`
+	RunID    string           `json:"run_id"`
+	Checks   []Check          `json:"checks"`
+	NewTests []string         `json:"new_tests,omitempty"` // in the run but not in the baseline, not gated
+}
+
+// Gate checks the total score and the score and p99 frame time of every test of the baseline.
+// Repeated runs are compared by their mean scores.
+func Gate(b *Baseline, doc *rundoc.Document) *Report {
+	report := &Report{Baseline: b.Name, Run: doc, RunID: doc.RunID}
+	base := b.Document
+
+	baseTotal, _ := base.Score(rundoc.TotalScoreKey)
+	total, _ := doc.Score(rundoc.TotalScoreKey)
+	report.Checks = append(report.Checks, scoreCheck(rundoc.TotalScoreKey, baseTotal, total, b.Tolerance.ScorePct))
+
+	for _, name := range base.TestNames() {
+		tolerance := b.ToleranceFor(name)
+		baseScore, _ := base.Score(name)
+		score, ok := doc.Score(name)
+		if !ok {
+			report.Checks = append(report.Checks, Check{
+				Test: name, Metric: MetricScore, Baseline: baseScore, TolerancePct: tolerance.ScorePct, Missing: true,
+			})
+			continue
+		}
+		check := scoreCheck(name, baseScore, score, tolerance.ScorePct)
+		test := doc.Tests[name]
+		if test != nil && test.Incomplete != "" {
+			check.Incomplete = test.Incomplete
+			check.Passed = false
+		}
+		report.Checks = append(report.Checks, check)
+
+		// Tests recorded without any frame time cannot be gated on it
+		baseP99 := base.Tests[name].P99FrameTimeMs()
+		if baseP99 <= 0 || test == nil {
+			continue
+		}
+		p99 := test.P99FrameTimeMs()
+		change := changePct(baseP99, p99)
+		report.Checks = append(report.Checks, Check{
+			Test:         name,
+			Metric:       MetricP99FrameTime,
+			Baseline:     baseP99,
+			Value:        p99,
+			ChangePct:    change,
+			TolerancePct: tolerance.P99FrameTimePct,
+			Passed:       p99 > 0 && change <= tolerance.P99FrameTimePct,
+		})
+	}
+
+	for _, name := range doc.TestNames() {
+		if _, ok := base.Tests[name]; !ok {
+			report.NewTests = append(report.NewTests, name)
+		}
+	}
+	return report
+}
+
+// Passed is true when every check passed
+func (r *Report) Passed() bool {
+	return len(r.Failures()) == 0
+}
+
+// Failures are the checks that did not pass
+func (r *Report) Failures() []Check {
+	var failures []Check
+	for _, check := range r.Checks {
+		if !check.Passed {
+			failures = append(failures, check)
+		}
+	}
+	return failures
+}
+
+// Describe explains a check in one line
+func (c Check) Describe() string {
+	if c.Missing {
+		return fmt.Sprintf("%s is missing from the run", c.Test)
+	}
+	if c.Incomplete != "" {
+		return fmt.Sprintf("%s did not run to its end (%s), score %.2f -> %.2f", c.Test, c.Incomplete, c.Baseline, c.Value)
+	}
+	direction := "drop"
+	if c.Metric == MetricP99FrameTime {
+		direction = "rise"
+	}
+	return fmt.Sprintf("%s %s %.2f -> %.2f (%+.1f%%, allowed %s %.1f%%)",
+		c.Test, c.Metric, c.Baseline, c.Value, c.ChangePct, direction, c.TolerancePct)
+}
+
+func scoreCheck(test string, baseScore, score, tolerance float64) Check {
+	change := changePct(baseScore, score)
+	return Check{
+		Test:         test,
+		Metric:       MetricScore,
+		Baseline:     baseScore,
+		Value:        score,
+		ChangePct:    change,
+		TolerancePct: tolerance,
+		Passed:       change >= -tolerance,
+	}
+}
+
+func changePct(base, value float64) float64 {
+	if base == 0 {
+		return 0
+	}
+	return (value - base) / math.Abs(base) * 100
+}
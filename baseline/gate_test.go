@@ -0,0 +1,148 @@
+package baseline
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"moddergltest/rundoc"
+)
+
+// A run of ocean and shadows; the slowest frame of every sample sets the p99 frame time
+func gateRun(oceanScore, oceanMinFps, shadowsScore float64) *rundoc.Document {
+	test := func(score, minFps float64) *rundoc.TestResult {
+		result := &rundoc.TestResult{Kind: rundoc.KindCore, Score: score}
+		for i := 0; i < 4; i++ {
+			result.Samples = append(result.Samples, rundoc.Sample{Stage: 1, AvgFps: minFps * 1.2, MinFps: minFps})
+		}
+		return result
+	}
+	return &rundoc.Document{
+		RunID:      "6ba7b810-9dad-41d1-80b4-00c04fd430c8",
+		CreatedAt:  time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+		System:     rundoc.System{GpuName: "Radeon RX 7600", DriverVersion: "24.9.1"},
+		TotalScore: 100,
+		Tests: map[string]*rundoc.TestResult{
+			"ocean":   test(oceanScore, oceanMinFps),
+			"shadows": test(shadowsScore, 50),
+		},
+	}
+}
+
+// The baseline: ocean at 50 points and 10 ms, shadows at 40 points with a looser 20% score tolerance
+func gateBaseline(t *testing.T) *Baseline {
+	t.Helper()
+	b, err := New("mesa-24-llvmpipe", gateRun(50, 100, 40))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.SetTolerance("shadows", Tolerance{ScorePct: 20, P99FrameTimePct: DefaultP99Tolerance})
+	return b
+}
+
+func findCheck(report *Report, test, metric string) (Check, bool) {
+	for _, check := range report.Checks {
+		if check.Test == test && check.Metric == metric {
+			return check, true
+		}
+	}
+	return Check{}, false
+}
+
+func TestGate(t *testing.T) {
+	cases := []struct {
+		name         string
+		run          *rundoc.Document
+		test, metric string
+		passed       bool
+	}{
+		{"score drop just inside the tolerance", gateRun(47.6, 100, 40), "ocean", MetricScore, true},
+		{"score drop just outside the tolerance", gateRun(47.4, 100, 40), "ocean", MetricScore, false},
+		{"p99 frame time rise inside the tolerance", gateRun(50, 95, 40), "ocean", MetricP99FrameTime, true},
+		{"p99 frame time rise outside the tolerance", gateRun(50, 88, 40), "ocean", MetricP99FrameTime, false},
+		// 15% lower fails the default of 5%, not the 20% of shadows
+		{"per-test tolerance over the default", gateRun(50, 100, 34), "shadows", MetricScore, true},
+		{"drop beyond the per-test tolerance", gateRun(50, 100, 31), "shadows", MetricScore, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			report := Gate(gateBaseline(t), c.run)
+			check, ok := findCheck(report, c.test, c.metric)
+			if !ok {
+				t.Fatalf("no %s check of %s in %+v", c.metric, c.test, report.Checks)
+			}
+			if check.Passed != c.passed || report.Passed() != c.passed {
+				t.Errorf("%s: passed %v, report passed %v, want %v", check.Describe(), check.Passed, report.Passed(), c.passed)
+			}
+		})
+	}
+}
+
+func TestGateMissingAndIncomplete(t *testing.T) {
+	run := gateRun(50, 100, 40)
+	delete(run.Tests, "shadows")
+	run.Tests["ocean"].Incomplete = "stopped"
+	run.Tests["alu"] = &rundoc.TestResult{Kind: rundoc.KindExtended}
+
+	report := Gate(gateBaseline(t), run)
+	if check, _ := findCheck(report, "shadows", MetricScore); !check.Missing || check.Passed {
+		t.Errorf("missing test %+v, want a failed check", check)
+	}
+	if _, ok := findCheck(report, "shadows", MetricP99FrameTime); ok {
+		t.Error("a missing test has a frame time check")
+	}
+	// The score is as good as the baseline, stopping early is what fails it
+	if check, _ := findCheck(report, "ocean", MetricScore); check.Incomplete != "stopped" || check.Passed {
+		t.Errorf("incomplete test %+v, want a failed check", check)
+	}
+	if len(report.Failures()) != 2 || report.Passed() {
+		t.Errorf("failures %+v, want shadows and ocean", report.Failures())
+	}
+	if len(report.NewTests) != 1 || report.NewTests[0] != "alu" {
+		t.Errorf("new tests %v, want alu", report.NewTests)
+	}
+}
+
+func TestWriteReports(t *testing.T) {
+	report := Gate(gateBaseline(t), gateRun(40, 88, 40))
+
+	var junit bytes.Buffer
+	if err := report.WriteJUnit(&junit); err != nil {
+		t.Fatal(err)
+	}
+	var suites junitSuites
+	if err := xml.Unmarshal(junit.Bytes(), &suites); err != nil {
+		t.Fatalf("%v in\n%s", err, junit.String())
+	}
+	suite := suites.Suites[0]
+	var failed []string
+	for _, testCase := range suite.Cases {
+		if testCase.Failure != nil {
+			failed = append(failed, testCase.ClassName+" "+testCase.Name)
+		}
+	}
+	// total, ocean score and p99, shadows score and p99
+	if suite.Tests != 5 || suite.Failures != 2 || strings.Join(failed, ",") != "gltest.ocean score,gltest.ocean p99_frame_time_ms" {
+		t.Errorf("%d tests, %d failures %v", suite.Tests, suite.Failures, failed)
+	}
+	if suite.Timestamp != "2026-10-18T12:00:00" {
+		t.Errorf("timestamp %s", suite.Timestamp)
+	}
+
+	var markdown bytes.Buffer
+	if err := report.WriteMarkdown(&markdown); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"## GLTest against `mesa-24-llvmpipe`: failed (2 of 5 checks)",
+		"on Radeon RX 7600, driver 24.9.1",
+		"| ocean | score | 50.00 | 40.00 | -20.0% | 5.0% | **FAIL** |",
+		"| shadows | score | 40.00 | 40.00 | +0.0% | 20.0% | pass |",
+	} {
+		if !strings.Contains(markdown.String(), want) {
+			t.Errorf("Markdown has no %q:\n%s", want, markdown.String())
+		}
+	}
+}
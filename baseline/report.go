@@ -0,0 +1,106 @@
+package baseline
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+}
+
+// WriteJUnit writes the report as JUnit XML, one test case per check
+func (r *Report) WriteJUnit(w io.Writer) error {
+	suite := junitSuite{
+		Name:      "GLTest against " + r.Baseline,
+		Tests:     len(r.Checks),
+		Failures:  len(r.Failures()),
+		Timestamp: r.Run.CreatedAt.UTC().Format("2006-01-02T15:04:05"),
+	}
+	for _, check := range r.Checks {
+		testCase := junitTestCase{ClassName: "gltest." + check.Test, Name: check.Metric}
+		if check.Passed {
+			testCase.SystemOut = check.Describe()
+		} else {
+			testCase.Failure = &junitFailure{Message: check.Describe(), Type: "regression"}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitSuites{Suites: []junitSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteMarkdown writes the report as a Markdown summary for CI job pages and merge requests
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	status := "passed"
+	if !r.Passed() {
+		status = fmt.Sprintf("failed (%d of %d checks)", len(r.Failures()), len(r.Checks))
+	}
+	fmt.Fprintf(&b, "## GLTest against `%s`: %s\n\n", r.Baseline, status)
+	fmt.Fprintf(&b, "Run `%s` on %s, driver %s\n\n", r.RunID, r.Run.System.GpuName, r.Run.System.DriverVersion)
+
+	b.WriteString("| Test | Metric | Baseline | Run | Change | Tolerance | Result |\n")
+	b.WriteString("|------|--------|---------:|----:|-------:|----------:|--------|\n")
+	for _, check := range r.Checks {
+		result := "pass"
+		if !check.Passed {
+			result = "**FAIL**"
+		}
+		if check.Incomplete != "" {
+			result += " (" + check.Incomplete + ")"
+		}
+		if check.Missing {
+			fmt.Fprintf(&b, "| %s | %s | %.2f | missing | | %.1f%% | %s |\n",
+				check.Test, check.Metric, check.Baseline, check.TolerancePct, result)
+			continue
+		}
+		fmt.Fprintf(&b, "| %s | %s | %.2f | %.2f | %+.1f%% | %.1f%% | %s |\n",
+			check.Test, check.Metric, check.Baseline, check.Value, check.ChangePct, check.TolerancePct, result)
+	}
+
+	if len(r.NewTests) > 0 {
+		fmt.Fprintf(&b, "\nNot in the baseline, not gated: %s\n", strings.Join(r.NewTests, ", "))
+	}
+	if unstable := r.Run.UnstableScores(); len(unstable) > 0 {
+		fmt.Fprintf(&b, "\nUnstable over the iterations of this run: %s\n", strings.Join(unstable, ", "))
+	}
+	if r.Run.Noisy {
+		fmt.Fprintf(&b, "\nThe system was busy with other work during this run (up to %.0f%% CPU), regressions may be noise\n", r.Run.Noise*100)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,633 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"moddergltest/baseline"
+	"moddergltest/compare"
+	"moddergltest/export"
+	"moddergltest/history"
+	"moddergltest/progress"
+	"moddergltest/report"
+	"moddergltest/rundoc"
+	"moddergltest/submit"
+)
+
+// GLTest.exe report [-o report.html] <run>: the HTML report of any stored run or run.json
+func runReport(args []string) int {
+	attachConsole()
+
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	output := flags.String("o", "", "output file, report-<run>.html by default")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: GLTest.exe report [-o report.html] <run>")
+		return 2
+	}
+
+	store, err := history.Default()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	doc, err := loadRun(store, flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	path := *output
+	if path == "" {
+		path = fmt.Sprintf("report-%.8s.html", doc.RunID)
+	}
+	if err := report.WriteHTMLFile(path, doc); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Report written to %s\n", path)
+	return 0
+}
+
+// GLTest.exe export [-o file] [--format json|csv|md|html] <run>: a run in a format for other tools
+func runExport(args []string) int {
+	attachConsole()
+
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := flags.String("o", "", "output file, its extension names the format; run-<run>.<format> by default")
+	format := flags.String("format", "", "json, csv, md or html, when the output file does not tell")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: GLTest.exe export [-o run.csv] [--format json|csv|md|html] <run>")
+		return 2
+	}
+
+	path := *output
+	switch {
+	case path == "" && *format == "":
+		*format = export.JSON
+	case *format == "":
+		var err error
+		if *format, err = export.FormatOf(path); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	case !slices.Contains(export.Formats, *format):
+		fmt.Fprintf(os.Stderr, "Unknown format %q, expected one of %s\n", *format, strings.Join(export.Formats, ", "))
+		return 2
+	}
+
+	store, err := history.Default()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	doc, err := loadRun(store, flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if path == "" {
+		path = fmt.Sprintf("run-%.8s.%s", doc.RunID, *format)
+	}
+	err = writeReport(path, func(w io.Writer) error {
+		return export.Write(w, doc, *format)
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Run exported to %s\n", path)
+	return 0
+}
+
+// A run by history ID, prefix or tag, or a run.json file
+func loadRun(store *history.Store, ref string) (*rundoc.Document, error) {
+	if _, err := os.Stat(ref); err == nil {
+		return rundoc.Read(ref)
+	}
+	run, err := store.Get(ref)
+	if err != nil {
+		return nil, err
+	}
+	return run.Document, nil
+}
+
+// GLTest.exe compare [--alpha 0.05] [--min-change 1] <run A> <run B>, exits with 1 on regressions
+func runCompare(args []string) int {
+	attachConsole()
+
+	flags := flag.NewFlagSet("compare", flag.ContinueOnError)
+	alpha := flags.Float64("alpha", compare.DefaultAlpha, "significance level")
+	minChange := flags.Float64("min-change", compare.DefaultMinChange, "smallest change in percent that counts")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "usage: GLTest.exe compare [--alpha 0.05] [--min-change 1] <run A> <run B>")
+		return 2
+	}
+
+	store, err := history.Default()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	runA, err := loadRun(store, flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	runB, err := loadRun(store, flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	comparison := compare.Runs(runA, runB, compare.Options{Alpha: *alpha, MinChange: *minChange})
+	comparison.WriteText(os.Stdout)
+	if len(comparison.Regressions()) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// GLTest.exe run [--iterations 1] [--cooldown 0] [--cv-threshold 5] [--tag name]: run the suite
+// without the GUI, write run.json and keep the run in the history. Nothing is sent.
+func runHeadless(args []string) int {
+	attachConsole()
+
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	iterations := flags.Int("iterations", 1, "number of passes of the suite")
+	cooldown := flags.Float64("cooldown", 0, "seconds of idle time between passes")
+	cvThreshold := flags.Float64("cv-threshold", rundoc.DefaultCVThreshold*100, "coefficient of variation in percent above which a score is unstable")
+	tag := flags.String("tag", "", "tag the run in the history")
+	timeout := flags.Float64("timeout", 0, "seconds a test may run before it is stopped, 0 for its announced duration plus two minutes")
+	suite := flags.String("suite", rundoc.SuiteFull, "quick (core tests only, stopped early) or full")
+	testList := flags.String("tests", "", "comma-separated tests to run instead of a suite")
+	noiseMode := flags.String("noise", noiseWarn, "when the system is busy before a test: warn, abort or off")
+	usage := func() int {
+		fmt.Fprintln(os.Stderr, "usage: GLTest.exe run [--suite full|quick | --tests ocean,postfx] [--iterations 1] [--cooldown 0] [--cv-threshold 5] [--timeout 0] [--noise warn|abort|off] [--tag name]")
+		return 2
+	}
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 || *iterations < 1 || *cooldown < 0 || *cvThreshold <= 0 || *timeout < 0 {
+		return usage()
+	}
+	if *suite != rundoc.SuiteFull && *suite != rundoc.SuiteQuick {
+		return usage()
+	}
+	if *noiseMode != noiseWarn && *noiseMode != noiseAbort && *noiseMode != noiseOff {
+		return usage()
+	}
+
+	customTests, err := discoverCustomTests()
+	if err != nil {
+		log.Printf("Failed to discover custom tests: %v", err)
+	}
+
+	selection := suiteSelection(*suite, customTests)
+	if *testList != "" {
+		selection = testSelection{Suite: rundoc.SuiteCustom, Tests: make(map[string]bool)}
+		for _, name := range strings.Split(*testList, ",") {
+			name = strings.TrimSpace(name)
+			if !slices.Contains(allTests(customTests), name) {
+				fmt.Fprintf(os.Stderr, "Unknown test %q, tests are: %s\n", name, strings.Join(allTests(customTests), ", "))
+				return 2
+			}
+			selection.Tests[name] = true
+		}
+	}
+
+	runner := newTestRunner(printProgress, time.Duration(*timeout*float64(time.Second)))
+	runner.noise = *noiseMode
+
+	// Ctrl+C stops the running test, which the console also tells directly, and keeps what
+	// was measured; a second one ends GLTest right away
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		signal.Stop(interrupt)
+		fmt.Fprintln(os.Stderr, "Cancelling, press Ctrl+C again to quit without saving")
+		runner.Cancel()
+	}()
+
+	var passes []BenchmarkResults
+	for i := 0; i < *iterations && !runner.Cancelled(); i++ {
+		if i > 0 && *cooldown > 0 {
+			runner.Sleep(time.Duration(*cooldown * float64(time.Second)))
+			if runner.Cancelled() {
+				break
+			}
+		}
+		fmt.Printf("Iteration %d of %d\n", i+1, *iterations)
+		results, err := runSuite(customTests, selection, func(err error) {
+			fmt.Fprintln(os.Stderr, err)
+		}, runner)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		passes = append(passes, results)
+	}
+
+	diagnostics := runDiagnostics(selection, runner, func(err error) {
+		fmt.Fprintln(os.Stderr, err)
+	})
+	doc, docPath, err := writeRunDocument(passes, selection.Suite, *cvThreshold/100, diagnostics, runner.Incomplete())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write run document: %v\n", err)
+		return 1
+	}
+
+	var tags []string
+	if *tag != "" {
+		tags = append(tags, *tag)
+	}
+	if store, err := history.Default(); err != nil {
+		log.Printf("Failed to open run history: %v", err)
+	} else if err := store.Add(doc, tags...); err != nil {
+		log.Printf("Failed to store run in history: %v", err)
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "TEST\tSCORE")
+	fmt.Fprintf(writer, "%s\t%s\n", rundoc.TotalScoreKey, formatScore(doc.Summary, rundoc.TotalScoreKey, doc.TotalScore))
+	for _, name := range doc.TestNames() {
+		score := formatScore(doc.Summary, name, doc.Tests[name].Score)
+		if reason := doc.Tests[name].Incomplete; reason != "" {
+			score += " (" + reason + ")"
+		}
+		fmt.Fprintf(writer, "%s\t%s\n", name, score)
+	}
+	writer.Flush()
+	fmt.Printf("Run %s written to %s\n", doc.RunID, docPath)
+	if reportPath, err := writeRunReport(doc, docPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write report: %v\n", err)
+	} else {
+		fmt.Printf("Report written to %s\n", reportPath)
+	}
+	if doc.Noisy {
+		fmt.Fprintf(os.Stderr, "The system was busy with other work (up to %.0f%% CPU), the run is flagged as noisy\n", doc.Noise*100)
+	}
+	if doc.Incomplete {
+		fmt.Fprintln(os.Stderr, "The run is incomplete")
+		return 1
+	}
+	return 0
+}
+
+// One console line per test and stage
+func printProgress(test string, event progress.Event) {
+	switch event.Kind {
+	case progress.Launched:
+		fmt.Printf("%s\n", test)
+	case progress.Preflight:
+		if event.Description != "" {
+			fmt.Printf("  system busy: %s\n", event.Description)
+		}
+	case progress.Stage:
+		fmt.Printf("  stage %d %s\n", event.Stage, event.Description)
+	}
+}
+
+// GLTest.exe baseline: manage the named reference runs used by gate
+func runBaseline(args []string) int {
+	attachConsole()
+
+	store, err := baseline.Default()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	command := "list"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	badUsage := func() int {
+		fmt.Fprintln(os.Stderr, "usage: GLTest.exe baseline [list | set [--score 5] [--p99 10] <name> <run> | tolerance [--score N] [--p99 N] <name> <test|default> | show <name> | delete <name>]")
+		return 2
+	}
+	flags := flag.NewFlagSet("baseline "+command, flag.ContinueOnError)
+	score := flags.Float64("score", -1, "percent the score may drop")
+	p99 := flags.Float64("p99", -1, "percent the p99 frame time may rise")
+
+	switch command {
+	case "list":
+		if len(args) != 0 {
+			return badUsage()
+		}
+		names, err := store.List()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "NAME\tRUN\tTIME\tTOTAL\tGPU\tDRIVER")
+		for _, name := range names {
+			b, err := store.Load(name)
+			if err != nil {
+				fmt.Fprintf(writer, "%s\t%v\t\t\t\t\n", name, err)
+				continue
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%.2f\t%s\t%s\n", name, b.Document.RunID[:8],
+				b.Document.CreatedAt.Local().Format("2006-01-02 15:04"), b.Document.TotalScore,
+				b.Document.System.GpuName, b.Document.System.DriverVersion)
+		}
+		writer.Flush()
+		return 0
+	case "set":
+		if flags.Parse(args) != nil || flags.NArg() != 2 {
+			return badUsage()
+		}
+		runs, err := history.Default()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		doc, err := loadRun(runs, flags.Arg(1))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		b, err := baseline.New(flags.Arg(0), doc)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		// Replacing a baseline keeps its tolerances
+		if old, err := store.Load(b.Name); err == nil {
+			b.Tolerance, b.Tests = old.Tolerance, old.Tests
+		}
+		if *score >= 0 {
+			b.Tolerance.ScorePct = *score
+		}
+		if *p99 >= 0 {
+			b.Tolerance.P99FrameTimePct = *p99
+		}
+		if err := store.Save(b); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	case "tolerance":
+		if flags.Parse(args) != nil || flags.NArg() != 2 {
+			return badUsage()
+		}
+		b, err := store.Load(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		test := flags.Arg(1)
+		tolerance := b.ToleranceFor(test)
+		if *score >= 0 {
+			tolerance.ScorePct = *score
+		}
+		if *p99 >= 0 {
+			tolerance.P99FrameTimePct = *p99
+		}
+		b.SetTolerance(test, tolerance)
+		if err := store.Save(b); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	case "show":
+		if len(args) != 1 {
+			return badUsage()
+		}
+		b, err := store.Load(args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("Baseline %s, run %s of %s on %s, driver %s\n\n", b.Name, b.Document.RunID,
+			b.Document.CreatedAt.Local().Format("2006-01-02 15:04"), b.Document.System.GpuName, b.Document.System.DriverVersion)
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "TEST\tSCORE\tP99 FRAME MS\tSCORE TOLERANCE\tP99 TOLERANCE")
+		total, _ := b.Document.Score(rundoc.TotalScoreKey)
+		fmt.Fprintf(writer, "%s\t%.2f\t\t%.1f%%\t\n", rundoc.TotalScoreKey, total, b.Tolerance.ScorePct)
+		for _, name := range b.Document.TestNames() {
+			score, _ := b.Document.Score(name)
+			tolerance := b.ToleranceFor(name)
+			fmt.Fprintf(writer, "%s\t%.2f\t%.2f\t%.1f%%\t%.1f%%\n", name, score, b.Document.Tests[name].P99FrameTimeMs(),
+				tolerance.ScorePct, tolerance.P99FrameTimePct)
+		}
+		writer.Flush()
+		return 0
+	case "delete":
+		if len(args) != 1 {
+			return badUsage()
+		}
+		err = store.Delete(args[0])
+	default:
+		return badUsage()
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// GLTest.exe gate [--junit file] [--markdown file] <baseline> [run]: exits with 1 when the run,
+// by default run.json next to the executable, regresses beyond the tolerances of the baseline
+func runGate(args []string) int {
+	attachConsole()
+
+	flags := flag.NewFlagSet("gate", flag.ContinueOnError)
+	junitPath := flags.String("junit", "", "write a JUnit XML report to this file")
+	markdownPath := flags.String("markdown", "", "write a Markdown summary to this file")
+	if err := flags.Parse(args); err != nil || flags.NArg() < 1 || flags.NArg() > 2 {
+		fmt.Fprintln(os.Stderr, "usage: GLTest.exe gate [--junit report.xml] [--markdown summary.md] <baseline> [run]")
+		return 2
+	}
+
+	store, err := baseline.Default()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	b, err := store.Load(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	runRef := flags.Arg(1)
+	if runRef == "" {
+		exePath, err := os.Executable()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		runRef = filepath.Join(filepath.Dir(exePath), "run.json")
+	}
+	runs, err := history.Default()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	doc, err := loadRun(runs, runRef)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	result := baseline.Gate(b, doc)
+	for path, write := range map[string]func(io.Writer) error{*junitPath: result.WriteJUnit, *markdownPath: result.WriteMarkdown} {
+		if path == "" {
+			continue
+		}
+		if err := writeReport(path, write); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write %s: %v\n", path, err)
+			return 2
+		}
+	}
+
+	for _, check := range result.Checks {
+		status := "PASS"
+		if !check.Passed {
+			status = "FAIL"
+		}
+		fmt.Printf("%s  %s\n", status, check.Describe())
+	}
+	if !result.Passed() {
+		fmt.Printf("%d of %d checks against %s failed\n", len(result.Failures()), len(result.Checks), b.Name)
+		return 1
+	}
+	fmt.Printf("All %d checks against %s passed\n", len(result.Checks), b.Name)
+	return 0
+}
+
+func writeReport(path string, write func(io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// GLTest.exe history [list [tag] | show <run> | tag <run> <tag>... | untag <run> <tag>... | delete <run>]
+func runHistory(args []string) int {
+	attachConsole()
+
+	store, err := history.Default()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	command := "list"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch {
+	case command == "list" && len(args) <= 1:
+		tag := ""
+		if len(args) == 1 {
+			tag = args[0]
+		}
+		runs, err := store.List(tag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "RUN\tTIME\tSUITE\tTOTAL\tGPU\tTAGS")
+		for _, run := range runs {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%.2f\t%s\t%s\n", run.RunID[:8], run.Time.Local().Format("2006-01-02 15:04"),
+				suiteLabel(run), run.Document.TotalScore, run.Document.System.GpuName, strings.Join(run.Tags, ","))
+		}
+		writer.Flush()
+	case command == "show" && len(args) == 1:
+		run, err := store.Get(args[0])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		data, err := json.MarshalIndent(run, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Println(string(data))
+	case command == "tag" && len(args) >= 2:
+		err = store.Tag(args[0], args[1:]...)
+	case command == "untag" && len(args) >= 2:
+		err = store.Untag(args[0], args[1:]...)
+	case command == "delete" && len(args) == 1:
+		err = store.Delete(args[0])
+	default:
+		fmt.Fprintln(os.Stderr, "usage: GLTest.exe history [list [tag] | show <run> | tag <run> <tag>... | untag <run> <tag>... | delete <run>]")
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// Let a GUI subsystem executable print to the console it was started from
+func attachConsole() {
+	const ATTACH_PARENT_PROCESS = ^uintptr(0)
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	if ret, _, _ := kernel32.NewProc("AttachConsole").Call(ATTACH_PARENT_PROCESS); ret == 0 {
+		return
+	}
+	if console, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0); err == nil {
+		os.Stdout = console
+		os.Stderr = console
+		log.SetOutput(console)
+	}
+}
+
+// GLTest.exe flush: retry every queued submission now
+func runFlush() int {
+	attachConsole()
+
+	config, err := submit.LoadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	outbox, err := submit.DefaultOutbox()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	delivered, err := outbox.Flush(config, true)
+	fmt.Printf("Delivered: %d\n", delivered)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	status, statusErr := outbox.Status()
+	if statusErr != nil {
+		fmt.Fprintln(os.Stderr, statusErr)
+		return 1
+	}
+	fmt.Printf("Still queued: %d\n", status.Pending)
+	if status.Pending > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,206 @@
+// Package compare lines up two runs test by test and stage by stage and flags regressions.
+package compare
+
+import (
+	"math"
+
+	"moddergltest/rundoc"
+	"moddergltest/stats"
+)
+
+// Defaults of Options
+const (
+	DefaultAlpha     = 0.05 // significance level of the Welch test over the iteration scores
+	DefaultMinChange = 1.0  // percent, smaller changes are never flagged
+)
+
+// Options tune when a difference counts as a regression
+type Options struct {
+	Alpha     float64
+	MinChange float64
+}
+
+// Delta is a value in run A and run B
+type Delta struct {
+	A   float64 `json:"a"`
+	B   float64 `json:"b"`
+	Abs float64 `json:"abs"`
+	Pct float64 `json:"pct"` // relative to A, 0 when A is 0
+}
+
+// Verdict of a comparison
+const (
+	Unchanged   = "unchanged"
+	Regression  = "regression"
+	Improvement = "improvement"
+	// Fewer than two iterations in either run: the deltas are all there is to go on
+	InsufficientData = "insufficient data"
+)
+
+// Stage is one stage of a test in both runs. FPS values are over the half-second samples,
+// which follow each other too closely to test a difference for significance.
+type Stage struct {
+	Stage     int     `json:"stage"`
+	Load      float64 `json:"load"`
+	AvgFps    Delta   `json:"avg_fps"`
+	MinFps    Delta   `json:"min_fps"`
+	P50Fps    Delta   `json:"p50_fps"`
+	P5Fps     Delta   `json:"p5_fps"`
+	GPUTimeMs *Delta  `json:"gpu_time_ms,omitempty"` // tests reporting GPU time only
+}
+
+// Test is one test in both runs. When both runs were repeated, Score is the mean over the
+// iterations and the verdict comes from the iteration scores; otherwise it is InsufficientData.
+type Test struct {
+	Name        string   `json:"name"`
+	Kind        string   `json:"kind"`
+	Score       Delta    `json:"score"`
+	ScorePValue *float64 `json:"score_p_value,omitempty"`
+	AvgFps      Delta    `json:"avg_fps"`
+	MinFps      Delta    `json:"min_fps"`
+	Stages      []Stage  `json:"stages"`
+	Verdict     string   `json:"verdict"`
+}
+
+// Comparison of run B against run A
+type Comparison struct {
+	A       *rundoc.Document `json:"-"`
+	B       *rundoc.Document `json:"-"`
+	Options Options          `json:"options"`
+	Total   Delta            `json:"total_score"`
+	// Repeated runs only, Welch t-test over the iteration totals
+	TotalPValue *float64 `json:"total_p_value,omitempty"`
+	Tests       []Test   `json:"tests"`
+	OnlyInA     []string `json:"only_in_a,omitempty"`
+	OnlyInB     []string `json:"only_in_b,omitempty"`
+}
+
+// Runs compares run B against run A, zero options take the defaults
+func Runs(a, b *rundoc.Document, options Options) *Comparison {
+	if options.Alpha <= 0 {
+		options.Alpha = DefaultAlpha
+	}
+	if options.MinChange <= 0 {
+		options.MinChange = DefaultMinChange
+	}
+
+	c := &Comparison{A: a, B: b, Options: options, Total: delta(a.TotalScore, b.TotalScore)}
+	if mean, p, ok := iterationDelta(a, b, rundoc.TotalScoreKey); ok {
+		c.Total = mean
+		c.TotalPValue = &p
+	}
+	for _, name := range a.TestNames() {
+		testB, ok := b.Tests[name]
+		if !ok {
+			c.OnlyInA = append(c.OnlyInA, name)
+			continue
+		}
+		test := compareTest(name, a.Tests[name], testB)
+		if mean, p, ok := iterationDelta(a, b, name); ok {
+			test.Score = mean
+			test.ScorePValue = &p
+			test.Verdict = verdict(mean, p, options)
+		}
+		c.Tests = append(c.Tests, test)
+	}
+	for _, name := range b.TestNames() {
+		if _, ok := a.Tests[name]; !ok {
+			c.OnlyInB = append(c.OnlyInB, name)
+		}
+	}
+	return c
+}
+
+// Regressions lists the tests with a significantly lower score over the iterations of repeated runs
+func (c *Comparison) Regressions() []string {
+	var names []string
+	for _, test := range c.Tests {
+		if test.Verdict == Regression {
+			names = append(names, test.Name)
+		}
+	}
+	return names
+}
+
+func compareTest(name string, a, b *rundoc.TestResult) Test {
+	test := Test{
+		Name:    name,
+		Kind:    a.Kind,
+		Score:   delta(a.Score, b.Score),
+		AvgFps:  delta(a.AvgFps, b.AvgFps),
+		MinFps:  delta(a.MinFps, b.MinFps),
+		Verdict: InsufficientData,
+	}
+
+	stagesA, stagesB := a.SamplesByStage(), b.SamplesByStage()
+	// Tests with a "GPU Time (ms)" column
+	gpuA, gpuB := a.ColumnByStage("GPU Time"), b.ColumnByStage("GPU Time")
+	for _, stage := range a.Stages() {
+		samplesB, ok := stagesB[stage]
+		if !ok {
+			continue
+		}
+		samplesA := stagesA[stage]
+		fpsA, fpsB := avgFps(samplesA), avgFps(samplesB)
+
+		s := Stage{
+			Stage:  stage,
+			Load:   samplesA[0].Load,
+			AvgFps: delta(stats.Mean(fpsA), stats.Mean(fpsB)),
+			MinFps: delta(stats.Mean(minFps(samplesA)), stats.Mean(minFps(samplesB))),
+			P50Fps: delta(stats.Median(fpsA), stats.Median(fpsB)),
+			P5Fps:  delta(stats.Percentile(fpsA, 5), stats.Percentile(fpsB, 5)),
+		}
+		if len(gpuA[stage]) > 0 && len(gpuB[stage]) > 0 {
+			gpu := delta(stats.Mean(gpuA[stage]), stats.Mean(gpuB[stage]))
+			s.GPUTimeMs = &gpu
+		}
+		test.Stages = append(test.Stages, s)
+	}
+	return test
+}
+
+// Higher scores are better; a change has to be both significant and large enough to matter
+func verdict(score Delta, pValue float64, options Options) string {
+	if pValue >= options.Alpha || math.Abs(score.Pct) < options.MinChange {
+		return Unchanged
+	}
+	if score.Abs < 0 {
+		return Regression
+	}
+	return Improvement
+}
+
+// Mean score over the iterations of both runs and the p-value of the difference, when both
+// runs have at least two iterations of the test
+func iterationDelta(a, b *rundoc.Document, name string) (Delta, float64, bool) {
+	scoresA, scoresB := a.IterationScores(name), b.IterationScores(name)
+	if len(scoresA) < 2 || len(scoresB) < 2 {
+		return Delta{}, 1, false
+	}
+	return delta(stats.Mean(scoresA), stats.Mean(scoresB)), stats.WelchTTest(scoresA, scoresB), true
+}
+
+func delta(a, b float64) Delta {
+	d := Delta{A: a, B: b, Abs: b - a}
+	if a != 0 {
+		d.Pct = (b - a) / math.Abs(a) * 100
+	}
+	return d
+}
+
+func avgFps(samples []rundoc.Sample) []float64 {
+	values := make([]float64, len(samples))
+	for i, sample := range samples {
+		values[i] = sample.AvgFps
+	}
+	return values
+}
+
+func minFps(samples []rundoc.Sample) []float64 {
+	values := make([]float64, len(samples))
+	for i, sample := range samples {
+		values[i] = sample.MinFps
+	}
+	return values
+}
@@ -0,0 +1,133 @@
+package compare
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"moddergltest/rundoc"
+	"moddergltest/stats"
+)
+
+func TestVerdict(t *testing.T) {
+	options := Options{Alpha: DefaultAlpha, MinChange: DefaultMinChange}
+	cases := []struct {
+		name   string
+		a, b   float64
+		pValue float64
+		want   string
+	}{
+		{"significant drop", 100, 95, 0.001, Regression},
+		{"significant rise", 100, 105, 0.001, Improvement},
+		{"drop that may be chance", 100, 95, 0.2, Unchanged},
+		{"at the significance level", 100, 95, DefaultAlpha, Unchanged},
+		{"significant but too small to matter", 100, 99.5, 0.001, Unchanged},
+		{"exactly the smallest change", 100, 99, 0.001, Regression},
+		{"from zero", 0, 10, 0.001, Unchanged}, // no relative change
+	}
+	for _, c := range cases {
+		if got := verdict(delta(c.a, c.b), c.pValue, options); got != c.want {
+			t.Errorf("%s: verdict(%g -> %g, p %g) = %s, want %s", c.name, c.a, c.b, c.pValue, got, c.want)
+		}
+	}
+}
+
+func TestDelta(t *testing.T) {
+	cases := []struct {
+		a, b float64
+		want Delta
+	}{
+		{100, 90, Delta{A: 100, B: 90, Abs: -10, Pct: -10}},
+		{-50, -25, Delta{A: -50, B: -25, Abs: 25, Pct: 50}},
+		{0, 5, Delta{A: 0, B: 5, Abs: 5}},
+	}
+	for _, c := range cases {
+		if got := delta(c.a, c.b); got != c.want {
+			t.Errorf("delta(%g, %g) = %+v, want %+v", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+// A run of one test with two stages, eight samples each around the given FPS
+func run(test string, fps1, fps2 float64) *rundoc.Document {
+	result := &rundoc.TestResult{Kind: rundoc.KindCore, Score: (fps1 + fps2) / 10, AvgFps: (fps1 + fps2) / 2}
+	for i := 0; i < 8; i++ {
+		jitter := float64(i%3) * 0.2
+		result.Samples = append(result.Samples,
+			rundoc.Sample{Stage: 1, Load: 1000, AvgFps: fps1 + jitter, MinFps: fps1 - 5},
+			rundoc.Sample{Stage: 2, Load: 2000, AvgFps: fps2 + jitter, MinFps: fps2 - 5})
+	}
+	return &rundoc.Document{TotalScore: result.Score, Tests: map[string]*rundoc.TestResult{test: result}}
+}
+
+func TestRuns(t *testing.T) {
+	a, b := run("ocean", 120, 60), run("ocean", 120, 54)
+	b.Tests["shadows"] = run("shadows", 1, 1).Tests["shadows"]
+
+	c := Runs(a, b, Options{})
+	if c.Options.Alpha != DefaultAlpha || c.Options.MinChange != DefaultMinChange {
+		t.Errorf("options %+v, want the defaults", c.Options)
+	}
+	if len(c.Tests) != 1 || !slices.Equal(c.OnlyInB, []string{"shadows"}) || len(c.OnlyInA) != 0 {
+		t.Fatalf("tests %d, only in A %v, only in B %v", len(c.Tests), c.OnlyInA, c.OnlyInB)
+	}
+	// The samples of a single pass are no evidence of a regression, however far a stage drops
+	test := c.Tests[0]
+	if test.Verdict != InsufficientData || len(c.Regressions()) != 0 || c.TotalPValue != nil {
+		t.Errorf("verdict %s, regressions %v, want insufficient data", test.Verdict, c.Regressions())
+	}
+	if len(test.Stages) != 2 {
+		t.Fatalf("stages %+v, want two", test.Stages)
+	}
+	if pct := test.Stages[1].AvgFps.Pct; math.Abs(pct+10) > 0.1 {
+		t.Errorf("stage 2 changed by %.2f%%, want -10%%", pct)
+	}
+}
+
+// Runs of the ocean test repeated with the given scores
+func repeated(scores ...float64) *rundoc.Document {
+	doc := run("ocean", 120, 60)
+	var iterations []rundoc.Iteration
+	for _, score := range scores {
+		iterations = append(iterations, rundoc.Iteration{TotalScore: score, Scores: map[string]float64{"ocean": score}})
+	}
+	doc.SetIterations(iterations, 0)
+	return doc
+}
+
+func TestRunsOverIterations(t *testing.T) {
+	cases := []struct {
+		name    string
+		a, b    *rundoc.Document
+		options Options
+		want    string
+	}{
+		{"scores within the noise", repeated(18, 17, 19), repeated(17.5, 18.5, 17), Options{}, Unchanged},
+		{"scores clearly lower", repeated(18, 18.2, 17.9), repeated(16, 16.1, 15.8), Options{}, Regression},
+		{"scores clearly higher", repeated(16, 16.1, 15.8), repeated(18, 18.2, 17.9), Options{}, Improvement},
+		// A looser threshold than the drop keeps it from counting
+		{"drop smaller than the minimum change", repeated(18, 18.2, 17.9), repeated(16, 16.1, 15.8), Options{MinChange: 15}, Unchanged},
+		{"one side run once", repeated(18, 18.2, 17.9), repeated(16), Options{}, InsufficientData},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			comparison := Runs(c.a, c.b, c.options)
+			test := comparison.Tests[0]
+			if test.Verdict != c.want {
+				t.Errorf("verdict %s, want %s", test.Verdict, c.want)
+			}
+			if c.want == InsufficientData {
+				if test.ScorePValue != nil || comparison.TotalPValue != nil {
+					t.Error("p-values without two iterations on each side")
+				}
+				return
+			}
+			if comparison.TotalPValue == nil || test.ScorePValue == nil {
+				t.Fatal("no p-values over the iterations")
+			}
+			if want := stats.Mean(c.a.IterationScores("ocean")); comparison.Total.A != want || test.Score.A != want {
+				t.Errorf("total %+v, score %+v, want the mean %g of the iterations", comparison.Total, test.Score, want)
+			}
+		})
+	}
+}
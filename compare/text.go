@@ -0,0 +1,73 @@
+package compare
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"moddergltest/rundoc"
+)
+
+// WriteText prints the comparison as aligned plain-text tables
+func (c *Comparison) WriteText(w io.Writer) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(writer, "A\t%s\n", describeRun(c.A))
+	fmt.Fprintf(writer, "B\t%s\n", describeRun(c.B))
+	fmt.Fprintf(writer, "Total score\t%s%s\n\n", formatDelta(c.Total, 2), formatPValue(c.TotalPValue))
+
+	fmt.Fprintln(writer, "TEST\tSCORE\tAVG FPS\tMIN FPS\tVERDICT")
+	for _, test := range c.Tests {
+		fmt.Fprintf(writer, "%s\t%s%s\t%s\t%s\t%s\n", test.Name, formatDelta(test.Score, 2), formatPValue(test.ScorePValue),
+			formatDelta(test.AvgFps, 1), formatDelta(test.MinFps, 1), strings.ToUpper(test.Verdict))
+	}
+	for _, name := range c.OnlyInA {
+		fmt.Fprintf(writer, "%s\tonly in A\t\t\t\n", name)
+	}
+	for _, name := range c.OnlyInB {
+		fmt.Fprintf(writer, "%s\tonly in B\t\t\t\n", name)
+	}
+
+	for _, test := range c.Tests {
+		fmt.Fprintf(writer, "\n%s\n", test.Name)
+		fmt.Fprintln(writer, "STAGE\tLOAD\tAVG FPS\tP50 FPS\tP5 FPS\tMIN FPS\tGPU MS")
+		for _, stage := range test.Stages {
+			gpu := "-"
+			if stage.GPUTimeMs != nil {
+				gpu = formatDelta(*stage.GPUTimeMs, 2)
+			}
+			fmt.Fprintf(writer, "%d\t%g\t%s\t%s\t%s\t%s\t%s\n", stage.Stage, stage.Load,
+				formatDelta(stage.AvgFps, 1), formatDelta(stage.P50Fps, 1), formatDelta(stage.P5Fps, 1),
+				formatDelta(stage.MinFps, 1), gpu)
+		}
+	}
+
+	fmt.Fprintf(writer, "\nRegression: score lower over the iterations with p < %g (Welch t-test) and by at least %g%%\n",
+		c.Options.Alpha, c.Options.MinChange)
+	if c.TotalPValue != nil {
+		fmt.Fprintf(writer, "Both runs were repeated (%d and %d iterations): scores are means over the iterations\n",
+			len(c.A.Iterations), len(c.B.Iterations))
+	} else {
+		fmt.Fprintln(writer, "Without at least two iterations in both runs there is no verdict, only the changes above")
+	}
+	return writer.Flush()
+}
+
+func describeRun(doc *rundoc.Document) string {
+	return fmt.Sprintf("%s  %s  %s  %s", doc.RunID, doc.CreatedAt.Local().Format("2006-01-02 15:04"),
+		doc.System.GpuName, doc.System.DriverVersion)
+}
+
+// " p=0.012" for scores compared over iterations
+func formatPValue(p *float64) string {
+	if p == nil {
+		return ""
+	}
+	return fmt.Sprintf(" p=%.3f", *p)
+}
+
+// "A -> B (+abs, +pct%)"
+func formatDelta(d Delta, precision int) string {
+	return fmt.Sprintf("%.*f -> %.*f (%+.*f, %+.1f%%)", precision, d.A, precision, d.B, precision, d.Abs, d.Pct)
+}
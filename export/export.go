@@ -0,0 +1,224 @@
+// Package export writes a run document in the formats other tools read: the JSON of the
+// upload schema, a long-format CSV of every sample, Markdown and the HTML report.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"moddergltest/report"
+	"moddergltest/rundoc"
+)
+
+// Formats
+const (
+	JSON     = "json"
+	CSV      = "csv"
+	Markdown = "md"
+	HTML     = "html"
+)
+
+// Formats in the order they are offered
+var Formats = []string{JSON, CSV, Markdown, HTML}
+
+// FormatOf picks the format from the extension of a file name
+func FormatOf(path string) (string, error) {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	switch ext {
+	case "markdown":
+		return Markdown, nil
+	case "htm":
+		return HTML, nil
+	case JSON, CSV, Markdown, HTML:
+		return ext, nil
+	}
+	return "", fmt.Errorf("unknown export format %q, expected one of %s", ext, strings.Join(Formats, ", "))
+}
+
+// Write writes the run in the given format
+func Write(w io.Writer, doc *rundoc.Document, format string) error {
+	switch format {
+	case JSON:
+		return WriteJSON(w, doc)
+	case CSV:
+		return WriteCSV(w, doc)
+	case Markdown:
+		return WriteMarkdown(w, doc)
+	case HTML:
+		return report.WriteHTML(w, doc)
+	}
+	return fmt.Errorf("unknown export format %q, expected one of %s", format, strings.Join(Formats, ", "))
+}
+
+// WriteFile writes the run to a file, in the format its extension names
+func WriteFile(path string, doc *rundoc.Document) error {
+	format, err := FormatOf(path)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := Write(file, doc, format); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// WriteJSON writes the run as the result that is uploaded, FPS histories included
+func WriteJSON(w io.Writer, doc *rundoc.Document) error {
+	data, err := json.MarshalIndent(doc.Result(), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// WriteCSV writes one row per sample of every test, with the run and test it belongs to
+func WriteCSV(w io.Writer, doc *rundoc.Document) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"run_id", "test", "kind", "load_label", "stage", "time", "load", "avg_fps", "min_fps"})
+	for _, name := range doc.TestNames() {
+		test := doc.Tests[name]
+		for _, sample := range test.Samples {
+			writer.Write([]string{
+				doc.RunID,
+				name,
+				test.Kind,
+				test.LoadLabel,
+				strconv.Itoa(sample.Stage),
+				strconv.FormatFloat(sample.Time, 'f', -1, 64),
+				strconv.FormatFloat(sample.Load, 'f', -1, 64),
+				strconv.FormatFloat(sample.AvgFps, 'f', -1, 64),
+				strconv.FormatFloat(sample.MinFps, 'f', -1, 64),
+			})
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteMarkdown writes the system, the scores and the average and minimum FPS and the bound of
+// every stage
+func WriteMarkdown(w io.Writer, doc *rundoc.Document) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# GLTest run %s\n\n", doc.RunID)
+	fmt.Fprintf(&b, "%s, suite %s", doc.CreatedAt.Format("2006-01-02 15:04:05 MST"), doc.Suite)
+	if doc.Incomplete {
+		b.WriteString(", **incomplete**")
+	}
+	if doc.Noisy {
+		fmt.Fprintf(&b, ", **noisy** (up to %.0f%% CPU busy with other work)", doc.Noise*100)
+	}
+	b.WriteString("\n\n")
+
+	system := doc.System
+	b.WriteString("| | |\n|---|---|\n")
+	for _, row := range [][2]string{
+		{"GPU", system.GpuName},
+		{"VRAM", system.VramSize},
+		{"Driver", system.DriverVersion},
+		{"CPU", system.CpuName},
+		{"RAM", system.RamSize},
+		{"Windows", system.WindowsVersion},
+		{"Wine", strconv.FormatBool(system.UsesWine)},
+	} {
+		fmt.Fprintf(&b, "| %s | %s |\n", row[0], escape(row[1]))
+	}
+
+	b.WriteString("\n## Scores\n\n")
+	b.WriteString("| Test | Kind | Score | Avg FPS | Min FPS | P99 frame (ms) |\n")
+	b.WriteString("|------|------|------:|--------:|--------:|---------------:|\n")
+	total, _ := doc.Score(rundoc.TotalScoreKey)
+	fmt.Fprintf(&b, "| **total** | | **%.2f** | | | |\n", total)
+	for _, name := range doc.TestNames() {
+		test := doc.Tests[name]
+		score, _ := doc.Score(name)
+		label := name
+		if test.Incomplete != "" {
+			label += " (" + test.Incomplete + ")"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %.2f | %.1f | %.1f | %.2f |\n",
+			label, test.Kind, score, test.AvgFps, test.MinFps, test.P99FrameTimeMs())
+	}
+	if len(doc.Iterations) > 0 {
+		fmt.Fprintf(&b, "\nScores are means over %d iterations.\n", len(doc.Iterations))
+	}
+
+	var powered []string
+	for _, name := range doc.TestNames() {
+		if doc.Tests[name].Power != nil {
+			powered = append(powered, name)
+		}
+	}
+	if len(powered) > 0 {
+		b.WriteString("\n## Power\n\n")
+		b.WriteString("| Test | Power (W) | CPU (W) | GPU (W) | Frames/J | Score/W |\n")
+		b.WriteString("|------|----------:|--------:|--------:|---------:|--------:|\n")
+		for _, name := range powered {
+			power := doc.Tests[name].Power
+			fmt.Fprintf(&b, "| %s | %.1f | %.1f | %.1f | %.2f | %.4f |\n",
+				name, power.Watts, power.CPUWatts, power.GPUWatts, power.FramesPerJoule, power.ScorePerWatt)
+		}
+		if perWatt := doc.ScorePerWatt(); perWatt > 0 {
+			fmt.Fprintf(&b, "\nTotal score per watt: %.4f\n", perWatt)
+		}
+	}
+
+	for _, name := range doc.TestNames() {
+		test := doc.Tests[name]
+		loadLabel := test.LoadLabel
+		if loadLabel == "" {
+			loadLabel = "Load"
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", name)
+		if process := test.Process; process != nil {
+			fmt.Fprintf(&b, "Test process: %.1f s user and %.1f s kernel CPU time (%.2f cores busy), peak resident memory %.0f MB.\n\n",
+				process.UserSec, process.SysSec, process.CPU(), process.PeakRSSMB)
+		}
+		bottlenecks := test.BottlenecksByStage()
+		if len(bottlenecks) > 0 {
+			fmt.Fprintf(&b, "Limited by: %s.\n\n", test.BottleneckSummary())
+			fmt.Fprintf(&b, "| Stage | %s | Samples | Avg FPS | Min FPS | Bound | Why |\n", escape(loadLabel))
+			b.WriteString("|------:|------:|--------:|--------:|--------:|-------|-----|\n")
+		} else {
+			fmt.Fprintf(&b, "| Stage | %s | Samples | Avg FPS | Min FPS |\n", escape(loadLabel))
+			b.WriteString("|------:|------:|--------:|--------:|--------:|\n")
+		}
+		byStage := test.SamplesByStage()
+		for _, stage := range test.Stages() {
+			samples := byStage[stage]
+			var sum, minimum float64
+			for i, sample := range samples {
+				sum += sample.AvgFps
+				if i == 0 || sample.MinFps < minimum {
+					minimum = sample.MinFps
+				}
+			}
+			fmt.Fprintf(&b, "| %d | %g | %d | %.1f | %.1f |",
+				stage, samples[0].Load, len(samples), sum/float64(len(samples)), minimum)
+			if len(bottlenecks) > 0 {
+				bottleneck := bottlenecks[stage]
+				fmt.Fprintf(&b, " %s | %s |", bottleneck.Label(), escape(bottleneck.Reason))
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Table cells must not break the row
+func escape(text string) string {
+	return strings.ReplaceAll(text, "|", "\\|")
+}
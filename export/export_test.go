@@ -0,0 +1,80 @@
+package export
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"moddergltest/rundoc"
+)
+
+// A run of ocean and shadows with two samples each, shadows stopped in its second stage
+func document() *rundoc.Document {
+	return &rundoc.Document{
+		RunID:      "6ba7b810-9dad-41d1-80b4-00c04fd430c8",
+		CreatedAt:  time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+		Suite:      rundoc.SuiteFull,
+		TotalScore: 45.5,
+		Tests: map[string]*rundoc.TestResult{
+			"shadows": {
+				Kind: rundoc.KindExtended, LoadLabel: "Lights", Score: 12.25, AvgFps: 75, MinFps: 40, Incomplete: "timeout",
+				Samples: []rundoc.Sample{
+					{Time: 0.5, Stage: 1, Load: 1, AvgFps: 100, MinFps: 80},
+					{Time: 10.5, Stage: 2, Load: 4, AvgFps: 50, MinFps: 40},
+				},
+			},
+			"ocean": {
+				Kind: rundoc.KindCore, LoadLabel: "Wave Octaves", Score: 45.5, AvgFps: 120.25, MinFps: 50,
+				Samples: []rundoc.Sample{
+					{Time: 0.5, Stage: 1, Load: 1, AvgFps: 144.5, MinFps: 100},
+					{Time: 1, Stage: 1, Load: 1, AvgFps: 96, MinFps: 50},
+				},
+			},
+		},
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var b strings.Builder
+	if err := WriteCSV(&b, document()); err != nil {
+		t.Fatal(err)
+	}
+	// Core tests first, one row per sample
+	want := `run_id,test,kind,load_label,stage,time,load,avg_fps,min_fps
+6ba7b810-9dad-41d1-80b4-00c04fd430c8,ocean,core,Wave Octaves,1,0.5,1,144.5,100
+6ba7b810-9dad-41d1-80b4-00c04fd430c8,ocean,core,Wave Octaves,1,1,1,96,50
+6ba7b810-9dad-41d1-80b4-00c04fd430c8,shadows,extended,Lights,1,0.5,1,100,80
+6ba7b810-9dad-41d1-80b4-00c04fd430c8,shadows,extended,Lights,2,10.5,4,50,40
+`
+	if b.String() != want {
+		t.Errorf("CSV\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestWriteMarkdownScores(t *testing.T) {
+	var b strings.Builder
+	if err := WriteMarkdown(&b, document()); err != nil {
+		t.Fatal(err)
+	}
+	markdown := b.String()
+	start := strings.Index(markdown, "## Scores\n")
+	end := strings.Index(markdown, "\n## ocean\n")
+	if start < 0 || end < start {
+		t.Fatalf("no score table in\n%s", markdown)
+	}
+	// The p99 frame time is of the slowest frame of every sample
+	want := `## Scores
+
+| Test | Kind | Score | Avg FPS | Min FPS | P99 frame (ms) |
+|------|------|------:|--------:|--------:|---------------:|
+| **total** | | **45.50** | | | |
+| ocean | core | 45.50 | 120.2 | 50.0 | 19.90 |
+| shadows (timeout) | extended | 12.25 | 75.0 | 40.0 | 24.88 |
+`
+	if got := markdown[start:end]; got != want {
+		t.Errorf("score table\n%s\nwant\n%s", got, want)
+	}
+	if !strings.HasPrefix(markdown, "# GLTest run 6ba7b810-9dad-41d1-80b4-00c04fd430c8\n\n2026-10-18 12:00:00 UTC, suite full\n") {
+		t.Errorf("heading\n%s", markdown[:start])
+	}
+}
@@ -0,0 +1,244 @@
+// Package history keeps every finished run on the machine, so runs can be listed, compared and
+// tagged long after the CSVs in tests/ were overwritten.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"moddergltest/rundoc"
+)
+
+// Run is one line of the store: the run document plus what was added to it afterwards
+type Run struct {
+	RunID    string           `json:"run_id"`
+	Time     time.Time        `json:"time"`
+	Suite    string           `json:"suite"`
+	Tags     []string         `json:"tags"`
+	Document *rundoc.Document `json:"document"`
+}
+
+// Store is a JSON-lines file with one run per line, oldest first. Adding a run appends a line;
+// tagging and deleting rewrite the file.
+type Store struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// Open uses the store at path, the file is created with the first run
+func Open(path string) *Store {
+	return &Store{Path: path}
+}
+
+// Default is the store in the user config directory
+func Default() (*Store, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return Open(filepath.Join(dir, "GLTest", "history.jsonl")), nil
+}
+
+// Add stores a finished run
+func (s *Store) Add(doc *rundoc.Document, tags ...string) error {
+	if doc.RunID == "" {
+		return fmt.Errorf("run has no ID")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runs, err := s.load()
+	if err != nil {
+		return err
+	}
+	for _, run := range runs {
+		if run.RunID == doc.RunID {
+			return fmt.Errorf("run %s is already stored", doc.RunID)
+		}
+	}
+
+	line, err := json.Marshal(&Run{
+		RunID:    doc.RunID,
+		Time:     doc.CreatedAt,
+		Suite:    doc.Suite,
+		Tags:     tags,
+		Document: doc,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// List returns the stored runs, newest first. With a tag only runs carrying it are returned.
+func (s *Store) List(tag string) ([]*Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runs, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	var listed []*Run
+	for _, run := range runs {
+		if tag == "" || slices.Contains(run.Tags, tag) {
+			listed = append(listed, run)
+		}
+	}
+	sort.SliceStable(listed, func(i, j int) bool { return listed[i].Time.After(listed[j].Time) })
+	return listed, nil
+}
+
+// Get finds a run by its ID or an unambiguous prefix of it, or by a tag carried by a single run
+func (s *Store) Get(id string) (*Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runs, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	index, err := find(runs, id)
+	if err != nil {
+		return nil, err
+	}
+	return runs[index], nil
+}
+
+// Tag adds tags to a run, tags it already has are kept once
+func (s *Store) Tag(id string, tags ...string) error {
+	return s.update(id, func(runs []*Run, index int) []*Run {
+		for _, tag := range tags {
+			if !slices.Contains(runs[index].Tags, tag) {
+				runs[index].Tags = append(runs[index].Tags, tag)
+			}
+		}
+		return runs
+	})
+}
+
+// Untag removes tags from a run
+func (s *Store) Untag(id string, tags ...string) error {
+	return s.update(id, func(runs []*Run, index int) []*Run {
+		runs[index].Tags = slices.DeleteFunc(runs[index].Tags, func(tag string) bool {
+			return slices.Contains(tags, tag)
+		})
+		return runs
+	})
+}
+
+// Delete removes a run from the store
+func (s *Store) Delete(id string) error {
+	return s.update(id, func(runs []*Run, index int) []*Run {
+		return slices.Delete(runs, index, index+1)
+	})
+}
+
+func (s *Store) update(id string, change func(runs []*Run, index int) []*Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runs, err := s.load()
+	if err != nil {
+		return err
+	}
+	index, err := find(runs, id)
+	if err != nil {
+		return err
+	}
+	return s.save(change(runs, index))
+}
+
+func find(runs []*Run, id string) (int, error) {
+	if id == "" {
+		return -1, fmt.Errorf("no run ID given")
+	}
+
+	found := -1
+	for i, run := range runs {
+		if run.RunID == id {
+			return i, nil
+		}
+		if strings.HasPrefix(run.RunID, id) || slices.Contains(run.Tags, id) {
+			if found >= 0 {
+				return -1, fmt.Errorf("%q matches more than one run", id)
+			}
+			found = i
+		}
+	}
+	if found < 0 {
+		return -1, fmt.Errorf("run %q not found", id)
+	}
+	return found, nil
+}
+
+func (s *Store) load() ([]*Run, error) {
+	file, err := os.Open(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var runs []*Run
+	scanner := bufio.NewScanner(file)
+	// Lines hold whole documents with their traces
+	scanner.Buffer(make([]byte, 0, 1024*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		run := &Run{}
+		if err := json.Unmarshal(scanner.Bytes(), run); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", s.Path, line, err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, scanner.Err()
+}
+
+// Rewrite the store through a temporary file, so a crash never loses the history
+func (s *Store) save(runs []*Run) error {
+	tmpPath := s.Path + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, run := range runs {
+		if err := encoder.Encode(run); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.Path)
+}
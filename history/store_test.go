@@ -0,0 +1,140 @@
+package history
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"moddergltest/rundoc"
+)
+
+func document(id string, created time.Time) *rundoc.Document {
+	return &rundoc.Document{RunID: id, CreatedAt: created, Suite: rundoc.SuiteFull, TotalScore: 90}
+}
+
+// A store with three runs added out of order, the middle one tagged "driver-24"
+func threeRuns(t *testing.T) *Store {
+	t.Helper()
+	store := Open(filepath.Join(t.TempDir(), "GLTest", "history.jsonl"))
+	day := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	for _, doc := range []*rundoc.Document{
+		document("aaaa1111-0000-4000-8000-000000000000", day),
+		document("bbbb2222-0000-4000-8000-000000000000", day.Add(2*time.Hour)),
+		document("aaaa3333-0000-4000-8000-000000000000", day.Add(time.Hour)),
+	} {
+		var tags []string
+		if strings.HasPrefix(doc.RunID, "aaaa3333") {
+			tags = []string{"driver-24"}
+		}
+		if err := store.Add(doc, tags...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func ids(runs []*Run) string {
+	var prefixes []string
+	for _, run := range runs {
+		prefixes = append(prefixes, run.RunID[:8])
+	}
+	return strings.Join(prefixes, ",")
+}
+
+func TestList(t *testing.T) {
+	store := threeRuns(t)
+	cases := []struct {
+		tag  string
+		want string
+	}{
+		{"", "bbbb2222,aaaa3333,aaaa1111"}, // newest first
+		{"driver-24", "aaaa3333"},
+		{"nobody", ""},
+	}
+	for _, c := range cases {
+		runs, err := store.List(c.tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ids(runs); got != c.want {
+			t.Errorf("List(%q) = %s, want %s", c.tag, got, c.want)
+		}
+	}
+	if err := store.Add(document("bbbb2222-0000-4000-8000-000000000000", time.Now())); err == nil {
+		t.Error("the same run was stored twice")
+	}
+	if err := store.Add(document("", time.Now())); err == nil {
+		t.Error("a run without an ID was stored")
+	}
+}
+
+func TestGet(t *testing.T) {
+	store := threeRuns(t)
+	cases := []struct {
+		id   string
+		want string // prefix of the run found, empty for an error
+	}{
+		{"bbbb2222-0000-4000-8000-000000000000", "bbbb2222"},
+		{"bbbb", "bbbb2222"},
+		{"aaaa1", "aaaa1111"},
+		{"aaaa", ""}, // two runs
+		{"driver-24", "aaaa3333"},
+		{"cccc", ""},
+		{"", ""},
+	}
+	for _, c := range cases {
+		run, err := store.Get(c.id)
+		switch {
+		case c.want == "" && err == nil:
+			t.Errorf("Get(%q) found %s, want an error", c.id, run.RunID)
+		case c.want != "" && err != nil:
+			t.Errorf("Get(%q): %v", c.id, err)
+		case c.want != "" && !strings.HasPrefix(run.RunID, c.want):
+			t.Errorf("Get(%q) = %s, want %s", c.id, run.RunID, c.want)
+		}
+	}
+}
+
+func TestTagAndDelete(t *testing.T) {
+	store := threeRuns(t)
+	if err := store.Tag("bbbb", "baseline", "driver-24", "baseline"); err != nil {
+		t.Fatal(err)
+	}
+	run, err := store.Get("bbbb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(run.Tags, []string{"baseline", "driver-24"}) {
+		t.Errorf("tags %v, want each once", run.Tags)
+	}
+	// Two runs carry the tag now, it no longer names one
+	if _, err := store.Get("driver-24"); err == nil {
+		t.Error("a tag of two runs named one of them")
+	}
+
+	if err := store.Untag("aaaa3333", "driver-24"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete("aaaa1"); err != nil {
+		t.Fatal(err)
+	}
+	runs, err := store.List("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids(runs) != "bbbb2222,aaaa3333" || len(runs[1].Tags) != 0 {
+		t.Errorf("runs %s, tags of aaaa3333 %v", ids(runs), runs[1].Tags)
+	}
+	// The rewritten store reads back the same and leaves no temporary file behind
+	if runs[0].Document.TotalScore != 90 || runs[0].Suite != rundoc.SuiteFull {
+		t.Errorf("run after the rewrite %+v", *runs[0])
+	}
+	if files, _ := filepath.Glob(filepath.Join(filepath.Dir(store.Path), "*")); len(files) != 1 {
+		t.Errorf("store directory holds %v", files)
+	}
+	if err := store.Delete("cccc"); err == nil {
+		t.Error("deleted a run that is not stored")
+	}
+}
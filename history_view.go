@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"moddergltest/compare"
+	"moddergltest/export"
+	"moddergltest/history"
+	"moddergltest/rundoc"
+)
+
+// History tab: the stored runs, the FPS charts of one of them, or of two side by side
+type historyView struct {
+	window       fyne.Window
+	runs         []*history.Run
+	list         *widget.List
+	detail       *fyne.Container
+	pinButton    *widget.Button
+	exportButton *widget.Button
+	current      *history.Run
+	pinned       *history.Run // compared with the current run
+}
+
+func newHistoryView(w fyne.Window) *historyView {
+	v := &historyView{window: w, detail: container.NewVBox()}
+	v.list = widget.NewList(
+		func() int { return len(v.runs) },
+		func() fyne.CanvasObject {
+			return container.NewGridWithColumns(5, widget.NewLabel(""), widget.NewLabel(""), widget.NewLabel(""), widget.NewLabel(""), widget.NewLabel(""))
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			run := v.runs[id]
+			cells := item.(*fyne.Container).Objects
+			cells[0].(*widget.Label).SetText(run.Time.Local().Format("2006-01-02 15:04"))
+			cells[1].(*widget.Label).SetText(run.Document.System.GpuName)
+			cells[2].(*widget.Label).SetText(run.Document.System.DriverVersion)
+			cells[3].(*widget.Label).SetText(formatScore(run.Document.Summary, rundoc.TotalScoreKey, run.Document.TotalScore))
+			cells[4].(*widget.Label).SetText(strings.TrimSpace(suiteLabel(run) + " " + strings.Join(run.Tags, ",")))
+		},
+	)
+	v.list.OnSelected = func(id widget.ListItemID) {
+		v.current = v.runs[id]
+		v.show()
+	}
+	v.pinButton = widget.NewButton("Compare With Another Run", func() {
+		if v.pinned != nil {
+			v.pinned = nil
+		} else {
+			v.pinned = v.current
+		}
+		v.show()
+	})
+	v.pinButton.Disable()
+	v.exportButton = widget.NewButton("Export...", v.export)
+	v.exportButton.Disable()
+	v.refresh()
+	return v
+}
+
+func (v *historyView) content() fyne.CanvasObject {
+	bold := fyne.TextStyle{Bold: true}
+	header := container.NewGridWithColumns(5,
+		widget.NewLabelWithStyle("Date", fyne.TextAlignLeading, bold),
+		widget.NewLabelWithStyle("GPU", fyne.TextAlignLeading, bold),
+		widget.NewLabelWithStyle("Driver", fyne.TextAlignLeading, bold),
+		widget.NewLabelWithStyle("Total", fyne.TextAlignLeading, bold),
+		widget.NewLabelWithStyle("Suite and tags", fyne.TextAlignLeading, bold),
+	)
+	buttons := container.NewHBox(widget.NewButton("Refresh", v.refresh), v.pinButton, v.exportButton)
+	runs := container.NewBorder(header, buttons, nil, nil, v.list)
+	split := container.NewVSplit(runs, container.NewVScroll(v.detail))
+	split.Offset = 0.35
+	return split
+}
+
+// Reload the runs from the history, e.g. after a run was added
+func (v *historyView) refresh() {
+	store, err := history.Default()
+	var runs []*history.Run
+	if err == nil {
+		runs, err = store.List("")
+	}
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("Failed to read run history: %v", err), v.window)
+	}
+	v.runs = runs
+	v.list.UnselectAll()
+	v.list.Refresh()
+	v.current, v.pinned = nil, nil
+	v.show()
+}
+
+// Select the newest run and compare it with the one before it
+func (v *historyView) compareNewest() {
+	v.refresh()
+	if len(v.runs) == 0 {
+		return
+	}
+	if len(v.runs) >= 2 {
+		v.pinned = v.runs[1]
+	}
+	v.list.Select(0)
+}
+
+// Save the selected run; the extension of the chosen file names the format
+func (v *historyView) export() {
+	run := v.current
+	if run == nil {
+		return
+	}
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil || writer == nil {
+			return
+		}
+		format, err := export.FormatOf(writer.URI().Name())
+		if err == nil {
+			err = export.Write(writer, run.Document, format)
+		}
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to export run: %v", err), v.window)
+		}
+	}, v.window)
+	save.SetFileName(fmt.Sprintf("run-%.8s.json", run.RunID))
+	save.SetFilter(storage.NewExtensionFileFilter([]string{".json", ".csv", ".md", ".html"}))
+	save.Show()
+}
+
+// Details of the selected run, next to the pinned one when two runs are compared
+func (v *historyView) show() {
+	v.detail.RemoveAll()
+	switch {
+	case v.current == nil:
+		v.pinButton.Disable()
+		v.exportButton.Disable()
+		v.detail.Add(widget.NewLabel("Select a run to see its FPS charts."))
+	case v.pinned == nil:
+		v.exportButton.Enable()
+		v.pinButton.SetText("Compare With Another Run")
+		v.pinButton.Enable()
+		v.detail.Add(runDetail(v.current))
+	case v.pinned == v.current:
+		v.pinButton.SetText("Stop Comparing")
+		v.detail.Add(widget.NewLabel(fmt.Sprintf("Select a run to compare with %.8s.", v.pinned.RunID)))
+		v.detail.Add(runDetail(v.pinned))
+	default:
+		v.pinButton.SetText("Stop Comparing")
+		var text strings.Builder
+		compare.Runs(v.pinned.Document, v.current.Document, compare.Options{}).WriteText(&text)
+		summary := widget.NewTextGrid()
+		summary.SetText(text.String())
+		v.detail.Add(widget.NewAccordion(widget.NewAccordionItem("Comparison", summary)))
+		v.detail.Add(runDetail(v.pinned, v.current))
+	}
+	v.detail.Refresh()
+}
+
+// Runs in columns: what they ran on, then every test with its FPS over time. Charts of the
+// same test share their scale.
+func runDetail(runs ...*history.Run) fyne.CanvasObject {
+	rows := container.NewVBox()
+	header := container.NewGridWithColumns(len(runs))
+	var names []string
+	for _, run := range runs {
+		doc := run.Document
+		total := formatScore(doc.Summary, rundoc.TotalScoreKey, doc.TotalScore)
+		header.Add(widget.NewLabel(fmt.Sprintf("Run %.8s, %s, suite %s\n%s\n%s\nTotal score %s",
+			run.RunID, run.Time.Local().Format("2006-01-02 15:04"), run.Suite,
+			doc.System.GpuName, doc.System.DriverVersion, total)))
+		for _, name := range doc.TestNames() {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	rows.Add(header)
+	rows.Add(widget.NewLabel("FPS over the time of each test: average in blue, minimum in red."))
+
+	for _, name := range names {
+		maxFps, maxTime := 0.0, 0.0
+		for _, run := range runs {
+			if test := run.Document.Tests[name]; test != nil {
+				for _, sample := range test.Samples {
+					maxFps = math.Max(maxFps, sample.AvgFps)
+					maxTime = math.Max(maxTime, sample.Time)
+				}
+			}
+		}
+		top := math.Ceil(maxFps*1.1/10) * 10
+
+		row := container.NewGridWithColumns(len(runs))
+		for _, run := range runs {
+			row.Add(testChart(run.Document, name, maxTime, top))
+		}
+		rows.Add(widget.NewSeparator())
+		rows.Add(row)
+	}
+	return rows
+}
+
+// "Limited by" and the bounds of every test of a run that has any, one test per line
+func bottleneckText(doc *rundoc.Document) string {
+	var lines []string
+	for _, name := range doc.TestNames() {
+		if bounds := doc.Tests[name].BottleneckSummary(); bounds != "" {
+			lines = append(lines, fmt.Sprintf("%s: %s", name, bounds))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "Limited by\n" + strings.Join(lines, "\n")
+}
+
+// A test of a run with its score and the chart of its samples
+func testChart(doc *rundoc.Document, name string, maxTime, maxFps float64) fyne.CanvasObject {
+	test := doc.Tests[name]
+	if test == nil {
+		return widget.NewLabel(name + ": not in this run")
+	}
+	text := fmt.Sprintf("%s: score %s, average %.1f FPS, minimum %.1f FPS, up to %.0f FPS",
+		name, formatScore(doc.Summary, name, test.Score), test.AvgFps, test.MinFps, maxFps)
+	if test.Incomplete != "" {
+		text += " (" + test.Incomplete + ")"
+	}
+	if bounds := test.BottleneckSummary(); bounds != "" {
+		text += "\nLimited by: " + bounds
+	}
+
+	average := chartLine{Color: chartAverage}
+	minimum := chartLine{Color: chartSlowest}
+	for _, sample := range test.Samples {
+		average.X = append(average.X, sample.Time)
+		average.Y = append(average.Y, sample.AvgFps)
+		minimum.X = append(minimum.X, sample.Time)
+		minimum.Y = append(minimum.Y, sample.MinFps)
+	}
+	chart := canvas.NewRaster(func(w, h int) image.Image {
+		return drawChart(w, h, []chartLine{minimum, average}, maxTime, maxFps, 60)
+	})
+	chart.SetMinSize(fyne.NewSize(360, 120))
+	return container.NewVBox(widget.NewLabel(text), chart)
+}
+
+// Suite of a stored run, flagged when other work kept the machine busy
+func suiteLabel(run *history.Run) string {
+	if run.Document.Noisy {
+		return run.Suite + " (noisy)"
+	}
+	return run.Suite
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"moddergltest/progress"
+)
+
+// Samples kept in the live chart, half a second each
+const liveSamples = 60
+
+// Live view of a running benchmark: overall progress, where the current test is and its frame times
+type liveView struct {
+	bar     *widget.ProgressBar
+	status  *widget.Label
+	chart   *canvas.Raster
+	caption *widget.Label
+
+	mu       sync.Mutex
+	total    int // tests in the whole run
+	index    int // current test, counted from 1
+	test     string
+	duration float64
+	stages   int
+	stage    int
+	about    string
+	samples  []progress.Event
+}
+
+func newLiveView() *liveView {
+	v := &liveView{
+		bar:     widget.NewProgressBar(),
+		status:  widget.NewLabel(""),
+		caption: widget.NewLabel(""),
+	}
+	v.chart = canvas.NewRaster(v.draw)
+	v.chart.SetMinSize(fyne.NewSize(400, 120))
+	return v
+}
+
+func (v *liveView) container() fyne.CanvasObject {
+	return container.NewVBox(v.bar, v.status, v.chart, v.caption)
+}
+
+// Prepare for a run of the given number of tests
+func (v *liveView) start(total int) {
+	v.mu.Lock()
+	v.total, v.index, v.test, v.samples = total, 0, "", nil
+	v.mu.Unlock()
+	v.bar.SetValue(0)
+	v.status.SetText("")
+	v.caption.SetText("")
+	v.chart.Refresh()
+}
+
+// Clear the view once the run is over
+func (v *liveView) finish() {
+	v.mu.Lock()
+	v.test = ""
+	v.mu.Unlock()
+	v.bar.SetValue(1)
+	v.status.SetText("")
+}
+
+// Progress of a test, called from the goroutine that runs it
+func (v *liveView) handle(test string, event progress.Event) {
+	v.mu.Lock()
+	switch event.Kind {
+	case progress.Launched:
+		v.index++
+		v.test, v.duration, v.stages, v.stage, v.about = test, 0, 0, 0, ""
+		v.samples = nil
+	case progress.Started:
+		v.duration, v.stages = event.Duration, event.Stages
+	case progress.Stage:
+		v.stage, v.about = event.Stage, event.Description
+	case progress.Sample:
+		v.stage = event.Stage
+		v.samples = append(v.samples, event)
+		if len(v.samples) > liveSamples {
+			v.samples = v.samples[len(v.samples)-liveSamples:]
+		}
+	}
+	status, value, caption := v.describe(event)
+	v.mu.Unlock()
+
+	v.status.SetText(status)
+	v.bar.SetValue(value)
+	if event.Kind == progress.Sample || event.Kind == progress.Launched {
+		v.caption.SetText(caption)
+		v.chart.Refresh()
+	}
+}
+
+// Status line, overall progress and chart caption, with v.mu held
+func (v *liveView) describe(event progress.Event) (string, float64, string) {
+	status := v.test
+	if v.total > 0 {
+		status += fmt.Sprintf(" (%d of %d)", v.index, v.total)
+	}
+	done := 0.0
+	switch {
+	case event.Kind == progress.Preflight && event.Description != "":
+		status += ": system busy, " + event.Description
+	case event.Kind == progress.Preflight:
+		status += ": checking system load"
+	case event.Kind == progress.Warmup:
+		status += ": warming up"
+	case v.stage > 0:
+		status += fmt.Sprintf(": stage %d", v.stage)
+		if v.stages > 0 {
+			status += fmt.Sprintf(" of %d", v.stages)
+		}
+		if v.about != "" {
+			status += ", " + v.about
+		}
+		if len(v.samples) > 0 {
+			last := v.samples[len(v.samples)-1]
+			if v.duration > 0 {
+				status += fmt.Sprintf(", %.1f s of %.0f s", last.Elapsed, v.duration)
+				done = math.Min(last.Elapsed/v.duration, 1)
+			}
+			status += fmt.Sprintf(", %.1f FPS", last.AvgFps)
+		}
+	default:
+		status += ": starting"
+	}
+
+	value := 0.0
+	if v.total > 0 {
+		value = math.Min((float64(v.index-1)+done)/float64(v.total), 1)
+	}
+
+	caption := ""
+	if len(v.samples) > 0 {
+		caption = fmt.Sprintf("Frame time of %s, last %.0f s: average (blue) and slowest frame (red), up to %.0f ms", v.test, float64(len(v.samples))/2, v.scale())
+	}
+	return status, value, caption
+}
+
+// Top of the chart in milliseconds, at least one 30 FPS frame
+func (v *liveView) scale() float64 {
+	top := 1000.0 / 30
+	for _, sample := range v.samples {
+		if sample.MinFps > 0 {
+			top = math.Max(top, 1000/sample.MinFps)
+		}
+	}
+	return math.Ceil(top/10) * 10
+}
+
+var (
+	chartBackground = color.NRGBA{R: 0xf9, G: 0xfa, B: 0xfb, A: 0xff}
+	chartGrid       = color.NRGBA{R: 0xd1, G: 0xd5, B: 0xdb, A: 0xff}
+	chartAverage    = color.NRGBA{R: 0x25, G: 0x63, B: 0xeb, A: 0xff}
+	chartSlowest    = color.NRGBA{R: 0xdc, G: 0x26, B: 0x26, A: 0xff}
+)
+
+// chartLine is one line of a raster chart, its points in chart units
+type chartLine struct {
+	X, Y  []float64
+	Color color.Color
+}
+
+// Raster of lines over 0..maxX and 0..maxY, with a dashed line at every mark on the Y axis.
+// Points with a Y of 0 or less are left out.
+func drawChart(w, h int, lines []chartLine, maxX, maxY float64, marks ...float64) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: chartBackground}, image.Point{}, draw.Src)
+	if w < 2 || h < 2 || maxX <= 0 || maxY <= 0 {
+		return img
+	}
+
+	px := func(x float64) int {
+		return int(math.Min(x/maxX, 1) * float64(w-1))
+	}
+	py := func(y float64) int {
+		return h - 1 - int(math.Min(y/maxY, 1)*float64(h-1))
+	}
+	for _, mark := range marks {
+		for x, y := 0, py(mark); x < w; x += 2 {
+			img.Set(x, y, chartGrid)
+		}
+	}
+	for _, line := range lines {
+		lastX, lastY := -1, 0
+		for i := range line.X {
+			if line.Y[i] <= 0 {
+				continue
+			}
+			x, y := px(line.X[i]), py(line.Y[i])
+			if lastX < 0 {
+				lastX, lastY = x, y
+			}
+			plotLine(img, lastX, lastY, x, y, line.Color)
+			lastX, lastY = x, y
+		}
+	}
+	return img
+}
+
+// Raster of the live chart: frame times of the kept samples, a line at 60 FPS. Samples fill
+// the chart from the left, the newest on the right once it is full.
+func (v *liveView) draw(w, h int) image.Image {
+	v.mu.Lock()
+	average := chartLine{Color: chartAverage}
+	slowest := chartLine{Color: chartSlowest}
+	for i, sample := range v.samples {
+		average.X = append(average.X, float64(i))
+		slowest.X = append(slowest.X, float64(i))
+		average.Y = append(average.Y, frameMs(sample.AvgFps))
+		slowest.Y = append(slowest.Y, frameMs(sample.MinFps))
+	}
+	top := v.scale()
+	v.mu.Unlock()
+
+	return drawChart(w, h, []chartLine{slowest, average}, liveSamples-1, top, 1000.0/60)
+}
+
+// Frame time in milliseconds at the given FPS, 0 when there is none
+func frameMs(fps float64) float64 {
+	if fps <= 0 {
+		return 0
+	}
+	return 1000 / fps
+}
+
+// Straight line between two pixels, two pixels thick
+func plotLine(img *image.NRGBA, x0, y0, x1, y1 int, c color.Color) {
+	steps := max(abs(x1-x0), abs(y1-y0), 1)
+	for i := 0; i <= steps; i++ {
+		x := x0 + (x1-x0)*i/steps
+		y := y0 + (y1-y0)*i/steps
+		img.Set(x, y, c)
+		img.Set(x, y+1, c)
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
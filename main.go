@@ -3,7 +3,11 @@ package main
 import (
 	"bytes"
 	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"fyne.io/fyne/v2"
     "fyne.io/fyne/v2/app"
     "fyne.io/fyne/v2/container"
@@ -11,15 +15,29 @@ import (
     "fyne.io/fyne/v2/layout"
     "fyne.io/fyne/v2/widget"
 	"log"
+	"maps"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"syscall"
 	"strings"
+	"sync"
 	"time"
 	"unsafe"
+
+	"moddergltest/history"
+	"moddergltest/noise"
+	"moddergltest/progress"
+	"moddergltest/report"
+	"moddergltest/rundoc"
+	"moddergltest/sensors"
+	"moddergltest/submit"
+	"moddergltest/sysinfo"
+	"moddergltest/usage"
 )
 
 // Windows API structures
@@ -90,36 +108,477 @@ type BenchmarkResults struct {
 	TrianglesScore float64
 	OceanScore     float64
 	TotalScore     float64
+	// Extended tests, reported separately from TotalScore
+	PostFXScore    float64
+	ShadowsScore   float64
+	ParticlesScore float64
+	// User-loadable shader tests by name, also outside TotalScore
+	CustomScores map[string]float64
+	// Every scored test with its samples, for the run document
+	Tests map[string]*rundoc.TestResult
+}
+
+// Remove the CSVs and screenshots of the given tests, so that a test which stops before
+// writing its own is never scored on what an earlier run left behind
+func clearTestOutput(names []string) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return
+	}
+	testsDir := filepath.Join(filepath.Dir(exePath), "tests")
+	for _, name := range names {
+		for _, ext := range []string{".csv", ".png"} {
+			if err := os.Remove(filepath.Join(testsDir, name+ext)); err != nil && !os.IsNotExist(err) {
+				log.Printf("Failed to remove old output of %s: %v", name, err)
+			}
+		}
+	}
 }
 
 // Run tests
-func runTest(testName string) error {
+func runTest(testName string, args ...string) error {
+	return newTestRunner(nil, 0).run(testName, testName, args...)
+}
+
+// Without a timeout set, a test may run this much longer than the duration it announces.
+// Tests that announce none get twice as long in total.
+const testTimeoutMargin = 2 * time.Minute
+
+// Time a test has to exit after it was asked to stop, before it is killed
+const testStopGrace = 10 * time.Second
+
+// What the runner does when other work keeps the system busy before a test
+const (
+	noiseWarn  = "warn"  // run the test all the same, its result is flagged as noisy
+	noiseAbort = "abort" // fail the run
+	noiseOff   = "off"   // do not look
+)
+
+// Time the system load is watched before every test
+const noisePreflight = 3 * time.Second
+
+// testStopped is the error of a test that was stopped before its end
+type testStopped struct {
+	Reason string // rundoc.StopSkipped, StopTimeout or StopCancelled
+}
+
+func (e *testStopped) Error() string {
+	return "stopped early: " + e.Reason
+}
+
+// testRunner launches tests one at a time and can skip the running one or cancel the run
+type testRunner struct {
+	onProgress func(test string, event progress.Event) // nil when nobody is watching
+	timeout    time.Duration                            // per test, 0 for one based on its announced duration
+	sensors    *sensors.Sensors                         // temperatures and clocks sampled while a test runs
+	noise      string                                   // noiseWarn, noiseAbort or noiseOff
+
+	mu        sync.Mutex
+	cancelled chan struct{}       // closed by Cancel
+	stopped   bool                // a test was stopped before its end
+	stop      func(reason string) // stops the running test, nil between tests
+	readings  map[string][]rundoc.SensorSample
+	loads     map[string]*rundoc.Noise
+	processes map[string]testProcess
+}
+
+// What the process of a test used, over the whole test and by stage
+type testProcess struct {
+	total  *rundoc.ProcessUsage
+	stages []rundoc.ProcessUsage
+}
+
+func newTestRunner(onProgress func(test string, event progress.Event), timeout time.Duration) *testRunner {
+	return &testRunner{
+		onProgress: onProgress,
+		timeout:    timeout,
+		sensors:    sensors.Discover(sensors.Root()),
+		noise:      noiseWarn,
+		cancelled:  make(chan struct{}),
+		readings:   make(map[string][]rundoc.SensorSample),
+		loads:      make(map[string]*rundoc.Noise),
+		processes:  make(map[string]testProcess),
+	}
+}
+
+// Sensor readings of the last run of a test, timed like its samples
+func (r *testRunner) Sensors(name string) []rundoc.SensorSample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.readings[name]
+}
+
+// Load of other work around the last run of a test, nil when it was not looked at
+func (r *testRunner) Noise(name string) *rundoc.Noise {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.loads[name]
+}
+
+// Usage of the process of the last run of a test, nil when it could not be read
+func (r *testRunner) Process(name string) (*rundoc.ProcessUsage, []rundoc.ProcessUsage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	process := r.processes[name]
+	return process.total, process.stages
+}
+
+// Skip stops the running test, the run goes on with the next one
+func (r *testRunner) Skip() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop != nil {
+		r.stop(rundoc.StopSkipped)
+	}
+}
+
+// Cancel stops the running test and no further test is started
+func (r *testRunner) Cancel() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.Cancelled() {
+		close(r.cancelled)
+	}
+	if r.stop != nil {
+		r.stop(rundoc.StopCancelled)
+	}
+}
+
+func (r *testRunner) Cancelled() bool {
+	select {
+	case <-r.cancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// Incomplete is true once a test was stopped early or the run was cancelled
+func (r *testRunner) Incomplete() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stopped || r.Cancelled()
+}
+
+// Sleep for the given time, or until the run is cancelled
+func (r *testRunner) Sleep(d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-r.cancelled:
+	}
+}
+
+// Usage of a test process that ran for the given time. The CPU times come from the exited
+// process, the other counters from the last time it was read while running.
+func processUsage(tracker *usage.Tracker, state *os.ProcessState, elapsed time.Duration) testProcess {
+	last, ok := tracker.Last()
+	if !ok || state == nil {
+		return testProcess{}
+	}
+	convert := func(stage int, seconds float64, u usage.Usage) rundoc.ProcessUsage {
+		return rundoc.ProcessUsage{
+			Stage:               stage,
+			Seconds:             seconds,
+			UserSec:             u.UserSec,
+			SysSec:              u.SysSec,
+			PeakRSSMB:           u.PeakRSSMB,
+			VoluntarySwitches:   u.VoluntarySwitches,
+			InvoluntarySwitches: u.InvoluntarySwitches,
+			MinorFaults:         u.MinorFaults,
+			MajorFaults:         u.MajorFaults,
+		}
+	}
+	
+	total := convert(0, elapsed.Seconds(), last)
+	total.UserSec, total.SysSec = state.UserTime().Seconds(), state.SystemTime().Seconds()
+	process := testProcess{total: &total}
+	for _, stage := range tracker.Stages() {
+		process.stages = append(process.stages, convert(stage.Stage, stage.Seconds, stage.Usage))
+	}
+	return process
+}
+
+// Watch the load of other work for noisePreflight before a test. False when it is not looked
+// at; an error when the run is cancelled meanwhile, or the system is busy and the runner aborts.
+func (r *testRunner) preflight(name string) (noise.Load, bool, error) {
+	before := noise.Take(sensors.Root())
+	if r.noise == noiseOff || !before.Available() {
+		return noise.Load{}, false, nil
+	}
+	if r.onProgress != nil {
+		r.onProgress(name, progress.Event{Kind: progress.Preflight})
+	}
+	r.Sleep(noisePreflight)
+	if r.Cancelled() {
+		return noise.Load{}, false, &testStopped{Reason: rundoc.StopCancelled}
+	}
+	
+	load, ok := noise.Between(before, noise.Take(sensors.Root()), 0)
+	if ok && load.Busy() {
+		if r.noise == noiseAbort {
+			return load, ok, fmt.Errorf("the system is busy: %s", load)
+		}
+		log.Printf("System busy before %s: %s", name, load)
+		if r.onProgress != nil {
+			r.onProgress(name, progress.Event{Kind: progress.Preflight, Description: load.String()})
+		}
+	}
+	return load, ok, nil
+}
+
+// Run a test and report its progress under the given name. A test asked to stop gets
+// testStopGrace to leave its loop, which keeps its CSV intact, and is killed after that.
+func (r *testRunner) run(name string, testName string, args ...string) error {
 	exePath, err := os.Executable()
 	if err != nil {
 		return err
 	}
 	testsDir := filepath.Join(filepath.Dir(exePath), "tests")
 	
+	if r.onProgress != nil {
+		r.onProgress(name, progress.Event{Kind: progress.Launched})
+	}
+	
+	// Other work skews the result: the system has to be quiet before the test starts, and
+	// is watched while it runs
+	preflight, checked, err := r.preflight(name)
+	if err != nil {
+		return err
+	}
+	
 	// Run benchmark
-	cmd := exec.Command(filepath.Join(testsDir, testName+".exe"))
+	cmd := exec.Command(filepath.Join(testsDir, testName+".exe"), args...)
 	cmd.Dir = testsDir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
 	
 	// Start testing process
 	if err := cmd.Start(); err != nil {
 		return err
 	}
+	started := noise.Take(sensors.Root())
+	tracker := usage.NewTracker(cmd.Process.Pid)
+	var sampler *sensors.Sampler
+	if !r.sensors.Empty() {
+		sampler = r.sensors.Start(sensors.Interval)
+	}
+	
+	// Asking again does nothing, the first reason is the one reported. Once the test has
+	// exited there is nothing left to stop.
+	var stopMu sync.Mutex
+	var reason string
+	var exited bool
+	var kill *time.Timer
+	stop := func(why string) {
+		stopMu.Lock()
+		defer stopMu.Unlock()
+		if reason != "" || exited {
+			return
+		}
+		reason = why
+		io.WriteString(stdin, "stop\n")
+		// A test stuck in the driver never reads it
+		kill = time.AfterFunc(testStopGrace, func() {
+			cmd.Process.Kill()
+		})
+	}
+	
+	r.mu.Lock()
+	r.stop = stop
+	if r.Cancelled() {
+		stop(rundoc.StopCancelled)
+	}
+	r.mu.Unlock()
+	
+	timeout := r.timeout
+	if timeout == 0 {
+		timeout = 2 * testTimeoutMargin
+	}
+	deadline := time.AfterFunc(timeout, func() {
+		stop(rundoc.StopTimeout)
+	})
+	
+	// The output has to be read to its end before waiting. The first sample tells when the
+	// measurement started, the time the sensor readings are timed from.
+	var measured time.Time
+	err = progress.Watch(stdout, func(event progress.Event) {
+		if event.Kind == progress.Sample && measured.IsZero() {
+			measured = time.Now().Add(-time.Duration(event.Elapsed * float64(time.Second)))
+		}
+		// The process is read at the stage boundaries and samples, while it still runs
+		switch event.Kind {
+		case progress.Stage:
+			tracker.StartStage(event.Stage)
+		case progress.Sample:
+			tracker.Sample(event.Stage)
+		}
+		if event.Kind == progress.Started && r.timeout == 0 {
+			deadline.Reset(time.Duration(event.Duration*float64(time.Second)) + testTimeoutMargin)
+		}
+		if r.onProgress != nil {
+			r.onProgress(name, event)
+		}
+	})
+	if err != nil {
+		log.Printf("Failed to follow test %s: %v", testName, err)
+		io.Copy(io.Discard, stdout)
+	}
+	waitErr := cmd.Wait()
+	// A test that ended on its own must not time out while its results are collected
+	deadline.Stop()
+	process := processUsage(tracker, cmd.ProcessState, time.Since(started.At))
+	
+	// The CPU time of the test itself is not noise
+	var load *rundoc.Noise
+	if checked && cmd.ProcessState != nil {
+		own := cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime()
+		during, _ := noise.Between(started, noise.Take(sensors.Root()), own)
+		load = &rundoc.Noise{
+			PreflightCPU:    preflight.CPU,
+			PreflightMemory: preflight.Memory,
+			CPU:             during.CPU,
+			Memory:          during.Memory,
+			Noisy:           preflight.Busy() || during.Busy(),
+		}
+	}
+	
+	var readings []rundoc.SensorSample
+	if sampler != nil {
+		samples := sampler.Stop()
+		// A test that never sampled has no time axis to put the readings on
+		if !measured.IsZero() {
+			for _, sample := range samples {
+				readings = append(readings, rundoc.SensorSample{
+					Time:       sample.At.Sub(measured).Seconds(),
+					GPUTempC:   sample.GPUTempC,
+					CPUTempC:   sample.CPUTempC,
+					GPUMHz:     sample.GPUMHz,
+					CPUMHz:     sample.CPUMHz,
+					Temps:      sample.Temps,
+					CPUEnergyJ: sample.CPUEnergyJ,
+					GPUWatts:   sample.GPUWatts,
+				})
+			}
+		}
+	}
 	
-	return cmd.Wait()
+	// Skip and Cancel take r.mu before stopMu, so the two are never held together here
+	stopMu.Lock()
+	exited = true
+	stopped := reason
+	if kill != nil {
+		kill.Stop()
+	}
+	stopMu.Unlock()
+	r.mu.Lock()
+	r.stop = nil
+	r.stopped = r.stopped || stopped != ""
+	r.readings[name] = readings
+	r.loads[name] = load
+	r.processes[name] = process
+	r.mu.Unlock()
+	if stopped != "" {
+		return &testStopped{Reason: stopped}
+	}
+	
+	// Tests report what went wrong on stderr, e.g. a shader info log
+	if waitErr != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%v: %s", waitErr, msg)
+		}
+		return waitErr
+	}
+	return nil
+}
+
+// Custom shader test, a tests/ subdirectory with a manifest.json run by custom.exe
+type CustomTest struct {
+	Name            string
+	Title           string
+	Dir             string
+	NormalizeFactor float64
+}
+
+// Find custom shader tests next to the built-in ones
+func discoverCustomTests() ([]CustomTest, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	testsDir := filepath.Join(filepath.Dir(exePath), "tests")
+
+	manifests, err := filepath.Glob(filepath.Join(testsDir, "*", "manifest.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	builtin := map[string]bool{"butterfly": true, "triangles": true, "ocean": true, "postfx": true,
+		"shadows": true, "particles": true, "alu": true, "memory": true, "custom": true}
+	seen := make(map[string]bool)
+
+	var customTests []CustomTest
+	for _, manifestPath := range manifests {
+		data, err := os.ReadFile(manifestPath)
+		if err != nil {
+			log.Printf("Skipping custom test %s: %v", manifestPath, err)
+			continue
+		}
+		var manifest struct {
+			Name      string  `json:"name"`
+			Title     string  `json:"title"`
+			Normalize float64 `json:"normalize"`
+		}
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			log.Printf("Skipping custom test %s: %v", manifestPath, err)
+			continue
+		}
+
+		dir := filepath.Dir(manifestPath)
+		test := CustomTest{
+			Name:            manifest.Name,
+			Title:           manifest.Title,
+			Dir:             dir,
+			NormalizeFactor: manifest.Normalize,
+		}
+		if test.Name == "" {
+			test.Name = filepath.Base(dir)
+		}
+		if test.Title == "" {
+			test.Title = test.Name
+		}
+		// The CSV is named after the test, so names must not collide
+		if builtin[test.Name] || seen[test.Name] {
+			log.Printf("Skipping custom test %s: name %q is already taken", manifestPath, test.Name)
+			continue
+		}
+		seen[test.Name] = true
+		customTests = append(customTests, test)
+	}
+
+	return customTests, nil
 }
 
-// Analyze CSV
-func parseResultsAndCalculateScore() (BenchmarkResults, error) {
+// Analyze CSV of the selected tests. Tests stopped early are scored on what they measured, or
+// left out when they stopped before their first sample. Only a core test that cannot be scored
+// is an error, an extended one is reported to onError and left out.
+func parseResultsAndCalculateScore(selected map[string]bool, stopped map[string]string, onError func(error)) (BenchmarkResults, error) {
 	results := BenchmarkResults{}
 	
 	tests := map[string]float64{
 		"butterfly": 16384000,
 		"ocean":     6,
 		"triangles": 10000000,
+		"postfx":    26,
+		"shadows":   100.66,
+		"particles": 4096000,
 	}
 	
 	exePath, err := os.Executable()
@@ -129,65 +588,44 @@ func parseResultsAndCalculateScore() (BenchmarkResults, error) {
 	testsDir := filepath.Join(filepath.Dir(exePath), "tests")
 	
 	// Tests processing
+	results.Tests = make(map[string]*rundoc.TestResult)
 	for testName, normalizeFactor := range tests {
-		csvPath := filepath.Join(testsDir, testName+".csv")
-		
-		// Opening CSV
-		file, err := os.Open(csvPath)
-		if err != nil {
-			return results, fmt.Errorf("Failed to open file %s: %v", csvPath, err)
+		if !selected[testName] {
+			continue
 		}
-		defer file.Close()
-		
-		// Reading CSV
-		reader := csv.NewReader(file)
-		reader.Comma = ',' 
-		rows, err := reader.ReadAll()
-		if err != nil {
-			return results, fmt.Errorf("Error reading CSV %s: %v", csvPath, err)
+		kind := rundoc.KindExtended
+		if testName == "butterfly" || testName == "triangles" || testName == "ocean" {
+			kind = rundoc.KindCore
 		}
-		
-		// Skip header
-		if len(rows) <= 1 {
-			return results, fmt.Errorf("Insufficient data in file %s", csvPath)
+		test, err := scoreTest(filepath.Join(testsDir, testName+".csv"), kind, normalizeFactor)
+		if _, ok := stopped[testName]; ok && err != nil {
+			continue
 		}
-		rows = rows[1:]
-		
-		// Calculate average
-		var avgFpsSum, minFpsSum, avgLoadSum float64
-		for _, row := range rows {
-			if len(row) < 5 {
-				continue
-			}
-			
-			avgFps, _ := strconv.ParseFloat(row[3], 64)
-			minFps, _ := strconv.ParseFloat(row[4], 64)
-			particles, _ := strconv.ParseFloat(row[2], 64)
-			
-			avgFpsSum += avgFps
-			minFpsSum += minFps
-			avgLoadSum += particles
+		if err != nil && kind == rundoc.KindExtended {
+			onError(fmt.Errorf("Failed to analyze test %s: %v", testName, err))
+			continue
+		}
+		if err != nil {
+			return results, err
 		}
+		test.Incomplete = stopped[testName]
+		results.Tests[testName] = test
+		score := test.Score
 		
-		// Calculate
-		rowCount := float64(len(rows))
-		if rowCount > 0 {
-			avgFps := avgFpsSum / rowCount
-			minFps := minFpsSum / rowCount
-			avgLoad := avgLoadSum / rowCount
-			
-			// Formula
-			score := ((avgFps*0.7 + minFps*0.3) * avgLoad) / normalizeFactor
-			
-			// Save result
-			switch testName {
-			case "butterfly":
-				results.ButterflyScore = score
-			case "triangles":
-				results.TrianglesScore = score
-			case "ocean":
-				results.OceanScore = score
-			}
+		// Save result
+		switch testName {
+		case "butterfly":
+			results.ButterflyScore = score
+		case "triangles":
+			results.TrianglesScore = score
+		case "ocean":
+			results.OceanScore = score
+		case "postfx":
+			results.PostFXScore = score
+		case "shadows":
+			results.ShadowsScore = score
+		case "particles":
+			results.ParticlesScore = score
 		}
 	}
 	
@@ -197,19 +635,510 @@ func parseResultsAndCalculateScore() (BenchmarkResults, error) {
 	return results, nil
 }
 
+// Score of a custom test; without a normalize factor in the manifest the highest stage load is used
+func calculateCustomScore(test CustomTest) (*rundoc.TestResult, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	csvPath := filepath.Join(filepath.Dir(exePath), "tests", test.Name+".csv")
+	return scoreTest(csvPath, rundoc.KindCustom, test.NormalizeFactor)
+}
+
+// Score a test CSV with Time, Stage, Load, Avg FPS and Min FPS columns
+func scoreTest(csvPath string, kind string, normalizeFactor float64) (*rundoc.TestResult, error) {
+	test, err := rundoc.ReadTestCSV(csvPath, kind)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := test.CalculateScore(normalizeFactor); err != nil {
+		return nil, fmt.Errorf("%v in file %s", err, csvPath)
+	}
+	
+	// The screenshot next to the CSV is only for reports
+	screenshotPath := strings.TrimSuffix(csvPath, ".csv") + ".png"
+	if screenshot, err := rundoc.ReadScreenshot(screenshotPath); err == nil {
+		test.Screenshot = screenshot
+	} else if !os.IsNotExist(err) {
+		log.Printf("Failed to read screenshot %s: %v", screenshotPath, err)
+	}
+	return test, nil
+}
+
+// Read the GL capabilities written by the memory probe
+func parseGLCaps() (map[string]string, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	csvPath := filepath.Join(filepath.Dir(exePath), "tests", "glcaps.csv")
+
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open file %s: %v", csvPath, err)
+	}
+	defer file.Close()
+
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("Error reading CSV %s: %v", csvPath, err)
+	}
+	caps := make(map[string]string)
+	for _, row := range rows[min(1, len(rows)):] {
+		if len(row) >= 2 {
+			caps[row[0]] = row[1]
+		}
+	}
+	return caps, nil
+}
+
+// Analyze memory probe CSV, keeping the peak bandwidth of every transfer type
+func parseMemoryProbe() (rundoc.MemoryProbe, error) {
+	probe := rundoc.MemoryProbe{}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		return probe, err
+	}
+	csvPath := filepath.Join(filepath.Dir(exePath), "tests", "memory.csv")
+
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return probe, fmt.Errorf("Failed to open file %s: %v", csvPath, err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return probe, fmt.Errorf("Error reading CSV %s: %v", csvPath, err)
+	}
+	if len(rows) <= 1 {
+		return probe, fmt.Errorf("Insufficient data in file %s", csvPath)
+	}
+
+	for _, row := range rows[1:] {
+		if len(row) < 5 {
+			continue
+		}
+		size, _ := strconv.Atoi(row[1])
+		bandwidth, _ := strconv.ParseFloat(row[3], 64)
+
+		switch row[0] {
+		case "upload":
+			probe.UploadMBps = max(probe.UploadMBps, bandwidth)
+		case "download":
+			probe.DownloadMBps = max(probe.DownloadMBps, bandwidth)
+		case "copy":
+			probe.CopyMBps = max(probe.CopyMBps, bandwidth)
+		case "vram_estimate":
+			probe.VRAMEstimateMB = size
+			probe.VRAMNote = row[4]
+		case "vram_driver":
+			probe.DriverVRAMMB = size
+		case "vram_driver_free":
+			probe.DriverFreeVRAMMB = size
+		}
+	}
+
+	return probe, nil
+}
+
+// Shader ALU kernels in display order
+var aluKernels = []string{"mad", "sin", "exp", "pow", "int", "branch_coherent", "branch_divergent"}
+
+// Analyze ALU CSV, keeping the peak throughput of every kernel in Gops/s
+func parseALUResults() (map[string]float64, error) {
+	throughput := make(map[string]float64)
+
+	exePath, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	csvPath := filepath.Join(filepath.Dir(exePath), "tests", "alu.csv")
+
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open file %s: %v", csvPath, err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("Error reading CSV %s: %v", csvPath, err)
+	}
+	if len(rows) <= 1 {
+		return nil, fmt.Errorf("Insufficient data in file %s", csvPath)
+	}
+
+	for _, row := range rows[1:] {
+		if len(row) < 8 {
+			continue
+		}
+		gops, _ := strconv.ParseFloat(row[7], 64)
+		throughput[row[5]] = max(throughput[row[5]], gops)
+	}
+
+	return throughput, nil
+}
+
+// Scored tests of one pass of the suite; the diagnostics run once, after the last pass
+var suiteTests = []string{"butterfly", "triangles", "ocean", "postfx", "shadows", "particles"}
+
+// Tests that are measured but not scored, run once after the last pass
+var diagnosticTests = []string{"alu", "memory"}
+
+// testOverride changes the parameters of a test, zero values keep its own
+type testOverride struct {
+	Duration float64 // seconds of measurement
+	MaxStage int     // last stage to run
+}
+
+// Arguments of the test program for the override
+func (o testOverride) args() []string {
+	var args []string
+	if o.Duration > 0 {
+		args = append(args, "-duration", strconv.FormatFloat(o.Duration, 'f', -1, 64))
+	}
+	if o.MaxStage > 0 {
+		args = append(args, "-max-stage", strconv.Itoa(o.MaxStage))
+	}
+	return args
+}
+
+// testSelection is what a run consists of: the suite, the tests picked and their overrides
+type testSelection struct {
+	Suite     string
+	Tests     map[string]bool
+	Overrides map[string]testOverride
+}
+
+// The quick suite stops the core tests early: about 100 s of measurement instead of 240 s
+var quickOverrides = map[string]testOverride{
+	"butterfly": {MaxStage: 4},
+	"triangles": {MaxStage: 3},
+	"ocean":     {MaxStage: 3},
+}
+
+// Selection of the quick or full suite. The full suite includes the custom tests.
+func suiteSelection(suite string, customTests []CustomTest) testSelection {
+	selection := testSelection{Suite: suite, Tests: make(map[string]bool)}
+	names := allTests(customTests)
+	if suite == rundoc.SuiteQuick {
+		names = rundoc.CoreTests
+		selection.Overrides = quickOverrides
+	}
+	for _, name := range names {
+		selection.Tests[name] = true
+	}
+	return selection
+}
+
+// Every test that can be picked, in running order
+func allTests(customTests []CustomTest) []string {
+	names := append([]string(nil), suiteTests...)
+	for _, test := range customTests {
+		names = append(names, test.Name)
+	}
+	return append(names, diagnosticTests...)
+}
+
+// Number of tests started by a run of the given number of passes
+func (s testSelection) launches(iterations int) int {
+	count := 0
+	for name, selected := range s.Tests {
+		switch {
+		case !selected:
+		case slices.Contains(diagnosticTests, name):
+			count++
+		default:
+			count += iterations
+		}
+	}
+	return count
+}
+
+// Run and score every test once. A broken extended test or custom shader is reported through
+// onError but does not stop the pass, its test is left out of the results; only a core test
+// that fails is an error. A test stopped early is scored on what it measured and marked
+// incomplete; once the run is cancelled no further test starts.
+func runSuite(customTests []CustomTest, selection testSelection, onError func(error), runner *testRunner) (BenchmarkResults, error) {
+	var names []string
+	for _, name := range allTests(customTests) {
+		if selection.Tests[name] && !slices.Contains(diagnosticTests, name) {
+			names = append(names, name)
+		}
+	}
+	clearTestOutput(names)
+
+	// Tests that failed to run have nothing to score
+	scored := maps.Clone(selection.Tests)
+	stopped := make(map[string]string)
+	for _, test := range suiteTests {
+		if !selection.Tests[test] {
+			continue
+		}
+		if runner.Cancelled() {
+			stopped[test] = rundoc.StopCancelled
+			continue
+		}
+		if err := runner.run(test, test, selection.Overrides[test].args()...); err != nil {
+			var stop *testStopped
+			switch {
+			case errors.As(err, &stop):
+				stopped[test] = stop.Reason
+			case slices.Contains(rundoc.CoreTests, test):
+				return BenchmarkResults{}, fmt.Errorf("Failed to run test %s: %v", test, err)
+			default:
+				onError(fmt.Errorf("Failed to run test %s: %v", test, err))
+				delete(scored, test)
+			}
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	failedCustom := make(map[string]bool)
+	for _, test := range customTests {
+		if !selection.Tests[test.Name] {
+			continue
+		}
+		if runner.Cancelled() {
+			stopped[test.Name] = rundoc.StopCancelled
+			continue
+		}
+		args := append(selection.Overrides[test.Name].args(), test.Dir)
+		if err := runner.run(test.Name, "custom", args...); err != nil {
+			var stop *testStopped
+			if errors.As(err, &stop) {
+				stopped[test.Name] = stop.Reason
+			} else {
+				onError(fmt.Errorf("Failed to run custom test %s: %v", test.Name, err))
+				failedCustom[test.Name] = true
+			}
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	results, err := parseResultsAndCalculateScore(scored, stopped, onError)
+	if err != nil {
+		return results, fmt.Errorf("Failed to analyze results: %v", err)
+	}
+
+	results.CustomScores = make(map[string]float64)
+	for _, test := range customTests {
+		if !selection.Tests[test.Name] || failedCustom[test.Name] {
+			continue
+		}
+		customTest, err := calculateCustomScore(test)
+		if _, ok := stopped[test.Name]; ok && err != nil {
+			continue
+		}
+		if err != nil {
+			onError(fmt.Errorf("Failed to analyze custom test %s: %v", test.Name, err))
+			continue
+		}
+		customTest.Incomplete = stopped[test.Name]
+		results.Tests[test.Name] = customTest
+		results.CustomScores[test.Name] = customTest.Score
+	}
+	
+	// Readings before the first or after the last sample are left out
+	for name, test := range results.Tests {
+		test.SetSensors(runner.Sensors(name))
+		test.Noise = runner.Noise(name)
+		test.Process, test.ProcessStages = runner.Process(name)
+	}
+	return results, nil
+}
+
+// Run the selected ALU and memory tests, which are measured but not scored. Results that cannot be
+// read are logged and left out. A diagnostic that fails to run is reported to onError and
+// does not fail the run; one stopped early keeps what it measured.
+func runDiagnostics(selection testSelection, runner *testRunner, onError func(error)) rundoc.Diagnostics {
+	var diagnostics rundoc.Diagnostics
+	clearTestOutput([]string{"alu", "memory", "glcaps"})
+	for _, test := range diagnosticTests {
+		if !selection.Tests[test] {
+			continue
+		}
+		if runner.Cancelled() {
+			break
+		}
+		if err := runner.run(test, test); err != nil {
+			var stop *testStopped
+			if errors.As(err, &stop) {
+				log.Printf("Test %s stopped early: %s", test, stop.Reason)
+			} else {
+				onError(fmt.Errorf("Failed to run test %s: %v", test, err))
+			}
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	if selection.Tests["memory"] {
+		if probe, err := parseMemoryProbe(); err != nil {
+			log.Printf("Failed to analyze memory probe: %v", err)
+		} else {
+			diagnostics.Memory = &probe
+		}
+		// The GL capabilities are written by the memory probe as well
+		if caps, err := parseGLCaps(); err != nil {
+			log.Printf("Failed to read GL capabilities: %v", err)
+		} else {
+			diagnostics.GL = caps
+		}
+	}
+	if selection.Tests["alu"] {
+		if throughput, err := parseALUResults(); err != nil {
+			log.Printf("Failed to analyze ALU results: %v", err)
+		} else {
+			diagnostics.ALU = throughput
+		}
+	}
+	return diagnostics
+}
+
+// Scores of one pass for the run document
+func (r BenchmarkResults) iteration() rundoc.Iteration {
+	iteration := rundoc.Iteration{TotalScore: r.TotalScore, Scores: make(map[string]float64)}
+	for name, test := range r.Tests {
+		iteration.Scores[name] = test.Score
+	}
+	return iteration
+}
+
+// Score as shown in the GUI; repeated runs show the mean with its 95% confidence interval
+func formatScore(summaries map[string]*rundoc.ScoreStats, name string, score float64) string {
+	summary, ok := summaries[name]
+	if !ok {
+		return fmt.Sprintf("%.2f", score)
+	}
+	text := fmt.Sprintf("%.2f ± %.2f", summary.Mean, summary.CI95)
+	if summary.Unstable {
+		text += fmt.Sprintf(" (unstable, CV %.1f%%)", summary.CV*100)
+	}
+	return text
+}
+
+// Write the document of a run. A repeated run keeps the samples of its median pass and the
+// scores of every pass. An incomplete run is written all the same, marked as such.
+func writeRunDocument(passes []BenchmarkResults, suite string, cvThreshold float64, diagnostics rundoc.Diagnostics, incomplete bool) (*rundoc.Document, string, error) {
+	totals := make([]float64, len(passes))
+	for i, pass := range passes {
+		totals[i] = pass.TotalScore
+	}
+	results := passes[rundoc.MedianIteration(totals)]
+
+	gpuName, vramSize, driverVersion, ramSize, cpuName := sysinfo.GetSystemInfo()
+	doc := rundoc.New(submit.NewRunID(), rundoc.System{
+		GpuName:        gpuName,
+		VramSize:       vramSize,
+		DriverVersion:  driverVersion,
+		WindowsVersion: sysinfo.GetWindowsVersion(),
+		UsesWine:       sysinfo.IsWineUsed(),
+		RamSize:        ramSize,
+		CpuName:        cpuName,
+	})
+	doc.TotalScore = results.TotalScore
+	doc.Tests = results.Tests
+	doc.SetNoise()
+	doc.Suite = suite
+	doc.Diagnostics = diagnostics
+	doc.Incomplete = incomplete
+	if len(passes) > 1 {
+		iterations := make([]rundoc.Iteration, len(passes))
+		for i, pass := range passes {
+			iterations[i] = pass.iteration()
+		}
+		doc.SetIterations(iterations, cvThreshold)
+	}
+	if err := doc.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		return nil, "", err
+	}
+	docPath := filepath.Join(filepath.Dir(exePath), "run.json")
+	return doc, docPath, doc.Write(docPath)
+}
+
+// The HTML report of a run goes next to its run.json
+func writeRunReport(doc *rundoc.Document, docPath string) (string, error) {
+	reportPath := filepath.Join(filepath.Dir(docPath), "report.html")
+	return reportPath, report.WriteHTMLFile(reportPath, doc)
+}
+
 // Send result to server
 func sendResultsForStatistics(results BenchmarkResults, gpuInfo string) error {
 	log.Println("Sending results to server:", results)
 	return nil
 }
 
+// Retry queued submissions that are due and show what is left
+func refreshOutboxStatus(label *widget.Label, flush bool) {
+	outbox, err := submit.DefaultOutbox()
+	if err != nil {
+		label.SetText(fmt.Sprintf("Outbox: %v", err))
+		return
+	}
+	if flush {
+		if config, err := submit.LoadConfig(); err != nil {
+			log.Printf("Failed to load submission config: %v", err)
+		} else if _, err := outbox.Flush(config, false); err != nil {
+			log.Printf("Outbox retry failed: %v", err)
+		}
+	}
+
+	status, err := outbox.Status()
+	switch {
+	case err != nil:
+		label.SetText(fmt.Sprintf("Outbox: %v", err))
+	case status.Pending == 0:
+		label.SetText("Outbox: all results sent")
+	default:
+		label.SetText(fmt.Sprintf("Outbox: %d queued, next retry %s", status.Pending, status.NextAttempt.Format("Jan 2 15:04")))
+	}
+}
+
 func main() {
 	runtime.LockOSThread()
 	
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "flush":
+			os.Exit(runFlush())
+		case "history":
+			os.Exit(runHistory(os.Args[2:]))
+		case "compare":
+			os.Exit(runCompare(os.Args[2:]))
+		case "run":
+			os.Exit(runHeadless(os.Args[2:]))
+		case "baseline":
+			os.Exit(runBaseline(os.Args[2:]))
+		case "gate":
+			os.Exit(runGate(os.Args[2:]))
+		case "report":
+			os.Exit(runReport(os.Args[2:]))
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		}
+	}
+	
+	// Submission target, passed on to send.exe
+	targetName := flag.String("target", "", "name of the submission target in submit.json")
+	flag.Parse()
+	
 	// Create Fyne App
-	a := app.New()
+	a := app.NewWithID("io.github.dmitrymodder.gltest")
 	w := a.NewWindow("GLTest")
-	w.Resize(fyne.NewSize(300, 400))
+	w.Resize(fyne.NewSize(800, 700))
+	
+	// Stored runs and their charts, on their own tab
+	historyTab := newHistoryView(w)
 	
 	// Get GPU info
 	gpuName, vramSize, openGLVersion := getGPUInfo()
@@ -231,6 +1160,9 @@ func main() {
 	trianglesScore := widget.NewLabel("-")
 	oceanScore := widget.NewLabel("-")
 	totalScore := widget.NewLabel("-")
+	postFXScore := widget.NewLabel("-")
+	shadowsScore := widget.NewLabel("-")
+	particlesScore := widget.NewLabel("-")
 	
 	// Create results section
 	resultsGrid := container.New(layout.NewGridLayout(2),
@@ -242,92 +1174,543 @@ func main() {
 		oceanScore,
 		widget.NewLabel("Total"),
 		totalScore,
+		widget.NewLabel("Post-processing"),
+		postFXScore,
+		widget.NewLabel("Shadows"),
+		shadowsScore,
+		widget.NewLabel("GPU particles"),
+		particlesScore,
 	)
 	
+	// Custom shader tests get a row each
+	customTests, err := discoverCustomTests()
+	if err != nil {
+		log.Printf("Failed to discover custom tests: %v", err)
+	}
+	customScores := make(map[string]*widget.Label)
+	for _, test := range customTests {
+		customScores[test.Name] = widget.NewLabel("-")
+		resultsGrid.Add(widget.NewLabel(test.Title))
+		resultsGrid.Add(customScores[test.Name])
+	}
+	
+	// What limited each test, once the run is done
+	bottlenecks := widget.NewLabel("")
+	bottlenecks.Wrapping = fyne.TextWrapWord
+	
 	resultsContainer := container.NewVBox(
 		widget.NewLabelWithStyle("Results", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 		resultsGrid,
+		bottlenecks,
+	)
+	
+	// Diagnostics section
+	uploadBandwidth := widget.NewLabel("-")
+	downloadBandwidth := widget.NewLabel("-")
+	copyBandwidth := widget.NewLabel("-")
+	usableVRAM := widget.NewLabel("-")
+	
+	diagnosticsGrid := container.New(layout.NewGridLayout(2),
+		widget.NewLabel("Upload"),
+		uploadBandwidth,
+		widget.NewLabel("Download"),
+		downloadBandwidth,
+		widget.NewLabel("GPU copy"),
+		copyBandwidth,
+		widget.NewLabel("Usable VRAM"),
+		usableVRAM,
+	)
+	
+	diagnosticsContainer := container.NewVBox(
+		widget.NewLabelWithStyle("Diagnostics", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		diagnosticsGrid,
+	)
+	
+	// Shader ALU section
+	aluLabels := make(map[string]*widget.Label)
+	aluGrid := container.New(layout.NewGridLayout(2))
+	for _, kernel := range aluKernels {
+		aluLabels[kernel] = widget.NewLabel("-")
+		aluGrid.Add(widget.NewLabel(kernel))
+		aluGrid.Add(aluLabels[kernel])
+	}
+	
+	aluContainer := container.NewVBox(
+		widget.NewLabelWithStyle("Shader ALU", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		aluGrid,
+	)
+	
+	// Tests of the next run: a suite, or tests picked by hand with their own parameters.
+	// The choice is kept in the preferences.
+	prefs := a.Preferences()
+	testNames := allTests(customTests)
+	testChecks := make(map[string]*widget.Check)
+	durationEntries := make(map[string]*widget.Entry)
+	maxStageEntries := make(map[string]*widget.Entry)
+	suiteSelect := widget.NewSelect([]string{rundoc.SuiteQuick, rundoc.SuiteFull, rundoc.SuiteCustom}, nil)
+	
+	savedSuite := prefs.StringWithFallback("suite", rundoc.SuiteFull)
+	picked := suiteSelection(savedSuite, customTests).Tests
+	if savedSuite == rundoc.SuiteCustom {
+		picked = make(map[string]bool)
+		for _, name := range prefs.StringList("tests") {
+			picked[name] = true
+		}
+	}
+	testsGrid := container.NewGridWithColumns(3)
+	settingsGrid := container.New(layout.NewGridLayout(3),
+		widget.NewLabel("Test"),
+		widget.NewLabel("Duration (s)"),
+		widget.NewLabel("Max stage"),
+	)
+	for _, name := range testNames {
+		testChecks[name] = widget.NewCheck(name, nil)
+		testChecks[name].SetChecked(picked[name])
+		testsGrid.Add(testChecks[name])
+		
+		// The diagnostics have no stages to override
+		if slices.Contains(diagnosticTests, name) {
+			continue
+		}
+		durationEntries[name] = widget.NewEntry()
+		durationEntries[name].SetPlaceHolder("default")
+		durationEntries[name].SetText(prefs.String("duration." + name))
+		maxStageEntries[name] = widget.NewEntry()
+		maxStageEntries[name].SetPlaceHolder("all")
+		maxStageEntries[name].SetText(prefs.String("max_stage." + name))
+		settingsGrid.Add(widget.NewLabel(name))
+		settingsGrid.Add(durationEntries[name])
+		settingsGrid.Add(maxStageEntries[name])
+	}
+	
+	saveSelection := func() {
+		var names []string
+		for _, name := range testNames {
+			if testChecks[name].Checked {
+				names = append(names, name)
+			}
+		}
+		prefs.SetString("suite", suiteSelect.Selected)
+		prefs.SetStringList("tests", names)
+		for name, entry := range durationEntries {
+			prefs.SetString("duration."+name, entry.Text)
+			prefs.SetString("max_stage."+name, maxStageEntries[name].Text)
+		}
+	}
+	
+	// Picking a suite sets the checkboxes, changing a checkbox makes the suite custom.
+	// Test settings only apply to custom runs.
+	updating := false
+	suiteSelect.OnChanged = func(suite string) {
+		if suite != rundoc.SuiteCustom {
+			updating = true
+			selected := suiteSelection(suite, customTests).Tests
+			for name, check := range testChecks {
+				check.SetChecked(selected[name])
+			}
+			updating = false
+		}
+		for name, entry := range durationEntries {
+			if suite == rundoc.SuiteCustom {
+				entry.Enable()
+				maxStageEntries[name].Enable()
+			} else {
+				entry.Disable()
+				maxStageEntries[name].Disable()
+			}
+		}
+		saveSelection()
+	}
+	for _, check := range testChecks {
+		check.OnChanged = func(bool) {
+			if updating {
+				return
+			}
+			updating = true
+			suiteSelect.SetSelected(rundoc.SuiteCustom)
+			updating = false
+			saveSelection()
+		}
+	}
+	for name, entry := range durationEntries {
+		entry.OnChanged = func(string) { saveSelection() }
+		maxStageEntries[name].OnChanged = func(string) { saveSelection() }
+	}
+	suiteSelect.SetSelected(savedSuite)
+	
+	// What the start button runs
+	currentSelection := func() (testSelection, error) {
+		selection := testSelection{Suite: suiteSelect.Selected, Tests: make(map[string]bool), Overrides: make(map[string]testOverride)}
+		for name, check := range testChecks {
+			selection.Tests[name] = check.Checked
+		}
+		if selection.launches(1) == 0 {
+			return selection, fmt.Errorf("Select at least one test")
+		}
+		if selection.Suite != rundoc.SuiteCustom {
+			selection.Overrides = suiteSelection(selection.Suite, customTests).Overrides
+			return selection, nil
+		}
+		for name, entry := range durationEntries {
+			var override testOverride
+			var err error
+			if text := strings.TrimSpace(entry.Text); text != "" {
+				if override.Duration, err = strconv.ParseFloat(text, 64); err != nil || override.Duration <= 0 {
+					return selection, fmt.Errorf("Duration of %s must be a positive number of seconds", name)
+				}
+			}
+			if text := strings.TrimSpace(maxStageEntries[name].Text); text != "" {
+				if override.MaxStage, err = strconv.Atoi(text); err != nil || override.MaxStage < 1 {
+					return selection, fmt.Errorf("Max stage of %s must be a whole number of at least 1", name)
+				}
+			}
+			selection.Overrides[name] = override
+		}
+		return selection, nil
+	}
+	
+	testsContainer := container.NewVBox(
+		widget.NewLabelWithStyle("Tests", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		container.New(layout.NewFormLayout(), widget.NewLabel("Suite"), suiteSelect),
+		testsGrid,
+		widget.NewAccordion(widget.NewAccordionItem("Test settings (custom suite)", container.NewVBox(
+			widget.NewLabel("Only custom runs take these settings, so a run with any of them is custom\nand is not sent for statistics. Quick runs stop the core tests early on their own."),
+			settingsGrid,
+		))),
 	)
 	
 	// Send data checkbox
 	sendStatsCheck := widget.NewCheck("Send results for statistics (recommended)", nil)
 	sendStatsCheck.SetChecked(true)
 	
+	// Repeated runs: the suite runs this many times, with a pause in between
+	iterationsEntry := widget.NewEntry()
+	iterationsEntry.SetText("1")
+	cooldownEntry := widget.NewEntry()
+	cooldownEntry.SetText("0")
+	cvThresholdEntry := widget.NewEntry()
+	cvThresholdEntry.SetText(strconv.FormatFloat(rundoc.DefaultCVThreshold*100, 'f', -1, 64))
+	timeoutEntry := widget.NewEntry()
+	timeoutEntry.SetPlaceHolder("auto")
+	// Other work on the machine is looked for before every test (Linux only)
+	noiseSelect := widget.NewSelect([]string{noiseWarn, noiseAbort, noiseOff}, nil)
+	noiseSelect.SetSelected(noiseWarn)
+	
+	runSettings := container.New(layout.NewFormLayout(),
+		widget.NewLabel("Iterations"),
+		iterationsEntry,
+		widget.NewLabel("Cooldown (s)"),
+		cooldownEntry,
+		widget.NewLabel("Unstable above CV (%)"),
+		cvThresholdEntry,
+		widget.NewLabel("Test timeout (s)"),
+		timeoutEntry,
+		widget.NewLabel("When the system is busy"),
+		noiseSelect,
+	)
+	runStatus := widget.NewLabel("")
+	
+	// Progress of the running test and its frame times as they come in
+	live := newLiveView()
+	
+	// Results that could not be sent yet, retried at every launch
+	outboxStatus := widget.NewLabel("Outbox: checking...")
+	go refreshOutboxStatus(outboxStatus, true)
+	
+	// Filled in once a run has written its report
+	var lastReport string
+	reportButton := widget.NewButton("Open Report", nil)
+	reportButton.Disable()
+	
+	// The running test can be skipped, or the whole run cancelled; what was measured is kept
+	var runner *testRunner
+	skipButton := widget.NewButton("Skip Test", func() {
+		runner.Skip()
+	})
+	skipButton.Disable()
+	cancelButton := widget.NewButton("Cancel", func() {
+		runner.Cancel()
+	})
+	cancelButton.Disable()
+	
 	// Start button
 startButton := widget.NewButton("Start Benchmark", nil)
+endRun := func() {
+    skipButton.Disable()
+    cancelButton.Disable()
+    startButton.Enable()
+}
 startButton.OnTapped = func() {
+    iterations, err := strconv.Atoi(strings.TrimSpace(iterationsEntry.Text))
+    if err != nil || iterations < 1 {
+        dialog.ShowError(fmt.Errorf("Iterations must be a whole number of at least 1"), w)
+        return
+    }
+    cooldown, err := strconv.ParseFloat(strings.TrimSpace(cooldownEntry.Text), 64)
+    if err != nil || cooldown < 0 {
+        dialog.ShowError(fmt.Errorf("Cooldown must be a number of seconds"), w)
+        return
+    }
+    cvThreshold, err := strconv.ParseFloat(strings.TrimSpace(cvThresholdEntry.Text), 64)
+    if err != nil || cvThreshold <= 0 {
+        dialog.ShowError(fmt.Errorf("CV threshold must be a positive percentage"), w)
+        return
+    }
+    timeout := 0.0
+    if text := strings.TrimSpace(timeoutEntry.Text); text != "" {
+        if timeout, err = strconv.ParseFloat(text, 64); err != nil || timeout < 0 {
+            dialog.ShowError(fmt.Errorf("Test timeout must be a number of seconds, or empty for the test duration plus two minutes"), w)
+            return
+        }
+    }
+
+    selection, err := currentSelection()
+    if err != nil {
+        dialog.ShowError(err, w)
+        return
+    }
+
     startButton.Disable()
+    runner = newTestRunner(live.handle, time.Duration(timeout*float64(time.Second)))
+    runner.noise = noiseSelect.Selected
+    skipButton.Enable()
+    cancelButton.Enable()
+    runStatus.SetText("")
+    // Every pass runs the selected tests, the diagnostics run once
+    live.start(selection.launches(iterations))
 
     // Reset results
     butterflyScore.SetText("-")
     trianglesScore.SetText("-")
     oceanScore.SetText("-")
     totalScore.SetText("-")
+    postFXScore.SetText("-")
+    shadowsScore.SetText("-")
+    particlesScore.SetText("-")
+    uploadBandwidth.SetText("-")
+    downloadBandwidth.SetText("-")
+    copyBandwidth.SetText("-")
+    usableVRAM.SetText("-")
+    for _, label := range aluLabels {
+        label.SetText("-")
+    }
+    for _, label := range customScores {
+        label.SetText("-")
+    }
+    bottlenecks.SetText("")
+
+    // Scores of a pass, or of the whole run once its document is written
+    showScores := func(results BenchmarkResults, summaries map[string]*rundoc.ScoreStats) {
+        // Tests stopped early say why, tests that never got to run say so
+        scoreText := func(name string, score float64) string {
+            test, ok := results.Tests[name]
+            if !ok && selection.Tests[name] && !runner.Incomplete() {
+                return "failed"
+            } else if !ok {
+                return "not run"
+            }
+            text := formatScore(summaries, name, score)
+            if test.Incomplete != "" {
+                text += " (" + test.Incomplete + ")"
+            }
+            return text
+        }
+        butterflyScore.SetText(scoreText("butterfly", results.ButterflyScore))
+        trianglesScore.SetText(scoreText("triangles", results.TrianglesScore))
+        oceanScore.SetText(scoreText("ocean", results.OceanScore))
+        totalScore.SetText(formatScore(summaries, rundoc.TotalScoreKey, results.TotalScore))
+        postFXScore.SetText(scoreText("postfx", results.PostFXScore))
+        shadowsScore.SetText(scoreText("shadows", results.ShadowsScore))
+        particlesScore.SetText(scoreText("particles", results.ParticlesScore))
+        for _, test := range customTests {
+            if score, ok := results.CustomScores[test.Name]; ok {
+                customScores[test.Name].SetText(scoreText(test.Name, score))
+            } else if runner.Incomplete() || !selection.Tests[test.Name] {
+                customScores[test.Name].SetText("not run")
+            } else {
+                customScores[test.Name].SetText("failed")
+            }
+        }
+    }
 
     go func() {
-        tests := []string{"butterfly", "triangles", "ocean"}
+        var passes []BenchmarkResults
+        for i := 0; i < iterations && !runner.Cancelled(); i++ {
+            if i > 0 && cooldown > 0 {
+                runStatus.SetText(fmt.Sprintf("Cooling down for %gs before iteration %d of %d", cooldown, i+1, iterations))
+                runner.Sleep(time.Duration(cooldown * float64(time.Second)))
+                if runner.Cancelled() {
+                    break
+                }
+            }
+            if iterations > 1 {
+                runStatus.SetText(fmt.Sprintf("Iteration %d of %d", i+1, iterations))
+            }
 
-        for _, test := range tests {
-            err := runTest(test)
+            // A broken extended test or custom shader is reported but does not stop the run
+            results, err := runSuite(customTests, selection, func(err error) {
+                log.Print(err)
+                dialog.ShowError(err, w)
+            }, runner)
             if err != nil {
-                log.Printf("Failed to run test %s: %v", test, err)
-                dialog.ShowError(fmt.Errorf("Failed to run test %s: %v", test, err), w)
-                startButton.Enable()
+                log.Print(err)
+                dialog.ShowError(err, w)
+                live.finish()
+                runStatus.SetText("")
+                endRun()
                 return
             }
-            time.Sleep(500 * time.Millisecond)
+            passes = append(passes, results)
+            showScores(results, nil)
         }
 
-        results, err := parseResultsAndCalculateScore()
+        // A failed diagnostic is shown, the scores of the run are kept all the same
+        diagnostics := runDiagnostics(selection, runner, func(err error) {
+            log.Print(err)
+            dialog.ShowError(err, w)
+        })
+        live.finish()
+
+        if probe := diagnostics.Memory; probe != nil {
+            uploadBandwidth.SetText(fmt.Sprintf("%.1f GB/s", probe.UploadMBps/1024))
+            downloadBandwidth.SetText(fmt.Sprintf("%.1f GB/s", probe.DownloadMBps/1024))
+            copyBandwidth.SetText(fmt.Sprintf("%.1f GB/s", probe.CopyMBps/1024))
+            vram := fmt.Sprintf("%d MB (%s)", probe.VRAMEstimateMB, probe.VRAMNote)
+            if probe.DriverVRAMMB > 0 {
+                vram += fmt.Sprintf(", driver: %d MB", probe.DriverVRAMMB)
+            }
+            if probe.DriverFreeVRAMMB > 0 {
+                vram += fmt.Sprintf(", driver: %d MB free", probe.DriverFreeVRAMMB)
+            }
+            usableVRAM.SetText(vram)
+        }
+
+        if throughput := diagnostics.ALU; throughput != nil {
+            for kernel, label := range aluLabels {
+                label.SetText(fmt.Sprintf("%.1f Gops/s", throughput[kernel]))
+            }
+        }
+
+        // Everything shown above goes into one document, which is what gets sent
+        doc, docPath, err := writeRunDocument(passes, selection.Suite, cvThreshold/100, diagnostics, runner.Incomplete())
         if err != nil {
-            log.Printf("Failed to analyze results: %v", err)
-            dialog.ShowError(fmt.Errorf("Failed to analyze results: %v", err), w)
-            startButton.Enable()
+            log.Printf("Failed to write run document: %v", err)
+            dialog.ShowError(fmt.Errorf("Failed to write run document: %v", err), w)
+            runStatus.SetText("")
+            endRun()
             return
         }
 
-        // Update UI
-        butterflyScore.SetText(fmt.Sprintf("%.2f", results.ButterflyScore))
-        trianglesScore.SetText(fmt.Sprintf("%.2f", results.TrianglesScore))
-        oceanScore.SetText(fmt.Sprintf("%.2f", results.OceanScore))
-        totalScore.SetText(fmt.Sprintf("%.2f", results.TotalScore))
+        bottlenecks.SetText(bottleneckText(doc))
+
+        // Repeated runs show every score as mean and confidence interval, unstable ones flagged
+        if len(passes) > 1 {
+            showScores(passes[len(passes)-1], doc.Summary)
+            if unstable := doc.UnstableScores(); len(unstable) > 0 {
+                runStatus.SetText(fmt.Sprintf("%d iterations, unstable: %s", len(passes), strings.Join(unstable, ", ")))
+            } else {
+                runStatus.SetText(fmt.Sprintf("%d iterations, all scores stable", len(passes)))
+            }
+        }
+
+        // Partial results are kept and reported, but not sent for statistics
+        if doc.Incomplete {
+            status := "Run cancelled, partial results are not sent"
+            if stopped := doc.IncompleteTests(); len(stopped) > 0 {
+                status = fmt.Sprintf("Incomplete run (%s), results are not sent", strings.Join(stopped, ", "))
+            }
+            runStatus.SetText(status)
+        } else if doc.Suite == rundoc.SuiteCustom && sendStatsCheck.Checked {
+            runStatus.SetText("Custom selection of tests, results are not sent")
+        } else if doc.Noisy {
+            runStatus.SetText(fmt.Sprintf("The system was busy with other work (up to %.0f%% CPU), results are flagged as noisy", doc.Noise*100))
+        }
+
+        // Keep the run in the local history
+        if store, err := history.Default(); err != nil {
+            log.Printf("Failed to open run history: %v", err)
+        } else if err := store.Add(doc); err != nil {
+            log.Printf("Failed to store run in history: %v", err)
+        } else {
+            historyTab.refresh()
+        }
+
+        if reportPath, err := writeRunReport(doc, docPath); err != nil {
+            log.Printf("Failed to write report: %v", err)
+        } else {
+            lastReport = reportPath
+            reportButton.Enable()
+        }
 
         // Start send process
-        if sendStatsCheck.Checked {
+        if sendStatsCheck.Checked && doc.Submittable() == nil {
             exePath, err := os.Executable()
             if err != nil {
                 exec.Command("msg", "*", fmt.Sprintf("Error: Failed to determine executable path: %v", err)).Run()
-                startButton.Enable()
+                endRun()
                 return
             }
             sendPath := filepath.Join(filepath.Dir(exePath), "send.exe")
-            cmd := exec.Command(sendPath,
-                fmt.Sprintf("%f", results.ButterflyScore),
-                fmt.Sprintf("%f", results.TrianglesScore),
-                fmt.Sprintf("%f", results.OceanScore),
-                fmt.Sprintf("%f", results.TotalScore),
-                gpuName, vramSize, openGLVersion)
+            var sendArgs []string
+            if *targetName != "" {
+                sendArgs = append(sendArgs, "--target", *targetName)
+            }
+            sendArgs = append(sendArgs, docPath)
+            cmd := exec.Command(sendPath, sendArgs...)
             cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
             if err := cmd.Run(); err != nil {
                 exec.Command("msg", "*", fmt.Sprintf("Error running send.exe: %v", err)).Run()
             }
+            refreshOutboxStatus(outboxStatus, false)
         }
 
-        startButton.Enable()
+        endRun()
     }()
 }
 	
+	// Report of the last run, opened in the browser
+	reportButton.OnTapped = func() {
+		if err := a.OpenURL(&url.URL{Scheme: "file", Path: "/" + filepath.ToSlash(lastReport)}); err != nil {
+			dialog.ShowError(fmt.Errorf("Failed to open report: %v", err), w)
+		}
+	}
+	
+	// Compare stored runs on the History tab, starting with the newest two
+	var tabs *container.AppTabs
+	historyItem := container.NewTabItem("History", historyTab.content())
+	compareButton := widget.NewButton("Compare Runs", func() {
+		tabs.Select(historyItem)
+		historyTab.compareNewest()
+	})
+	
 	// Create main container
 	content := container.NewVBox(
 		gpuInfoContainer,
 		widget.NewSeparator(),
 		resultsContainer,
 		widget.NewSeparator(),
+		diagnosticsContainer,
+		widget.NewSeparator(),
+		aluContainer,
+		widget.NewSeparator(),
+		testsContainer,
+		widget.NewSeparator(),
+		runSettings,
 		sendStatsCheck,
+		outboxStatus,
+		runStatus,
+		live.container(),
 		startButton,
+		container.NewGridWithColumns(2, skipButton, cancelButton),
+		reportButton,
+		compareButton,
 	)
 	
 	// Open window
-	w.SetContent(content)
+	tabs = container.NewAppTabs(
+		container.NewTabItem("Benchmark", container.NewVScroll(content)),
+		historyItem,
+	)
+	w.SetContent(tabs)
 	w.ShowAndRun()
 }
\ No newline at end of file
@@ -0,0 +1,118 @@
+// Package noise tells how busy the machine is with other work, before a test starts and while
+// it runs, from the CPU times in /proc/stat and the memory stall time in /proc/pressure. Under
+// Wine /proc is reached through the Z: drive, see sensors.Root; on Windows itself nothing is
+// measured.
+package noise
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Clock ticks per second of /proc/stat, the same on every Linux architecture that matters
+const userHZ = 100
+
+// A machine is busy above these shares of other work
+const (
+	BusyCPU    = 0.10 // of all cores
+	BusyMemory = 0.05 // of the time some task stalled on memory
+)
+
+// Snapshot is the counters at one moment
+type Snapshot struct {
+	At          time.Time
+	busy, total float64 // CPU seconds of all cores
+	memoryStall float64 // seconds some task waited for memory
+	hasCPU      bool
+	hasMemory   bool
+}
+
+// Available is true when a counter could be read
+func (s Snapshot) Available() bool {
+	return s.hasCPU || s.hasMemory
+}
+
+// Load is the share of the machine taken by other work between two snapshots
+type Load struct {
+	CPU    float64 // of all cores, 0 to 1
+	Memory float64 // of the time some task stalled on memory, 0 to 1
+}
+
+// Take reads the counters under root
+func Take(root string) Snapshot {
+	s := Snapshot{At: time.Now()}
+	if data, err := os.ReadFile(filepath.Join(root, "proc/stat")); err == nil {
+		s.busy, s.total, s.hasCPU = cpuTimes(string(data))
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "proc/pressure/memory")); err == nil {
+		s.memoryStall, s.hasMemory = stallTime(string(data))
+	}
+	return s
+}
+
+// Between is the load from a to b, without the CPU time the test itself used. False when
+// neither counter could be read.
+func Between(a, b Snapshot, own time.Duration) (Load, bool) {
+	var load Load
+	if a.hasCPU && b.hasCPU && b.total > a.total {
+		load.CPU = max(0, (b.busy-a.busy-own.Seconds())/(b.total-a.total))
+	}
+	if elapsed := b.At.Sub(a.At).Seconds(); a.hasMemory && b.hasMemory && elapsed > 0 {
+		load.Memory = min(1, max(0, (b.memoryStall-a.memoryStall)/elapsed))
+	}
+	return load, a.hasCPU && b.hasCPU || a.hasMemory && b.hasMemory
+}
+
+// Busy is true when the other work is enough to skew a result
+func (l Load) Busy() bool {
+	return l.CPU > BusyCPU || l.Memory > BusyMemory
+}
+
+func (l Load) String() string {
+	return fmt.Sprintf("%.0f%% CPU busy with other work, %.0f%% memory stall", l.CPU*100, l.Memory*100)
+}
+
+// Busy and total seconds from the "cpu" line of /proc/stat: user nice system idle iowait irq
+// softirq steal, the guest times are already part of user and nice
+func cpuTimes(stat string) (busy, total float64, ok bool) {
+	for _, line := range strings.Split(stat, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 9 || fields[0] != "cpu" {
+			continue
+		}
+		var ticks [8]float64
+		for i := range ticks {
+			value, err := strconv.ParseFloat(fields[i+1], 64)
+			if err != nil {
+				return 0, 0, false
+			}
+			ticks[i] = value
+		}
+		idle := ticks[3] + ticks[4]
+		busy = ticks[0] + ticks[1] + ticks[2] + ticks[5] + ticks[6] + ticks[7]
+		return busy / userHZ, (busy + idle) / userHZ, true
+	}
+	return 0, 0, false
+}
+
+// Seconds of the "some" line of a pressure file: "some avg10=0.00 avg60=0.00 avg300=0.00 total=1234"
+// with the total in microseconds
+func stallTime(pressure string) (float64, bool) {
+	for _, line := range strings.Split(pressure, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "some" {
+			continue
+		}
+		for _, field := range fields[1:] {
+			if value, ok := strings.CutPrefix(field, "total="); ok {
+				micros, err := strconv.ParseFloat(value, 64)
+				return micros / 1e6, err == nil
+			}
+		}
+	}
+	return 0, false
+}
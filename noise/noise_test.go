@@ -0,0 +1,93 @@
+package noise
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Writes /proc/stat and /proc/pressure/memory under root, empty content leaves a file out
+func writeProc(t *testing.T, root, stat, pressure string) {
+	t.Helper()
+	for path, content := range map[string]string{"proc/stat": stat, "proc/pressure/memory": pressure} {
+		path = filepath.Join(root, path)
+		os.Remove(path)
+		if content == "" {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBetween(t *testing.T) {
+	root := t.TempDir()
+	// user nice system idle iowait irq softirq steal guest guest_nice, in ticks of 10 ms
+	writeProc(t, root,
+		"cpu  1000 0 500 8000 500 0 0 0 0 0\ncpu0 500 0 250 4000 250 0 0 0 0 0\n",
+		"some avg10=0.00 avg60=0.00 avg300=0.00 total=1000000\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n")
+	before := Take(root)
+	// 4 s of CPU over 10 s of all cores: 1 s busy and 3 s idle per second on 4 cores
+	writeProc(t, root,
+		"cpu  1300 0 600 8500 600 0 0 0 0 0\n",
+		"some avg10=0.00 avg60=0.00 avg300=0.00 total=1500000\n")
+	after := Take(root)
+	after.At = before.At.Add(2 * time.Second)
+
+	cases := []struct {
+		name       string
+		own        time.Duration
+		cpu, stall float64
+		busy       bool
+	}{
+		{"all of it other work", 0, 0.4, 0.25, true},
+		{"most of it the test", 3500 * time.Millisecond, 0.05, 0.25, true},
+		{"more than measured is the test", 10 * time.Second, 0, 0.25, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			load, ok := Between(before, after, c.own)
+			if !ok {
+				t.Fatal("no load")
+			}
+			if math.Abs(load.CPU-c.cpu) > 1e-9 || math.Abs(load.Memory-c.stall) > 1e-9 {
+				t.Errorf("load = %+v, want CPU %g and memory %g", load, c.cpu, c.stall)
+			}
+			if load.Busy() != c.busy {
+				t.Errorf("Busy() = %v, want %v", load.Busy(), c.busy)
+			}
+		})
+	}
+}
+
+func TestQuietMachine(t *testing.T) {
+	root := t.TempDir()
+	writeProc(t, root, "cpu  1000 0 500 8000 0 0 0 0 0 0\n", "")
+	before := Take(root)
+	writeProc(t, root, "cpu  1005 0 500 8995 0 0 0 0 0 0\n", "")
+	load, ok := Between(before, Take(root), 0)
+	if !ok || load.Busy() || math.Abs(load.CPU-0.005) > 1e-9 || load.Memory != 0 {
+		t.Errorf("load = %+v, %v, want 0.5%% CPU and a quiet machine", load, ok)
+	}
+}
+
+func TestNothingToRead(t *testing.T) {
+	root := t.TempDir()
+	snapshot := Take(root)
+	if snapshot.Available() {
+		t.Error("counters available in an empty tree")
+	}
+	if _, ok := Between(snapshot, Take(root), 0); ok {
+		t.Error("a load between snapshots without counters")
+	}
+	writeProc(t, root, "intr 12345\n", "some avg10=0.00\n")
+	if Take(root).Available() {
+		t.Error("counters available from files without a cpu line or a total")
+	}
+}
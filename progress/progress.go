@@ -0,0 +1,117 @@
+// Package progress follows a running test through the lines it prints on stdout.
+package progress
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Kinds of events
+const (
+	Launched  = "launched"  // sent by the runner before it starts the test process
+	Preflight = "preflight" // sent by the runner before it checks the system load, and again when it is busy
+	Started   = "started"   // "Duration: 60s, Stages: 6"
+	Warmup    = "warmup"    // "Warming up..."
+	Stage     = "stage"     // "Starting stage 2 with 40000 particles"
+	Sample    = "sample"    // "Time: 12.5s, Stage: 2, Particles: 40000, Avg FPS: 60.1, Min FPS: 40.2"
+)
+
+// Event is one line of a test that says where it is
+type Event struct {
+	Kind string
+	// Started
+	Duration float64 // seconds of measurement, without the warm-up
+	Stages   int
+	// Stage and Sample
+	Stage       int
+	Description string // what a stage does, e.g. "with 40000 particles", or the load of a busy system
+	// Sample
+	Elapsed   float64
+	LoadLabel string
+	Load      float64
+	AvgFps    float64
+	MinFps    float64
+}
+
+// Watch reads the output of a test until it ends and calls handle for every progress line
+func Watch(r io.Reader, handle func(Event)) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if event, ok := ParseLine(scanner.Text()); ok {
+			handle(event)
+		}
+	}
+	return scanner.Err()
+}
+
+// ParseLine turns a line printed by a test into an event
+func ParseLine(line string) (Event, bool) {
+	line = strings.TrimSpace(line)
+	switch {
+	case line == "Warming up...":
+		return Event{Kind: Warmup}, true
+	case strings.HasPrefix(line, "Starting stage "):
+		rest := strings.TrimPrefix(line, "Starting stage ")
+		number, description, _ := strings.Cut(rest, " ")
+		stage, err := strconv.Atoi(strings.TrimSuffix(number, ":"))
+		if err != nil {
+			return Event{}, false
+		}
+		return Event{Kind: Stage, Stage: stage, Description: strings.TrimSpace(description)}, true
+	case strings.HasPrefix(line, "Duration: "):
+		fields := fields(line)
+		duration, err := strconv.ParseFloat(strings.TrimSuffix(fields["Duration"], "s"), 64)
+		if err != nil {
+			return Event{}, false
+		}
+		stages, _ := strconv.Atoi(fields["Stages"])
+		return Event{Kind: Started, Duration: duration, Stages: stages}, true
+	case strings.HasPrefix(line, "Time: "):
+		return parseSample(line)
+	}
+	return Event{}, false
+}
+
+func parseSample(line string) (Event, bool) {
+	event := Event{Kind: Sample}
+	var err error
+	for i, field := range strings.Split(line, ", ") {
+		key, value, ok := strings.Cut(field, ": ")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Time":
+			event.Elapsed, err = strconv.ParseFloat(strings.TrimSuffix(value, "s"), 64)
+		case "Stage":
+			event.Stage, err = strconv.Atoi(value)
+		case "Avg FPS":
+			event.AvgFps, err = strconv.ParseFloat(value, 64)
+		case "Min FPS":
+			event.MinFps, err = strconv.ParseFloat(value, 64)
+		default:
+			// The load comes third, under the name of the test's CSV column
+			if i == 2 {
+				event.LoadLabel = key
+				event.Load, _ = strconv.ParseFloat(value, 64)
+			}
+		}
+		if err != nil {
+			return Event{}, false
+		}
+	}
+	return event, true
+}
+
+// "Key: value, Key: value" as a map
+func fields(line string) map[string]string {
+	values := make(map[string]string)
+	for _, field := range strings.Split(line, ", ") {
+		if key, value, ok := strings.Cut(field, ": "); ok {
+			values[key] = value
+		}
+	}
+	return values
+}
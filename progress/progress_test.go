@@ -0,0 +1,58 @@
+package progress
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLine(t *testing.T) {
+	cases := []struct {
+		line string
+		want Event
+		ok   bool
+	}{
+		{"Duration: 60s, Stages: 6", Event{Kind: Started, Duration: 60, Stages: 6}, true},
+		{"Warming up...", Event{Kind: Warmup}, true},
+		{"Starting stage 2 with 40000 particles", Event{Kind: Stage, Stage: 2, Description: "with 40000 particles"}, true},
+		{"Starting stage 3: fp32 MAD with 4096 iterations", Event{Kind: Stage, Stage: 3, Description: "fp32 MAD with 4096 iterations"}, true},
+		{"Starting stage 4", Event{Kind: Stage, Stage: 4}, true},
+		{
+			"Time: 12.5s, Stage: 2, Particles: 40000, Avg FPS: 60.1, Min FPS: 40.2",
+			Event{Kind: Sample, Elapsed: 12.5, Stage: 2, LoadLabel: "Particles", Load: 40000, AvgFps: 60.1, MinFps: 40.2},
+			true,
+		},
+		{
+			// Custom tests name the load after their uniform, the ALU test adds fields at the end
+			"Time: 3.0s, Stage: 1, Iterations: 64, Avg FPS: 900.0, Min FPS: 850.5, Kernel: sin, Gops/s: 12.34",
+			Event{Kind: Sample, Elapsed: 3, Stage: 1, LoadLabel: "Iterations", Load: 64, AvgFps: 900, MinFps: 850.5},
+			true,
+		},
+		{"  Time: 1.0s, Stage: 1, Points: 10, Avg FPS: 5.0, Min FPS: 4.0\r", Event{Kind: Sample, Elapsed: 1, Stage: 1, LoadLabel: "Points", Load: 10, AvgFps: 5, MinFps: 4}, true},
+		{"Time: soon, Stage: 1", Event{}, false},
+		{"Time: 1.0s, Stage: two", Event{}, false},
+		{"Starting stage x with nothing", Event{}, false},
+		{"Duration: long", Event{}, false},
+		{"Renderer: Mesa Intel(R) UHD Graphics", Event{}, false},
+		{"", Event{}, false},
+	}
+	for _, c := range cases {
+		got, ok := ParseLine(c.line)
+		if ok != c.ok || got != c.want {
+			t.Errorf("ParseLine(%q) = %+v, %v, want %+v, %v", c.line, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	output := "Renderer: test\nDuration: 20s, Stages: 2\nWarming up...\n\nStarting stage 1 with 8 octaves\n" +
+		"Time: 0.5s, Stage: 1, Wave Octaves: 8, Avg FPS: 144.0, Min FPS: 120.0\nResults saved\n"
+	var kinds []string
+	if err := Watch(strings.NewReader(output), func(event Event) {
+		kinds = append(kinds, event.Kind)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(kinds, ","); got != "started,warmup,stage,sample" {
+		t.Errorf("events %s, want started,warmup,stage,sample", got)
+	}
+}
@@ -0,0 +1,375 @@
+// Package report renders a run document as a single self-contained HTML file: no scripts and
+// no external resources, charts are inline SVG and screenshots are embedded.
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"moddergltest/rundoc"
+	"moddergltest/stats"
+)
+
+// GL capabilities shown as strings rather than limits
+var glStrings = []string{"vendor", "renderer", "version", "glsl_version"}
+
+type page struct {
+	Doc          *rundoc.Document
+	Generated    time.Time
+	Total        string
+	ScorePerWatt float64 // 0 without the power of every core test
+	HasPower     bool
+	Unstable     []string
+	System       [][2]string
+	GLStrings    [][2]string
+	GLLimits     [][2]string
+	Extensions   []string
+	Memory       *rundoc.MemoryProbe
+	ALU          [][2]string
+	Tests        []testView
+	Iterations   *iterationView
+}
+
+type testView struct {
+	Name           string
+	Kind           string
+	LoadLabel      string
+	Score          string
+	AvgFps         float64
+	MinFps         float64
+	P99FrameTimeMs float64
+	Incomplete     string
+	Power          *rundoc.Power
+	Noise          *rundoc.Noise
+	Process        *rundoc.ProcessUsage
+	Bottlenecks    string // bounds of the stages, empty when none is classified
+	Screenshot     template.URL
+	FPSChart       template.HTML
+	FrameTimeChart template.HTML
+	TempChart      template.HTML // empty without sensor readings
+	ClockChart     template.HTML
+	PowerChart     template.HTML
+	Stages         []stageRow
+	HasGPUTime     bool
+	HasSensors     bool
+	HasPower       bool
+	HasProcess     bool
+	HasBottlenecks bool
+	Percentiles    []percentileRow
+}
+
+type stageRow struct {
+	Stage      int
+	Load       float64
+	Samples    int
+	AvgFps     float64
+	MinFps     float64
+	P50Fps     float64
+	P5Fps      float64
+	P1Fps      float64
+	P99FrameMs float64
+	GPUTimeMs  float64
+	// Sensors: the hottest reading and the mean clocks of the stage
+	GPUTempC float64
+	CPUTempC float64
+	GPUMHz   float64
+	CPUMHz   float64
+	Power    *rundoc.Power
+	Process  *rundoc.ProcessUsage // of the test process
+	// What limits the stage, Bound is empty when it is not classified
+	Bottleneck rundoc.Bottleneck
+}
+
+type percentileRow struct {
+	Percentile string
+	Fps        float64 // average FPS of the half-second samples
+	FrameMs    float64 // slowest frame of the half-second samples
+}
+
+type iterationView struct {
+	Names   []string
+	Rows    [][]float64 // total first, then Names
+	Summary []summaryRow
+}
+
+type summaryRow struct {
+	Name  string
+	Stats *rundoc.ScoreStats
+}
+
+// WriteHTML renders the report of a run
+func WriteHTML(w io.Writer, doc *rundoc.Document) error {
+	return reportTemplate.Execute(w, newPage(doc))
+}
+
+// WriteHTMLFile renders the report of a run to a file
+func WriteHTMLFile(path string, doc *rundoc.Document) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteHTML(file, doc); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func newPage(doc *rundoc.Document) *page {
+	p := &page{
+		Doc:          doc,
+		Generated:    time.Now(),
+		Total:        formatScore(doc, rundoc.TotalScoreKey, doc.TotalScore),
+		ScorePerWatt: doc.ScorePerWatt(),
+		Unstable:     doc.UnstableScores(),
+		Memory:       doc.Diagnostics.Memory,
+	}
+
+	system := doc.System
+	p.System = [][2]string{
+		{"GPU", system.GpuName},
+		{"VRAM", system.VramSize},
+		{"Driver", system.DriverVersion},
+		{"CPU", system.CpuName},
+		{"RAM", system.RamSize},
+		{"Windows", system.WindowsVersion},
+		{"Wine", fmt.Sprint(system.UsesWine)},
+	}
+
+	for _, name := range glStrings {
+		if value, ok := doc.Diagnostics.GL[name]; ok {
+			p.GLStrings = append(p.GLStrings, [2]string{name, value})
+		}
+	}
+	for _, name := range sortedKeys(doc.Diagnostics.GL) {
+		if name != "extensions" && !slices.Contains(glStrings, name) {
+			p.GLLimits = append(p.GLLimits, [2]string{name, doc.Diagnostics.GL[name]})
+		}
+	}
+	p.Extensions = strings.Fields(doc.Diagnostics.GL["extensions"])
+
+	for _, kernel := range sortedKeys(doc.Diagnostics.ALU) {
+		p.ALU = append(p.ALU, [2]string{kernel, fmt.Sprintf("%.1f Gops/s", doc.Diagnostics.ALU[kernel])})
+	}
+
+	for _, name := range doc.TestNames() {
+		p.Tests = append(p.Tests, newTestView(doc, name))
+		p.HasPower = p.HasPower || doc.Tests[name].Power != nil
+	}
+
+	if len(doc.Iterations) > 0 {
+		view := &iterationView{}
+		for _, name := range doc.TestNames() {
+			if _, ok := doc.Summary[name]; ok {
+				view.Names = append(view.Names, name)
+			}
+		}
+		for _, iteration := range doc.Iterations {
+			row := []float64{iteration.TotalScore}
+			for _, name := range view.Names {
+				row = append(row, iteration.Scores[name])
+			}
+			view.Rows = append(view.Rows, row)
+		}
+		for _, name := range append([]string{rundoc.TotalScoreKey}, view.Names...) {
+			view.Summary = append(view.Summary, summaryRow{Name: name, Stats: doc.Summary[name]})
+		}
+		p.Iterations = view
+	}
+	return p
+}
+
+func newTestView(doc *rundoc.Document, name string) testView {
+	test := doc.Tests[name]
+	view := testView{
+		Name:           name,
+		Kind:           test.Kind,
+		LoadLabel:      test.LoadLabel,
+		Score:          formatScore(doc, name, test.Score),
+		AvgFps:         test.AvgFps,
+		MinFps:         test.MinFps,
+		P99FrameTimeMs: test.P99FrameTimeMs(),
+		Incomplete:     test.Incomplete,
+		Power:          test.Power,
+		Noise:          test.Noise,
+		Process:        test.Process,
+		Bottlenecks:    test.BottleneckSummary(),
+	}
+	// Only embedded images, a report never loads anything from elsewhere
+	if strings.HasPrefix(test.Screenshot, "data:image/jpeg;base64,") || strings.HasPrefix(test.Screenshot, "data:image/png;base64,") {
+		view.Screenshot = template.URL(test.Screenshot)
+	}
+
+	var times, avgFps, minFps, avgFrameMs, worstFrameMs, allFps, allFrameMs []float64
+	var marks []mark
+	for i, sample := range test.Samples {
+		times = append(times, sample.Time)
+		avgFps = append(avgFps, sample.AvgFps)
+		minFps = append(minFps, sample.MinFps)
+		avgFrameMs = append(avgFrameMs, frameMs(sample.AvgFps))
+		worstFrameMs = append(worstFrameMs, frameMs(sample.MinFps))
+		allFps = append(allFps, sample.AvgFps)
+		if sample.MinFps > 0 {
+			allFrameMs = append(allFrameMs, frameMs(sample.MinFps))
+		}
+		if i > 0 && sample.Stage != test.Samples[i-1].Stage {
+			marks = append(marks, mark{x: sample.Time, label: fmt.Sprintf("S%d", sample.Stage)})
+		}
+	}
+	view.FPSChart = lineChart("FPS over time", "FPS", []series{
+		{name: "Avg FPS", color: "#2563eb", x: times, y: avgFps},
+		{name: "Min FPS", color: "#dc2626", x: times, y: minFps},
+	}, marks)
+	view.FrameTimeChart = lineChart("Frame time over time", "ms", []series{
+		{name: "Average frame", color: "#2563eb", x: times, y: avgFrameMs},
+		{name: "Slowest frame", color: "#dc2626", x: times, y: worstFrameMs},
+	}, marks)
+
+	view.TempChart, view.ClockChart, view.PowerChart = sensorCharts(test, marks)
+
+	for _, percentile := range []float64{1, 5, 50, 95, 99} {
+		view.Percentiles = append(view.Percentiles, percentileRow{
+			Percentile: fmt.Sprintf("P%g", percentile),
+			// Low FPS and long frames are the bad end, so the two columns mirror each other
+			Fps:     stats.Percentile(allFps, percentile),
+			FrameMs: stats.Percentile(allFrameMs, 100-percentile),
+		})
+	}
+
+	samples := test.SamplesByStage()
+	gpuTime := test.ColumnByStage("GPU Time")
+	view.HasGPUTime = gpuTime != nil
+	readings := test.SensorsByStage()
+	view.HasSensors = len(test.Sensors) > 0
+	power := test.PowerByStage()
+	view.HasPower = len(power) > 0
+	process := make(map[int]*rundoc.ProcessUsage)
+	for i := range test.ProcessStages {
+		process[test.ProcessStages[i].Stage] = &test.ProcessStages[i]
+	}
+	view.HasProcess = len(process) > 0
+	bottlenecks := test.BottlenecksByStage()
+	view.HasBottlenecks = len(bottlenecks) > 0
+	for _, stage := range test.Stages() {
+		stageSamples := samples[stage]
+		var fps, minimum, worst []float64
+		for _, sample := range stageSamples {
+			fps = append(fps, sample.AvgFps)
+			minimum = append(minimum, sample.MinFps)
+			if sample.MinFps > 0 {
+				worst = append(worst, frameMs(sample.MinFps))
+			}
+		}
+		var gpuTemp, cpuTemp float64
+		var gpuMHz, cpuMHz []float64
+		for _, reading := range readings[stage] {
+			gpuTemp = max(gpuTemp, reading.GPUTempC)
+			cpuTemp = max(cpuTemp, reading.CPUTempC)
+			gpuMHz = append(gpuMHz, reading.GPUMHz)
+			cpuMHz = append(cpuMHz, reading.CPUMHz)
+		}
+		view.Stages = append(view.Stages, stageRow{
+			Stage:      stage,
+			Load:       stageSamples[0].Load,
+			Samples:    len(stageSamples),
+			AvgFps:     stats.Mean(fps),
+			MinFps:     stats.Mean(minimum),
+			P50Fps:     stats.Median(fps),
+			P5Fps:      stats.Percentile(fps, 5),
+			P1Fps:      stats.Percentile(fps, 1),
+			P99FrameMs: stats.Percentile(worst, 99),
+			GPUTimeMs:  stats.Mean(gpuTime[stage]),
+			GPUTempC:   gpuTemp,
+			CPUTempC:   cpuTemp,
+			GPUMHz:     stats.Mean(gpuMHz),
+			CPUMHz:     stats.Mean(cpuMHz),
+			Power:      power[stage],
+			Process:    process[stage],
+			Bottleneck: bottlenecks[stage],
+		})
+	}
+	return view
+}
+
+// Temperature, clock and power charts of a test on the time axis of its FPS chart, so that
+// throttling lines up with the FPS it costs. Sensors that never gave a reading are left out.
+func sensorCharts(test *rundoc.TestResult, marks []mark) (temps, clocks, power template.HTML) {
+	var times, gpuTemp, cpuTemp, gpuMHz, cpuMHz, gpuWatts, cpuWatts []float64
+	for i, reading := range test.Sensors {
+		times = append(times, reading.Time)
+		gpuTemp = append(gpuTemp, reading.GPUTempC)
+		cpuTemp = append(cpuTemp, reading.CPUTempC)
+		gpuMHz = append(gpuMHz, reading.GPUMHz)
+		cpuMHz = append(cpuMHz, reading.CPUMHz)
+		gpuWatts = append(gpuWatts, reading.GPUWatts)
+		// The CPU energy counter gives the power since the previous reading
+		watts := math.NaN()
+		if i > 0 {
+			previous := test.Sensors[i-1]
+			if previous.CPUEnergyJ > 0 && reading.Time > previous.Time {
+				watts = (reading.CPUEnergyJ - previous.CPUEnergyJ) / (reading.Time - previous.Time)
+			}
+		}
+		cpuWatts = append(cpuWatts, watts)
+	}
+	present := func(lines ...series) []series {
+		var kept []series
+		for _, line := range lines {
+			if slices.ContainsFunc(line.y, func(v float64) bool { return v > 0 }) {
+				kept = append(kept, line)
+			}
+		}
+		return kept
+	}
+	if lines := present(
+		series{name: "GPU", color: "#dc2626", x: times, y: gpuTemp},
+		series{name: "CPU", color: "#2563eb", x: times, y: cpuTemp},
+	); lines != nil {
+		temps = lineChart("Temperature over time", "°C", lines, marks)
+	}
+	if lines := present(
+		series{name: "GPU clock", color: "#dc2626", x: times, y: gpuMHz},
+		series{name: "CPU clock (mean)", color: "#2563eb", x: times, y: cpuMHz},
+	); lines != nil {
+		clocks = lineChart("Clocks over time", "MHz", lines, marks)
+	}
+	if lines := present(
+		series{name: "GPU", color: "#dc2626", x: times, y: gpuWatts},
+		series{name: "CPU package", color: "#2563eb", x: times, y: cpuWatts},
+	); lines != nil {
+		power = lineChart("Power over time", "W", lines, marks)
+	}
+	return temps, clocks, power
+}
+
+// Score as text; repeated runs give the mean with its 95% confidence interval
+func formatScore(doc *rundoc.Document, name string, score float64) string {
+	summary, ok := doc.Summary[name]
+	if !ok {
+		return fmt.Sprintf("%.2f", score)
+	}
+	return fmt.Sprintf("%.2f ± %.2f", summary.Mean, summary.CI95)
+}
+
+func frameMs(fps float64) float64 {
+	if fps <= 0 {
+		return math.NaN()
+	}
+	return 1000 / fps
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
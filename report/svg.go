@@ -0,0 +1,125 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"strings"
+)
+
+// Size of the charts in SVG units
+const (
+	chartWidth  = 760
+	chartHeight = 240
+	marginLeft  = 56
+	marginRight = 12
+	marginTop   = 24
+	marginBelow = 32
+)
+
+// series is one line of a chart
+type series struct {
+	name  string
+	color string
+	x, y  []float64
+}
+
+// mark is a vertical line across the chart, e.g. where a stage starts
+type mark struct {
+	x     float64
+	label string
+}
+
+// lineChart draws the series over a shared time axis as inline SVG
+func lineChart(title, unit string, lines []series, marks []mark) template.HTML {
+	var minX, maxX, maxY float64
+	minX = math.Inf(1)
+	for _, line := range lines {
+		for i := range line.x {
+			minX = min(minX, line.x[i])
+			maxX = max(maxX, line.x[i])
+			if !math.IsInf(line.y[i], 0) && !math.IsNaN(line.y[i]) {
+				maxY = max(maxY, line.y[i])
+			}
+		}
+	}
+	if math.IsInf(minX, 1) {
+		return ""
+	}
+	if maxX <= minX {
+		maxX = minX + 1
+	}
+	maxY = niceCeil(maxY)
+
+	plotWidth := float64(chartWidth - marginLeft - marginRight)
+	plotHeight := float64(chartHeight - marginTop - marginBelow)
+	px := func(x float64) float64 { return marginLeft + (x-minX)/(maxX-minX)*plotWidth }
+	py := func(y float64) float64 { return marginTop + plotHeight - min(y, maxY)/maxY*plotHeight }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg class="chart" viewBox="0 0 %d %d" xmlns="http://www.w3.org/2000/svg" role="img">`, chartWidth, chartHeight)
+	fmt.Fprintf(&b, `<text x="%d" y="16" class="title">%s</text>`, marginLeft, html.EscapeString(title))
+
+	// Horizontal grid with the value axis
+	for i := 0; i <= 4; i++ {
+		value := maxY * float64(i) / 4
+		y := py(value)
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" class="grid"/>`, marginLeft, y, chartWidth-marginRight, y)
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" class="axis" text-anchor="end">%s</text>`, marginLeft-6, y+4, formatAxis(value))
+	}
+	fmt.Fprintf(&b, `<text x="12" y="%d" class="axis" transform="rotate(-90 12 %d)" text-anchor="middle">%s</text>`,
+		marginTop+int(plotHeight/2), marginTop+int(plotHeight/2), html.EscapeString(unit))
+
+	// Time axis
+	for i := 0; i <= 5; i++ {
+		value := minX + (maxX-minX)*float64(i)/5
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" class="axis" text-anchor="middle">%.0f s</text>`, px(value), chartHeight-10, value)
+	}
+
+	for _, m := range marks {
+		x := px(m.x)
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%.1f" class="mark"/>`, x, marginTop, x, marginTop+plotHeight)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" class="axis">%s</text>`, x+3, marginTop+10, html.EscapeString(m.label))
+	}
+
+	for i, line := range lines {
+		points := make([]string, 0, len(line.x))
+		for j := range line.x {
+			if math.IsInf(line.y[j], 0) || math.IsNaN(line.y[j]) {
+				continue
+			}
+			points = append(points, fmt.Sprintf("%.1f,%.1f", px(line.x[j]), py(line.y[j])))
+		}
+		fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="1.5"/>`, strings.Join(points, " "), line.color)
+
+		// Legend in the top right corner
+		x := chartWidth - marginRight - 150*(len(lines)-i)
+		fmt.Fprintf(&b, `<rect x="%d" y="8" width="12" height="3" fill="%s"/>`, x, line.color)
+		fmt.Fprintf(&b, `<text x="%d" y="14" class="axis">%s</text>`, x+16, html.EscapeString(line.name))
+	}
+
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// Round up to a step of a power of ten that divides into four round grid lines
+func niceCeil(value float64) float64 {
+	if value <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(value)))
+	for _, step := range []float64{1, 2, 4, 6, 8, 10} {
+		if value <= step*magnitude {
+			return step * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+func formatAxis(value float64) string {
+	if value >= 100 || value == math.Trunc(value) {
+		return fmt.Sprintf("%.0f", value)
+	}
+	return fmt.Sprintf("%.1f", value)
+}
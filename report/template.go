@@ -0,0 +1,132 @@
+package report
+
+import (
+	"html/template"
+	"math"
+	"strconv"
+)
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"f1":  func(v float64) string { return formatFloat(v, 1) },
+	"f2":  func(v float64) string { return formatFloat(v, 2) },
+	"pct": func(v float64) string { return formatFloat(v*100, 1) + "%" },
+	"inc": func(i int) int { return i + 1 },
+	"f0":  func(v float64) string { return formatFloat(v, 0) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>GLTest report {{.Doc.System.GpuName}} {{.Doc.CreatedAt.Format "2006-01-02 15:04"}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em auto; max-width: 1000px; padding: 0 1em; color: #1f2937; }
+h1 { margin-bottom: 0.2em; }
+h2 { border-bottom: 1px solid #d1d5db; padding-bottom: 0.2em; margin-top: 2em; }
+.meta { color: #6b7280; }
+table { border-collapse: collapse; margin: 0.8em 0; }
+th, td { padding: 0.25em 0.8em; border-bottom: 1px solid #e5e7eb; text-align: left; }
+td.num, th.num { text-align: right; font-variant-numeric: tabular-nums; }
+.total { font-size: 2em; font-weight: bold; }
+.warn { color: #b45309; font-weight: bold; }
+.test { display: flex; gap: 1.5em; flex-wrap: wrap; align-items: flex-start; }
+.test img { max-width: 480px; border: 1px solid #d1d5db; }
+.chart { width: 100%; max-width: 760px; display: block; margin: 0.5em 0; }
+.chart .title { font-size: 13px; font-weight: bold; fill: #1f2937; }
+.chart .axis { font-size: 11px; fill: #6b7280; }
+.chart .grid { stroke: #e5e7eb; }
+.chart .mark { stroke: #9ca3af; stroke-dasharray: 4 3; }
+details { margin: 0.5em 0; }
+.extensions { font-family: monospace; font-size: 0.85em; columns: 2; }
+</style>
+</head>
+<body>
+<h1>GLTest report</h1>
+<p class="meta">Run {{.Doc.RunID}}, {{.Doc.CreatedAt.Format "2006-01-02 15:04:05 MST"}}, suite {{.Doc.Suite}}. Generated {{.Generated.Format "2006-01-02 15:04"}}.</p>
+<p><span class="total">{{.Total}}</span> total score{{if .ScorePerWatt}}, {{f2 .ScorePerWatt}} per watt{{end}}</p>
+{{if .Doc.Incomplete}}<p class="warn">Incomplete run: tests were skipped, timed out or cancelled, and tests that never ran are left out.</p>{{end}}
+{{if .Doc.Noisy}}<p class="warn">Noisy run: other work kept the system busy (up to {{pct .Doc.Noise}} CPU while a test ran), scores may be low.</p>{{end}}
+{{if .Unstable}}<p class="warn">Unstable over the iterations: {{range $i, $name := .Unstable}}{{if $i}}, {{end}}{{$name}}{{end}}</p>{{end}}
+
+<h2>System</h2>
+<table>
+{{range .System}}<tr><th>{{index . 0}}</th><td>{{index . 1}}</td></tr>
+{{end}}</table>
+
+{{if or .GLStrings .GLLimits}}
+<h2>OpenGL</h2>
+<table>
+{{range .GLStrings}}<tr><th>{{index . 0}}</th><td>{{index . 1}}</td></tr>
+{{end}}{{range .GLLimits}}<tr><th>{{index . 0}}</th><td class="num">{{index . 1}}</td></tr>
+{{end}}</table>
+{{if .Extensions}}<details><summary>{{len .Extensions}} extensions</summary>
+<div class="extensions">{{range .Extensions}}{{.}}<br>
+{{end}}</div></details>{{end}}
+{{end}}
+
+<h2>Scores</h2>
+<table>
+<tr><th>Test</th><th>Kind</th><th class="num">Score</th><th class="num">Avg FPS</th><th class="num">Min FPS</th><th class="num">P99 frame (ms)</th>{{if .HasPower}}<th class="num">Power (W)</th><th class="num">Frames/J</th><th class="num">Score/W</th>{{end}}</tr>
+{{$power := .HasPower}}{{range .Tests}}<tr><td><a href="#test-{{.Name}}">{{.Name}}</a>{{if .Incomplete}} <span class="warn">{{.Incomplete}}</span>{{end}}</td><td>{{.Kind}}</td><td class="num">{{.Score}}</td><td class="num">{{f1 .AvgFps}}</td><td class="num">{{f1 .MinFps}}</td><td class="num">{{f2 .P99FrameTimeMs}}</td>{{if $power}}{{with .Power}}<td class="num">{{f1 .Watts}}</td><td class="num">{{f2 .FramesPerJoule}}</td><td class="num">{{f2 .ScorePerWatt}}</td>{{else}}<td></td><td></td><td></td>{{end}}{{end}}</tr>
+{{end}}</table>
+
+{{with .Iterations}}
+<h2>Iterations</h2>
+<table>
+<tr><th>Score</th><th class="num">Iterations</th><th class="num">Mean</th><th class="num">Median</th><th class="num">Std dev</th><th class="num">95% CI</th><th class="num">CV</th><th></th></tr>
+{{range .Summary}}<tr><td>{{.Name}}</td><td class="num">{{.Stats.Iterations}}</td><td class="num">{{f2 .Stats.Mean}}</td><td class="num">{{f2 .Stats.Median}}</td><td class="num">{{f2 .Stats.StdDev}}</td><td class="num">± {{f2 .Stats.CI95}}</td><td class="num">{{pct .Stats.CV}}</td><td>{{if .Stats.Unstable}}<span class="warn">unstable</span>{{end}}</td></tr>
+{{end}}</table>
+<table>
+<tr><th>#</th><th class="num">total</th>{{range .Names}}<th class="num">{{.}}</th>{{end}}</tr>
+{{range $i, $row := .Rows}}<tr><td>{{inc $i}}</td>{{range $row}}<td class="num">{{f2 .}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}
+
+{{if or .Memory .ALU}}
+<h2>Diagnostics</h2>
+<table>
+{{with .Memory}}<tr><th>Upload</th><td class="num">{{f1 .UploadMBps}} MB/s</td></tr>
+<tr><th>Download</th><td class="num">{{f1 .DownloadMBps}} MB/s</td></tr>
+<tr><th>GPU copy</th><td class="num">{{f1 .CopyMBps}} MB/s</td></tr>
+<tr><th>Usable VRAM</th><td class="num">{{.VRAMEstimateMB}} MB ({{.VRAMNote}}){{if .DriverVRAMMB}}, driver: {{.DriverVRAMMB}} MB{{end}}{{if .DriverFreeVRAMMB}}, driver: {{.DriverFreeVRAMMB}} MB free{{end}}</td></tr>
+{{end}}{{range .ALU}}<tr><th>ALU {{index . 0}}</th><td class="num">{{index . 1}}</td></tr>
+{{end}}</table>
+{{end}}
+
+{{range .Tests}}
+<h2 id="test-{{.Name}}">{{.Name}}</h2>
+{{if .Incomplete}}<p class="warn">Stopped before its end ({{.Incomplete}}), the samples cover only part of the test.</p>{{end}}
+<p>Score {{.Score}}, average {{f1 .AvgFps}} FPS, minimum {{f1 .MinFps}} FPS, p99 frame time {{f2 .P99FrameTimeMs}} ms.</p>
+{{with .Noise}}<p{{if .Noisy}} class="warn"{{end}}>Other work: {{pct .PreflightCPU}} CPU and {{pct .PreflightMemory}} memory stall before the test, {{pct .CPU}} CPU and {{pct .Memory}} memory stall while it ran.</p>{{end}}
+{{with .Process}}<p>Test process: {{f1 .UserSec}} s user and {{f1 .SysSec}} s kernel CPU time over {{f1 .Seconds}} s ({{f2 .CPU}} cores busy on average), peak resident memory {{f0 .PeakRSSMB}} MB, {{f0 .VoluntarySwitches}} voluntary and {{f0 .InvoluntarySwitches}} involuntary context switches, {{f0 .MinorFaults}} minor and {{f0 .MajorFaults}} major page faults.</p>{{end}}
+{{with .Bottlenecks}}<p>Limited by: {{.}}.</p>{{end}}
+{{with .Power}}<p>Mean power {{f1 .Watts}} W{{if .CPUWatts}} (CPU package {{f1 .CPUWatts}} W{{if .GPUWatts}}, GPU {{f1 .GPUWatts}} W{{end}}){{else}} (GPU){{end}}, {{f2 .FramesPerJoule}} frames per joule, {{f2 .ScorePerWatt}} score per watt.</p>{{end}}
+<div class="test">
+{{if .Screenshot}}<img src="{{.Screenshot}}" alt="Last stage of {{.Name}}">{{end}}
+<table>
+<tr><th>Percentile</th><th class="num">Avg FPS</th><th class="num">Slowest frame (ms)</th></tr>
+{{range .Percentiles}}<tr><td>{{.Percentile}}</td><td class="num">{{f1 .Fps}}</td><td class="num">{{f2 .FrameMs}}</td></tr>
+{{end}}</table>
+</div>
+{{.FPSChart}}
+{{.FrameTimeChart}}
+{{.TempChart}}
+{{.ClockChart}}
+{{.PowerChart}}
+<table>
+<tr><th class="num">Stage</th><th class="num">{{or .LoadLabel "Load"}}</th><th class="num">Samples</th><th class="num">Avg FPS</th><th class="num">Min FPS</th><th class="num">P50 FPS</th><th class="num">P5 FPS</th><th class="num">P1 FPS</th><th class="num">P99 frame (ms)</th>{{if .HasGPUTime}}<th class="num">GPU (ms)</th>{{end}}{{if .HasSensors}}<th class="num">GPU max °C</th><th class="num">CPU max °C</th><th class="num">GPU MHz</th><th class="num">CPU MHz</th>{{end}}{{if .HasPower}}<th class="num">Power (W)</th><th class="num">Frames/J</th>{{end}}{{if .HasProcess}}<th class="num">CPU (cores)</th><th class="num">User (s)</th><th class="num">Kernel (s)</th><th class="num">Peak RSS (MB)</th><th class="num">Ctx switches</th><th class="num">Page faults</th>{{end}}{{if .HasBottlenecks}}<th>Bound</th>{{end}}</tr>
+{{$gpu := .HasGPUTime}}{{$sensors := .HasSensors}}{{$power := .HasPower}}{{$process := .HasProcess}}{{$bound := .HasBottlenecks}}{{range .Stages}}<tr><td class="num">{{.Stage}}</td><td class="num">{{.Load}}</td><td class="num">{{.Samples}}</td><td class="num">{{f1 .AvgFps}}</td><td class="num">{{f1 .MinFps}}</td><td class="num">{{f1 .P50Fps}}</td><td class="num">{{f1 .P5Fps}}</td><td class="num">{{f1 .P1Fps}}</td><td class="num">{{f2 .P99FrameMs}}</td>{{if $gpu}}<td class="num">{{f2 .GPUTimeMs}}</td>{{end}}{{if $sensors}}<td class="num">{{f1 .GPUTempC}}</td><td class="num">{{f1 .CPUTempC}}</td><td class="num">{{f1 .GPUMHz}}</td><td class="num">{{f1 .CPUMHz}}</td>{{end}}{{if $power}}{{with .Power}}<td class="num">{{f1 .Watts}}</td><td class="num">{{f2 .FramesPerJoule}}</td>{{else}}<td></td><td></td>{{end}}{{end}}{{if $process}}{{with .Process}}<td class="num">{{f2 .CPU}}</td><td class="num">{{f2 .UserSec}}</td><td class="num">{{f2 .SysSec}}</td><td class="num">{{f0 .PeakRSSMB}}</td><td class="num">{{f0 .VoluntarySwitches}} / {{f0 .InvoluntarySwitches}}</td><td class="num">{{f0 .MinorFaults}} / {{f0 .MajorFaults}}</td>{{else}}<td></td><td></td><td></td><td></td><td></td><td></td>{{end}}{{end}}{{if $bound}}<td title="{{.Bottleneck.Reason}}">{{.Bottleneck.Label}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}
+
+<p class="meta">FPS values are over the half-second samples the tests record: the average FPS of each sample and its slowest frame. Slowest-frame percentiles are therefore upper bounds of the frame time percentiles over all frames.</p>
+<p class="meta">The bound of a stage is a guess from the share of the frame the GPU and the CPU were busy, the time spent uploading and the CPU the test process used; hover it for the numbers behind it. Vsync/present means the frame rate sits at a display refresh rate with time to spare.</p>
+</body>
+</html>
+`))
+
+func formatFloat(v float64, precision int) string {
+	if math.IsNaN(v) {
+		return "-"
+	}
+	return strconv.FormatFloat(v, 'f', precision, 64)
+}
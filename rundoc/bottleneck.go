@@ -0,0 +1,257 @@
+package rundoc
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"moddergltest/stats"
+)
+
+// What limits the frame rate of a stage
+const (
+	BoundGPU    = "gpu"
+	BoundCPU    = "cpu"    // the test or the driver on the CPU
+	BoundVsync  = "vsync"  // presenting waits for the display
+	BoundUpload = "upload" // the CPU copies data to the GPU every frame
+)
+
+// Thresholds of the classification
+const (
+	boundShare     = 0.8  // of the frame time the GPU or the CPU takes when it limits the frame
+	uploadShare    = 0.5  // of the CPU time spent in uploads when they limit the frame
+	busyCores      = 0.9  // cores the test process keeps busy when the CPU limits the frame
+	idleCores      = 0.5  // below this the CPU mostly waits
+	vsyncTolerance = 0.03 // relative distance of the FPS from a refresh rate
+	vsyncGPUShare  = 0.5  // of the frame time the GPU takes at most when it waits for the display
+	kernelShare    = 0.3  // of the CPU time in the kernel worth pointing out
+)
+
+// Refresh rates of common displays, a stage holding one of them may be waiting for the display
+var refreshRates = []float64{30, 50, 60, 72, 75, 90, 100, 120, 144, 165, 180, 240}
+
+// Bottleneck is what limits one stage and the numbers that show it
+type Bottleneck struct {
+	Stage  int    `json:"stage"`
+	Bound  string `json:"bound"`
+	Reason string `json:"reason"`
+}
+
+// Label names the bound for people
+func (b Bottleneck) Label() string {
+	switch b.Bound {
+	case BoundGPU:
+		return "GPU"
+	case BoundCPU:
+		return "CPU/driver"
+	case BoundVsync:
+		return "Vsync/present"
+	case BoundUpload:
+		return "Upload"
+	}
+	return b.Bound
+}
+
+// BottlenecksByStage classifies every stage from the ratios of what the test measured: the GPU
+// time of its timer queries, its CPU time per frame and the part of it in uploads, and the CPU
+// the test process used. A stage with too little to go on is left out. Without GPU time a
+// busy CPU is taken as the limit, although some drivers spin on the CPU while they wait for
+// the GPU. A stage at a refresh rate waits for the display only when its GPU time is well
+// below the frame time, or when it holds the rate next to a stage of more load that does too.
+func (t *TestResult) BottlenecksByStage() map[int]Bottleneck {
+	samples := t.SamplesByStage()
+	gpuTime := t.ColumnByStage("GPU Time")
+	cpuTime := t.ColumnByStage("CPU Time")
+	uploadTime := t.ColumnByStage("Upload Time")
+	process := make(map[int]*ProcessUsage)
+	for i := range t.ProcessStages {
+		process[t.ProcessStages[i].Stage] = &t.ProcessStages[i]
+	}
+
+	order := t.Stages()
+	frames := make([]stageFrame, len(order))
+	for i, stage := range order {
+		var fps, load []float64
+		for _, sample := range samples[stage] {
+			fps = append(fps, sample.AvgFps)
+			load = append(load, sample.Load)
+		}
+		frames[i] = stageFrame{
+			fps:      stats.Mean(fps),
+			load:     stats.Mean(load),
+			gpuMs:    meanOrNaN(gpuTime[stage]),
+			cpuMs:    meanOrNaN(cpuTime[stage]),
+			uploadMs: meanOrNaN(uploadTime[stage]),
+			process:  process[stage],
+		}
+	}
+	pinVsync(frames)
+
+	stages := make(map[int]Bottleneck)
+	for i, stage := range order {
+		if bound, reason := frames[i].classify(); bound != "" {
+			stages[stage] = Bottleneck{Stage: stage, Bound: bound, Reason: reason}
+		}
+	}
+	return stages
+}
+
+// pinVsync marks the frames, in stage order, that hold a refresh rate together with the stage
+// before or after them while the load rises between the two: more work at the same FPS means
+// the FPS is not set by the work
+func pinVsync(frames []stageFrame) {
+	for i := 1; i < len(frames); i++ {
+		before, after := &frames[i-1], &frames[i]
+		rate := nearestRefreshRate(after.fps)
+		if rate > 0 && nearestRefreshRate(before.fps) == rate && after.load > before.load {
+			before.pinned, after.pinned = true, true
+		}
+	}
+}
+
+// BottleneckSummary lists the bounds of the test with their stages in order, e.g.
+// "CPU/driver (stages 1-2), Upload (stages 3-6)"; empty when no stage is classified
+func (t *TestResult) BottleneckSummary() string {
+	bottlenecks := t.BottlenecksByStage()
+	var labels []string
+	stages := make(map[string][]int)
+	for _, stage := range t.Stages() {
+		bottleneck, ok := bottlenecks[stage]
+		if !ok {
+			continue
+		}
+		label := bottleneck.Label()
+		if _, seen := stages[label]; !seen {
+			labels = append(labels, label)
+		}
+		stages[label] = append(stages[label], stage)
+	}
+	parts := make([]string, len(labels))
+	for i, label := range labels {
+		parts[i] = fmt.Sprintf("%s (%s)", label, stageList(stages[label]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// stageFrame is the mean frame of a stage; times not measured are NaN. pinned is set when
+// the stage holds a refresh rate over rising load.
+type stageFrame struct {
+	fps, load              float64
+	gpuMs, cpuMs, uploadMs float64
+	process                *ProcessUsage
+	pinned                 bool
+}
+
+func (f stageFrame) classify() (string, string) {
+	if f.fps <= 0 {
+		return "", ""
+	}
+	frameMs := 1000 / f.fps
+	gpu := f.gpuMs / frameMs
+	cpu := f.cpuMs / frameMs
+	cores := math.NaN()
+	if f.process != nil && f.process.Seconds > 0 {
+		cores = f.process.CPU()
+	}
+	// NaN compares false, so a time not measured never limits the frame. The CPU time per
+	// frame counts when the test measured it, the test process otherwise.
+	gpuBusy := gpu >= boundShare
+	cpuBusy := cpu >= boundShare || math.IsNaN(cpu) && cores >= busyCores
+
+	// An FPS at a refresh rate alone is no proof, many stages land near 60 or 144 by chance
+	switch rate := nearestRefreshRate(f.fps); {
+	case rate > 0 && !gpuBusy && !cpuBusy:
+		switch {
+		case gpu <= vsyncGPUShare:
+			return BoundVsync, reason(fmt.Sprintf("holds %.0f FPS, a display refresh rate", rate), f.shares(gpu, cpu))
+		case f.pinned:
+			return BoundVsync, reason(fmt.Sprintf("holds %.0f FPS, a display refresh rate, as the load rises", rate), f.shares(gpu, cpu))
+		}
+		return "", ""
+	case gpuBusy && !(cpu > gpu):
+		return BoundGPU, f.shares(gpu, cpu)
+	case cpu >= boundShare && f.uploadMs >= uploadShare*f.cpuMs:
+		return BoundUpload, reason(fmt.Sprintf("uploads take %.0f%% of the frame", f.uploadMs/frameMs*100), f.shares(gpu, cpu))
+	case cpu >= boundShare:
+		return BoundCPU, f.shares(gpu, cpu)
+	case cpuBusy:
+		return BoundCPU, reason(fmt.Sprintf("the test process keeps %.2f cores busy", cores)+f.kernel(), f.shares(gpu, cpu))
+	case math.IsNaN(gpu) && math.IsNaN(cpu) && cores < idleCores:
+		return BoundGPU, fmt.Sprintf("the test process keeps only %.2f cores busy, it waits for the GPU", cores)
+	}
+	return "", ""
+}
+
+// "GPU busy 92% and CPU 20% of the frame" for the shares that were measured
+func (f stageFrame) shares(gpu, cpu float64) string {
+	switch {
+	case !math.IsNaN(gpu) && !math.IsNaN(cpu):
+		return fmt.Sprintf("GPU busy %.0f%% and CPU %.0f%% of the frame", gpu*100, cpu*100) + f.kernel()
+	case !math.IsNaN(gpu):
+		return fmt.Sprintf("GPU busy %.0f%% of the frame", gpu*100)
+	case !math.IsNaN(cpu):
+		return fmt.Sprintf("CPU busy %.0f%% of the frame", cpu*100) + f.kernel()
+	}
+	return ""
+}
+
+// ", 40% of it in the kernel" when the driver spends much of the CPU time there
+func (f stageFrame) kernel() string {
+	if f.process == nil || f.process.UserSec+f.process.SysSec <= 0 {
+		return ""
+	}
+	share := f.process.SysSec / (f.process.UserSec + f.process.SysSec)
+	if share < kernelShare {
+		return ""
+	}
+	return fmt.Sprintf(", %.0f%% of it in the kernel", share*100)
+}
+
+// The parts that are not empty, joined
+func reason(parts ...string) string {
+	var kept []string
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, "; ")
+}
+
+// "stage 3", "stages 3-6" or "stages 1, 4-5"
+func stageList(stages []int) string {
+	var ranges []string
+	for i := 0; i < len(stages); {
+		j := i
+		for j+1 < len(stages) && stages[j+1] == stages[j]+1 {
+			j++
+		}
+		if j > i {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", stages[i], stages[j]))
+		} else {
+			ranges = append(ranges, fmt.Sprint(stages[i]))
+		}
+		i = j + 1
+	}
+	if len(stages) == 1 {
+		return "stage " + ranges[0]
+	}
+	return "stages " + strings.Join(ranges, ", ")
+}
+
+// The refresh rate the FPS is within the tolerance of, 0 for none
+func nearestRefreshRate(fps float64) float64 {
+	for _, rate := range refreshRates {
+		if math.Abs(fps-rate) <= rate*vsyncTolerance {
+			return rate
+		}
+	}
+	return 0
+}
+
+func meanOrNaN(values []float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	return stats.Mean(values)
+}
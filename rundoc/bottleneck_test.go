@@ -0,0 +1,100 @@
+package rundoc
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	nan := math.NaN()
+	cases := []struct {
+		name   string
+		frame  stageFrame
+		bound  string
+		reason string // part of the reason, when it matters
+	}{
+		{"no frames", stageFrame{gpuMs: nan, cpuMs: nan, uploadMs: nan}, "", ""},
+		{"GPU timer queries fill the frame", stageFrame{fps: 100, gpuMs: 9, cpuMs: 2, uploadMs: nan}, BoundGPU, "GPU busy 90%"},
+		{"CPU above the GPU", stageFrame{fps: 100, gpuMs: 8.5, cpuMs: 9.5, uploadMs: 1}, BoundCPU, "CPU 95%"},
+		{"uploads take most of the CPU", stageFrame{fps: 20, gpuMs: 10, cpuMs: 45, uploadMs: 30}, BoundUpload, "uploads take 60%"},
+		{
+			name:   "busy process without frame times",
+			frame:  stageFrame{fps: 40, gpuMs: nan, cpuMs: nan, uploadMs: nan, process: &ProcessUsage{Seconds: 10, UserSec: 6, SysSec: 4}},
+			bound:  BoundCPU,
+			reason: "1.00 cores busy, 40% of it in the kernel",
+		},
+		{
+			name:   "idle process without frame times",
+			frame:  stageFrame{fps: 40, gpuMs: nan, cpuMs: nan, uploadMs: nan, process: &ProcessUsage{Seconds: 10, UserSec: 2}},
+			bound:  BoundGPU,
+			reason: "only 0.20 cores busy",
+		},
+		{"neither busy nor idle", stageFrame{fps: 40, gpuMs: nan, cpuMs: nan, uploadMs: nan, process: &ProcessUsage{Seconds: 10, UserSec: 7}}, "", ""},
+		{"refresh rate with little GPU time", stageFrame{fps: 59.5, gpuMs: 3, cpuMs: 2, uploadMs: nan}, BoundVsync, "holds 60 FPS"},
+		{"refresh rate with GPU time close to the frame", stageFrame{fps: 60, gpuMs: 12, cpuMs: 2, uploadMs: nan}, "", ""},
+		{"refresh rate with the GPU busy", stageFrame{fps: 144, gpuMs: 6.5, cpuMs: 1, uploadMs: nan}, BoundGPU, ""},
+		// Without GPU time an FPS near a refresh rate proves nothing on its own
+		{"refresh rate alone", stageFrame{fps: 61, gpuMs: nan, cpuMs: nan, uploadMs: nan, process: &ProcessUsage{Seconds: 10, UserSec: 2}}, "", ""},
+		{
+			name:   "refresh rate over rising load",
+			frame:  stageFrame{fps: 61, gpuMs: nan, cpuMs: nan, uploadMs: nan, process: &ProcessUsage{Seconds: 10, UserSec: 2}, pinned: true},
+			bound:  BoundVsync,
+			reason: "as the load rises",
+		},
+		{
+			name:   "refresh rate over rising load with a busy CPU",
+			frame:  stageFrame{fps: 60, gpuMs: nan, cpuMs: 16, uploadMs: nan, pinned: true},
+			bound:  BoundCPU,
+			reason: "CPU busy 96%",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			bound, reason := c.frame.classify()
+			if bound != c.bound || !strings.Contains(reason, c.reason) {
+				t.Errorf("classify() = %q, %q, want %q with %q", bound, reason, c.bound, c.reason)
+			}
+		})
+	}
+}
+
+func TestStageList(t *testing.T) {
+	cases := []struct {
+		stages []int
+		want   string
+	}{
+		{[]int{3}, "stage 3"},
+		{[]int{3, 4, 5, 6}, "stages 3-6"},
+		{[]int{1, 4, 5}, "stages 1, 4-5"},
+		{[]int{1, 3, 5}, "stages 1, 3, 5"},
+		{[]int{1, 2, 4, 6, 7}, "stages 1-2, 4, 6-7"},
+	}
+	for _, c := range cases {
+		if got := stageList(c.stages); got != c.want {
+			t.Errorf("stageList(%v) = %q, want %q", c.stages, got, c.want)
+		}
+	}
+}
+
+func TestBottlenecksPinnedRefreshRate(t *testing.T) {
+	// Stages 1 and 2 hold 60 FPS while the load doubles, stage 3 drops below it and stage 4
+	// lands on 30 FPS alone. The process stays idle, no frame times are measured.
+	test := &TestResult{}
+	for stage, fps := range map[int]float64{1: 60.2, 2: 59.8, 3: 45, 4: 30} {
+		for i := 0; i < 2; i++ {
+			test.Samples = append(test.Samples, Sample{Stage: stage, Load: float64(stage * 1000), AvgFps: fps})
+		}
+		test.ProcessStages = append(test.ProcessStages, ProcessUsage{Stage: stage, Seconds: 10, UserSec: 6})
+	}
+
+	bottlenecks := test.BottlenecksByStage()
+	for stage, want := range map[int]string{1: BoundVsync, 2: BoundVsync, 3: "", 4: ""} {
+		if got := bottlenecks[stage].Bound; got != want {
+			t.Errorf("stage %d bound %q, want %q", stage, got, want)
+		}
+	}
+	if summary := test.BottleneckSummary(); summary != "Vsync/present (stages 1-2)" {
+		t.Errorf("BottleneckSummary() = %q", summary)
+	}
+}
@@ -0,0 +1,162 @@
+package rundoc
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ReadTestCSV reads a test CSV with Time, Stage, Load, Avg FPS and Min FPS as its first
+// columns. AvgFps and MinFps are the means over all samples.
+func ReadTestCSV(csvPath string, kind string) (*TestResult, error) {
+	// Opening CSV
+	file, err := os.Open(csvPath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open file %s: %v", csvPath, err)
+	}
+	defer file.Close()
+
+	// Reading CSV
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("Error reading CSV %s: %v", csvPath, err)
+	}
+
+	// Skip header
+	if len(rows) <= 1 {
+		return nil, fmt.Errorf("Insufficient data in file %s", csvPath)
+	}
+	test := &TestResult{Kind: kind, Columns: rows[0], Rows: rows[1:]}
+	if len(rows[0]) > 2 {
+		test.LoadLabel = rows[0][2]
+	}
+
+	var avgFpsSum, minFpsSum float64
+	for _, row := range rows[1:] {
+		if len(row) < 5 {
+			continue
+		}
+
+		timeSec, _ := strconv.ParseFloat(row[0], 64)
+		stage, _ := strconv.Atoi(row[1])
+		load, _ := strconv.ParseFloat(row[2], 64)
+		avgFps, _ := strconv.ParseFloat(row[3], 64)
+		minFps, _ := strconv.ParseFloat(row[4], 64)
+
+		test.Samples = append(test.Samples, Sample{Time: timeSec, Stage: stage, Load: load, AvgFps: avgFps, MinFps: minFps})
+		avgFpsSum += avgFps
+		minFpsSum += minFps
+	}
+	if len(test.Samples) == 0 {
+		return nil, fmt.Errorf("Insufficient data in file %s", csvPath)
+	}
+
+	test.AvgFps = avgFpsSum / float64(len(test.Samples))
+	test.MinFps = minFpsSum / float64(len(test.Samples))
+	return test, nil
+}
+
+// CalculateScore sets the score of the test from its samples. Without a normalize factor the
+// highest stage load is used.
+func (t *TestResult) CalculateScore(normalizeFactor float64) (float64, error) {
+	var avgLoadSum, maxLoad float64
+	for _, sample := range t.Samples {
+		avgLoadSum += sample.Load
+		maxLoad = max(maxLoad, sample.Load)
+	}
+	if normalizeFactor <= 0 {
+		normalizeFactor = maxLoad
+	}
+	if normalizeFactor <= 0 || len(t.Samples) == 0 {
+		return 0, fmt.Errorf("No load recorded")
+	}
+	avgLoad := avgLoadSum / float64(len(t.Samples))
+
+	// Formula
+	t.Score = ((t.AvgFps*0.7 + t.MinFps*0.3) * avgLoad) / normalizeFactor
+	return t.Score, nil
+}
+
+// ColumnByStage collects the positive values of the first extra CSV column whose name starts
+// with prefix, e.g. "GPU Time", by stage. Nil when the test has no such column.
+func (t *TestResult) ColumnByStage(prefix string) map[int][]float64 {
+	column := slices.IndexFunc(t.Columns, func(name string) bool {
+		return strings.HasPrefix(name, prefix)
+	})
+	if column < 0 {
+		return nil
+	}
+
+	stages := make(map[int][]float64)
+	for _, row := range t.Rows {
+		if len(row) <= column {
+			continue
+		}
+		stage, err := strconv.Atoi(row[1])
+		if err != nil {
+			continue
+		}
+		if value, err := strconv.ParseFloat(row[column], 64); err == nil && value > 0 {
+			stages[stage] = append(stages[stage], value)
+		}
+	}
+	return stages
+}
+
+// SamplesByStage groups the samples by stage
+func (t *TestResult) SamplesByStage() map[int][]Sample {
+	stages := make(map[int][]Sample)
+	for _, sample := range t.Samples {
+		stages[sample.Stage] = append(stages[sample.Stage], sample)
+	}
+	return stages
+}
+
+// Stages lists the stages of the test in order
+func (t *TestResult) Stages() []int {
+	var stages []int
+	for _, sample := range t.Samples {
+		if !slices.Contains(stages, sample.Stage) {
+			stages = append(stages, sample.Stage)
+		}
+	}
+	slices.Sort(stages)
+	return stages
+}
+
+// SetSensors keeps the sensor readings taken while the samples were measured, each under the
+// stage of the sample whose half second it falls into, and the mean power they show. The
+// test is scored first.
+func (t *TestResult) SetSensors(readings []SensorSample) {
+	t.Sensors, t.Power = nil, nil
+	if len(t.Samples) == 0 {
+		return
+	}
+	last := t.Samples[len(t.Samples)-1].Time
+	for _, reading := range readings {
+		if reading.Time < 0 || reading.Time > last {
+			continue
+		}
+		i := sort.Search(len(t.Samples), func(i int) bool { return t.Samples[i].Time >= reading.Time })
+		reading.Stage = t.Samples[i].Stage
+		t.Sensors = append(t.Sensors, reading)
+	}
+	if t.Power = MeanPower(t.Sensors, t.AvgFps); t.Power != nil {
+		t.Power.ScorePerWatt = t.Score / t.Power.Watts
+	}
+}
+
+// SensorsByStage groups the sensor readings by stage
+func (t *TestResult) SensorsByStage() map[int][]SensorSample {
+	stages := make(map[int][]SensorSample)
+	for _, reading := range t.Sensors {
+		stages[reading.Stage] = append(stages[reading.Stage], reading)
+	}
+	return stages
+}
@@ -0,0 +1,353 @@
+// Package rundoc defines the run document: everything GLTest knows about one benchmark run,
+// written by the runner and read by the sender and every later consumer.
+package rundoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"time"
+
+	"moddergltest/stats"
+)
+
+// Version of the document layout, raised on incompatible changes
+const SchemaVersion = 1
+
+// Test kinds. Only core tests add up to the total score.
+const (
+	KindCore     = "core"
+	KindExtended = "extended"
+	KindCustom   = "custom"
+)
+
+// Suites. Full runs have the total score of every other full run, quick runs that of other
+// quick runs; a custom selection of tests or test parameters has none.
+const (
+	SuiteFull   = "full"   // every test with its own parameters, and the diagnostics
+	SuiteQuick  = "quick"  // the core tests only, each stopped at an earlier stage
+	SuiteCustom = "custom" // tests picked by hand, possibly shortened
+)
+
+// Why a test stopped before its end
+const (
+	StopSkipped   = "skipped"
+	StopTimeout   = "timeout"
+	StopCancelled = "cancelled"
+)
+
+// Tests that make up the total score
+var CoreTests = []string{"butterfly", "triangles", "ocean"}
+
+// Document is one benchmark run
+type Document struct {
+	SchemaVersion int                    `json:"schema_version"`
+	RunID         string                 `json:"run_id"`
+	CreatedAt     time.Time              `json:"created_at"`
+	Suite         string                 `json:"suite,omitempty"` // which tests were run, "full" by default
+	System        System                 `json:"system"`
+	TotalScore    float64                `json:"total_score"`
+	Tests         map[string]*TestResult `json:"tests"`
+	Diagnostics   Diagnostics            `json:"diagnostics"`
+	// A test stopped early or the run was cancelled; tests that never ran are left out
+	Incomplete bool `json:"incomplete,omitempty"`
+	// Other work kept the machine busy around a test, see SetNoise
+	Noisy bool    `json:"noisy,omitempty"`
+	Noise float64 `json:"noise,omitempty"`
+	// Repeated runs only: every pass of the suite and each score summarized over them
+	Iterations  []Iteration            `json:"iterations,omitempty"`
+	Summary     map[string]*ScoreStats `json:"summary,omitempty"`
+	CVThreshold float64                `json:"cv_threshold,omitempty"`
+}
+
+// System is the machine the run happened on. Sizes are in MB, "Unknown" when not detected.
+type System struct {
+	GpuName        string `json:"gpu_name"`
+	VramSize       string `json:"vram_size"`
+	DriverVersion  string `json:"driver_version"`
+	WindowsVersion string `json:"windows_version"`
+	UsesWine       bool   `json:"uses_wine"`
+	RamSize        string `json:"ram_size"`
+	CpuName        string `json:"cpu_name"`
+}
+
+// TestResult is one test of the run with every sample of its CSV
+type TestResult struct {
+	Kind      string   `json:"kind"`
+	Score     float64  `json:"score"`
+	AvgFps    float64  `json:"avg_fps"`
+	MinFps    float64  `json:"min_fps"`
+	LoadLabel string   `json:"load_label"`
+	Samples   []Sample `json:"samples"`
+	// Last stage as a JPEG data URI, for reports
+	Screenshot string `json:"screenshot,omitempty"`
+	// Why the test stopped before its end, its samples then cover only part of it
+	Incomplete string `json:"incomplete,omitempty"`
+	// Raw CSV of the test, including columns beyond the five every test has
+	Columns []string   `json:"columns,omitempty"`
+	Rows    [][]string `json:"rows,omitempty"`
+	// Temperatures and clocks while the samples were measured, Linux only
+	Sensors []SensorSample `json:"sensors,omitempty"`
+	// Mean power over the samples, when the machine reports it
+	Power *Power `json:"power,omitempty"`
+	// Other work on the machine before and while the test ran, Linux only
+	Noise *Noise `json:"noise,omitempty"`
+	// What the test process used over the whole test and over each stage
+	Process       *ProcessUsage  `json:"process,omitempty"`
+	ProcessStages []ProcessUsage `json:"process_stages,omitempty"`
+}
+
+// Sample is one CSV row, recorded every half second
+type Sample struct {
+	Time   float64 `json:"time"`
+	Stage  int     `json:"stage"`
+	Load   float64 `json:"load"`
+	AvgFps float64 `json:"avg_fps"`
+	MinFps float64 `json:"min_fps"`
+}
+
+// SensorSample is one reading of temperatures and clocks, taken every half second on the time
+// axis of the samples. Values not available are zero.
+type SensorSample struct {
+	Time     float64            `json:"time"`
+	Stage    int                `json:"stage"`
+	GPUTempC float64            `json:"gpu_temp_c,omitempty"`
+	CPUTempC float64            `json:"cpu_temp_c,omitempty"`
+	GPUMHz   float64            `json:"gpu_mhz,omitempty"`
+	CPUMHz   float64            `json:"cpu_mhz,omitempty"` // mean over the cores
+	Temps    map[string]float64 `json:"temps,omitempty"`   // every sensor in °C, by "chip:label"
+	// RAPL package energy counter, only its differences mean anything
+	CPUEnergyJ float64 `json:"cpu_energy_j,omitempty"`
+	GPUWatts   float64 `json:"gpu_w,omitempty"`
+}
+
+// P99FrameTimeMs is the 99th percentile of the slowest frame of every sample, in milliseconds.
+// The CSVs record only the slowest frame of each half second, so this bounds the p99 of all
+// frames from above.
+func (t *TestResult) P99FrameTimeMs() float64 {
+	var frameTimes []float64
+	for _, sample := range t.Samples {
+		if sample.MinFps > 0 {
+			frameTimes = append(frameTimes, 1000/sample.MinFps)
+		}
+	}
+	return stats.Percentile(frameTimes, 99)
+}
+
+// Noise is the share of the machine other work took, checked for a few seconds before the test
+// and over the whole test. CPU is of all cores, memory the share of the time some task stalled
+// on memory; both 0 to 1.
+type Noise struct {
+	PreflightCPU    float64 `json:"preflight_cpu"`
+	PreflightMemory float64 `json:"preflight_memory"`
+	CPU             float64 `json:"cpu"`
+	Memory          float64 `json:"memory"`
+	Noisy           bool    `json:"noisy"` // either was busy enough to skew the result
+}
+
+// ProcessUsage is what the test process took of the machine over the whole test, from start to
+// exit, or over one stage, from its start to its last sample. Counters a system does not keep
+// are zero; on Windows there are no context switches and every page fault is minor.
+type ProcessUsage struct {
+	Stage               int     `json:"stage,omitempty"`
+	Seconds             float64 `json:"seconds"` // wall time covered
+	UserSec             float64 `json:"user_s"`
+	SysSec              float64 `json:"sys_s"`
+	PeakRSSMB           float64 `json:"peak_rss_mb"`
+	VoluntarySwitches   float64 `json:"voluntary_switches"`
+	InvoluntarySwitches float64 `json:"involuntary_switches"`
+	MinorFaults         float64 `json:"minor_faults"`
+	MajorFaults         float64 `json:"major_faults"`
+}
+
+// CPU is the number of cores the process kept busy on average, user and kernel time over
+// wall time
+func (u *ProcessUsage) CPU() float64 {
+	if u.Seconds <= 0 {
+		return 0
+	}
+	return (u.UserSec + u.SysSec) / u.Seconds
+}
+
+// Diagnostics are measured but not scored
+type Diagnostics struct {
+	Memory *MemoryProbe       `json:"memory,omitempty"`
+	ALU    map[string]float64 `json:"alu,omitempty"` // peak Gops/s per kernel
+	GL     map[string]string  `json:"gl,omitempty"`  // strings, limits and extensions of the GL implementation
+}
+
+// GPU memory probe results
+type MemoryProbe struct {
+	UploadMBps       float64 `json:"upload_mbps"`
+	DownloadMBps     float64 `json:"download_mbps"`
+	CopyMBps         float64 `json:"copy_mbps"`
+	VRAMEstimateMB   int     `json:"vram_estimate_mb"`
+	VRAMNote         string  `json:"vram_note"`
+	DriverVRAMMB     int     `json:"driver_vram_mb"`      // dedicated VRAM, NVIDIA only
+	DriverFreeVRAMMB int     `json:"driver_free_vram_mb"` // free VRAM when the probe ran, AMD only
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// New starts a document for a run
+func New(runID string, system System) *Document {
+	return &Document{
+		SchemaVersion: SchemaVersion,
+		RunID:         runID,
+		Suite:         SuiteFull,
+		CreatedAt:     time.Now(),
+		System:        system,
+		Tests:         make(map[string]*TestResult),
+	}
+}
+
+// Names of the tests in the document, core tests first
+func (d *Document) TestNames() []string {
+	names := make([]string, 0, len(d.Tests))
+	for name := range d.Tests {
+		names = append(names, name)
+	}
+	rank := func(name string) int {
+		for i, core := range CoreTests {
+			if name == core {
+				return i
+			}
+		}
+		return len(CoreTests)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if rank(names[i]) != rank(names[j]) {
+			return rank(names[i]) < rank(names[j])
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// Tests of the run that stopped before their end
+func (d *Document) IncompleteTests() []string {
+	var names []string
+	for _, name := range d.TestNames() {
+		if d.Tests[name].Incomplete != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// SetNoise flags the run as noisy when a test was, with the highest CPU share of other work
+// while a test ran as its noise
+func (d *Document) SetNoise() {
+	d.Noisy, d.Noise = false, 0
+	for _, test := range d.Tests {
+		if test.Noise != nil {
+			d.Noisy = d.Noisy || test.Noise.Noisy
+			d.Noise = max(d.Noise, test.Noise.CPU)
+		}
+	}
+}
+
+// Submittable tells why a valid run should still not go into the shared statistics
+func (d *Document) Submittable() error {
+	if d.Incomplete {
+		return fmt.Errorf("the run is incomplete, a test was skipped, timed out or cancelled")
+	}
+	if d.Suite == SuiteCustom {
+		return fmt.Errorf("the run is a custom selection of tests, its scores are not comparable")
+	}
+	if d.Suite == SuiteQuick {
+		return fmt.Errorf("the run is a quick run of shortened tests, its scores are not comparable")
+	}
+	return nil
+}
+
+// Validate checks that the document is complete and consistent before it is uploaded
+func (d *Document) Validate() error {
+	if d.SchemaVersion != SchemaVersion {
+		return fmt.Errorf("unsupported schema version %d, expected %d", d.SchemaVersion, SchemaVersion)
+	}
+	if !uuidPattern.MatchString(d.RunID) {
+		return fmt.Errorf("run_id %q is not a UUID", d.RunID)
+	}
+	if d.CreatedAt.IsZero() {
+		return fmt.Errorf("created_at is missing")
+	}
+	if d.System.GpuName == "" {
+		return fmt.Errorf("system.gpu_name is missing")
+	}
+
+	var coreSum float64
+	for _, name := range CoreTests {
+		test, ok := d.Tests[name]
+		if !ok && (d.Incomplete || d.Suite == SuiteCustom) {
+			continue
+		}
+		if !ok {
+			return fmt.Errorf("test %s is missing", name)
+		}
+		if test.Kind != KindCore {
+			return fmt.Errorf("test %s has kind %q, expected %q", name, test.Kind, KindCore)
+		}
+		coreSum += test.Score
+	}
+
+	for name, test := range d.Tests {
+		if test == nil {
+			return fmt.Errorf("test %s is empty", name)
+		}
+		switch test.Kind {
+		case KindCore, KindExtended, KindCustom:
+		default:
+			return fmt.Errorf("test %s has unknown kind %q", name, test.Kind)
+		}
+		if len(test.Samples) == 0 {
+			return fmt.Errorf("test %s has no samples", name)
+		}
+		if test.Incomplete != "" && !d.Incomplete {
+			return fmt.Errorf("test %s stopped early (%s) but the run is not marked incomplete", name, test.Incomplete)
+		}
+		for _, value := range []float64{test.Score, test.AvgFps, test.MinFps} {
+			if math.IsNaN(value) || math.IsInf(value, 0) || value < 0 {
+				return fmt.Errorf("test %s has an invalid value %v", name, value)
+			}
+		}
+	}
+
+	if math.Abs(coreSum-d.TotalScore) > 1e-6*math.Max(1, coreSum) {
+		return fmt.Errorf("total_score %.4f does not match the sum of core scores %.4f", d.TotalScore, coreSum)
+	}
+	return d.validateIterations()
+}
+
+// Write saves the document as indented JSON
+func (d *Document) Write(path string) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Read loads a document from a file, or from stdin when path is "-"
+func Read(path string) (*Document, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Document{}
+	if err := json.Unmarshal(data, d); err != nil {
+		return nil, fmt.Errorf("invalid run document: %v", err)
+	}
+	return d, nil
+}
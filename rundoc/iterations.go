@@ -0,0 +1,158 @@
+package rundoc
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"moddergltest/stats"
+)
+
+// Defaults of repeated runs
+const (
+	DefaultCVThreshold = 0.05 // a score varying more than 5% between iterations is unstable
+	ConfidenceLevel    = 0.95
+)
+
+// Summary key of the total score, next to the test names
+const TotalScoreKey = "total"
+
+// Iteration is one pass of the suite in a repeated run
+type Iteration struct {
+	TotalScore float64            `json:"total_score"`
+	Scores     map[string]float64 `json:"scores"`
+}
+
+// ScoreStats describes a score over all iterations of a run
+type ScoreStats struct {
+	Iterations int     `json:"iterations"`
+	Mean       float64 `json:"mean"`
+	Median     float64 `json:"median"`
+	StdDev     float64 `json:"stddev"`
+	CI95       float64 `json:"ci95"` // half-width of the 95% confidence interval of the mean
+	CV         float64 `json:"cv"`   // coefficient of variation, stddev / mean
+	Unstable   bool    `json:"unstable"`
+}
+
+// SetIterations records the passes of a repeated run and summarizes every score over them.
+// Tests and TotalScore keep the samples of a single pass, see MedianIteration.
+func (d *Document) SetIterations(iterations []Iteration, cvThreshold float64) {
+	if cvThreshold <= 0 {
+		cvThreshold = DefaultCVThreshold
+	}
+	d.Iterations = iterations
+	d.CVThreshold = cvThreshold
+	d.Summary = make(map[string]*ScoreStats)
+
+	values := make(map[string][]float64)
+	for _, iteration := range iterations {
+		values[TotalScoreKey] = append(values[TotalScoreKey], iteration.TotalScore)
+		for name, score := range iteration.Scores {
+			values[name] = append(values[name], score)
+		}
+	}
+	for name, scores := range values {
+		d.Summary[name] = summarize(scores, cvThreshold)
+	}
+}
+
+// Score of a test, or of TotalScoreKey: the mean over the iterations of a repeated run,
+// otherwise the single score. False when the test is not in the run.
+func (d *Document) Score(name string) (float64, bool) {
+	if summary, ok := d.Summary[name]; ok {
+		return summary.Mean, true
+	}
+	if name == TotalScoreKey {
+		return d.TotalScore, true
+	}
+	test, ok := d.Tests[name]
+	if !ok {
+		return 0, false
+	}
+	return test.Score, true
+}
+
+// IterationScores are the scores of a test, or of TotalScoreKey, in every iteration
+func (d *Document) IterationScores(name string) []float64 {
+	var scores []float64
+	for _, iteration := range d.Iterations {
+		if name == TotalScoreKey {
+			scores = append(scores, iteration.TotalScore)
+		} else if score, ok := iteration.Scores[name]; ok {
+			scores = append(scores, score)
+		}
+	}
+	return scores
+}
+
+// UnstableScores lists the scores whose variation exceeded the threshold, total first
+func (d *Document) UnstableScores() []string {
+	var names []string
+	for name, summary := range d.Summary {
+		if summary.Unstable {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if (names[i] == TotalScoreKey) != (names[j] == TotalScoreKey) {
+			return names[i] == TotalScoreKey
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// MedianIteration picks the iteration whose total score is closest to the median, the one
+// whose samples go into the document
+func MedianIteration(totals []float64) int {
+	median := stats.Median(totals)
+	best := 0
+	for i, total := range totals {
+		if math.Abs(total-median) < math.Abs(totals[best]-median) {
+			best = i
+		}
+	}
+	return best
+}
+
+func summarize(scores []float64, cvThreshold float64) *ScoreStats {
+	summary := &ScoreStats{
+		Iterations: len(scores),
+		Mean:       stats.Mean(scores),
+		Median:     stats.Median(scores),
+		StdDev:     stats.StdDev(scores),
+		CI95:       stats.ConfidenceInterval(scores, ConfidenceLevel),
+		CV:         stats.CoefficientOfVariation(scores),
+	}
+	summary.Unstable = summary.CV > cvThreshold
+	return summary
+}
+
+func (d *Document) validateIterations() error {
+	if len(d.Iterations) == 0 {
+		return nil
+	}
+	for i, iteration := range d.Iterations {
+		var coreSum float64
+		for _, name := range CoreTests {
+			score, ok := iteration.Scores[name]
+			if !ok {
+				return fmt.Errorf("iteration %d has no score for %s", i+1, name)
+			}
+			coreSum += score
+		}
+		for name, score := range iteration.Scores {
+			if math.IsNaN(score) || math.IsInf(score, 0) || score < 0 {
+				return fmt.Errorf("iteration %d has an invalid score %v for %s", i+1, score, name)
+			}
+		}
+		if math.Abs(coreSum-iteration.TotalScore) > 1e-6*math.Max(1, coreSum) {
+			return fmt.Errorf("iteration %d total_score %.4f does not match the sum of core scores %.4f",
+				i+1, iteration.TotalScore, coreSum)
+		}
+	}
+	if _, ok := d.Summary[TotalScoreKey]; !ok {
+		return fmt.Errorf("summary of the iterations is missing")
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package rundoc
+
+import "moddergltest/stats"
+
+// Power is the mean power draw over a test or a stage and what it bought. The CPU package
+// comes from its RAPL energy counter, the GPU from its own power reading; an integrated GPU
+// is part of the package and reports no power of its own.
+type Power struct {
+	CPUWatts       float64 `json:"cpu_w,omitempty"`
+	GPUWatts       float64 `json:"gpu_w,omitempty"`
+	Watts          float64 `json:"w"`
+	FramesPerJoule float64 `json:"frames_per_joule"`
+	ScorePerWatt   float64 `json:"score_per_watt,omitempty"` // whole tests only
+}
+
+// MeanPower is the power of the readings at the given average FPS, nil when none of them has
+// an energy counter or a power reading
+func MeanPower(readings []SensorSample, avgFps float64) *Power {
+	var first, last *SensorSample
+	var gpuWatts []float64
+	for i := range readings {
+		if readings[i].CPUEnergyJ > 0 {
+			if first == nil {
+				first = &readings[i]
+			}
+			last = &readings[i]
+		}
+		if readings[i].GPUWatts > 0 {
+			gpuWatts = append(gpuWatts, readings[i].GPUWatts)
+		}
+	}
+
+	power := &Power{}
+	if first != nil && last.Time > first.Time {
+		power.CPUWatts = (last.CPUEnergyJ - first.CPUEnergyJ) / (last.Time - first.Time)
+	}
+	if len(gpuWatts) > 0 {
+		power.GPUWatts = stats.Mean(gpuWatts)
+	}
+	power.Watts = power.CPUWatts + power.GPUWatts
+	if power.Watts <= 0 {
+		return nil
+	}
+	power.FramesPerJoule = avgFps / power.Watts
+	return power
+}
+
+// PowerByStage is the power of every stage that has readings with it
+func (t *TestResult) PowerByStage() map[int]*Power {
+	samples := t.SamplesByStage()
+	stages := make(map[int]*Power)
+	for stage, readings := range t.SensorsByStage() {
+		var fps []float64
+		for _, sample := range samples[stage] {
+			fps = append(fps, sample.AvgFps)
+		}
+		if power := MeanPower(readings, stats.Mean(fps)); power != nil {
+			stages[stage] = power
+		}
+	}
+	return stages
+}
+
+// ScorePerWatt is the total score over the mean power of the core tests, 0 unless every core
+// test of the run has its power
+func (d *Document) ScorePerWatt() float64 {
+	var watts []float64
+	for _, name := range CoreTests {
+		test, ok := d.Tests[name]
+		if !ok || test.Power == nil {
+			return 0
+		}
+		watts = append(watts, test.Power.Watts)
+	}
+	return d.TotalScore / stats.Mean(watts)
+}
@@ -0,0 +1,88 @@
+package rundoc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMeanPower(t *testing.T) {
+	cases := []struct {
+		name     string
+		readings []SensorSample
+		avgFps   float64
+		want     *Power
+	}{
+		{"no power readings", []SensorSample{{Time: 0, GPUTempC: 60}, {Time: 1, GPUTempC: 61}}, 100, nil},
+		{
+			name: "CPU package from the energy counter",
+			readings: []SensorSample{
+				{Time: 0, CPUEnergyJ: 1000},
+				{Time: 0.5}, // no counter in this reading
+				{Time: 2, CPUEnergyJ: 1060},
+			},
+			avgFps: 90,
+			want:   &Power{CPUWatts: 30, Watts: 30, FramesPerJoule: 3},
+		},
+		{
+			name:     "GPU from its own readings",
+			readings: []SensorSample{{Time: 0, GPUWatts: 100}, {Time: 0.5, GPUWatts: 140}},
+			avgFps:   240,
+			want:     &Power{GPUWatts: 120, Watts: 120, FramesPerJoule: 2},
+		},
+		{
+			name:     "both",
+			readings: []SensorSample{{Time: 0, CPUEnergyJ: 10, GPUWatts: 50}, {Time: 1, CPUEnergyJ: 40, GPUWatts: 70}},
+			avgFps:   180,
+			want:     &Power{CPUWatts: 30, GPUWatts: 60, Watts: 90, FramesPerJoule: 2},
+		},
+		{"a single counter reading has no power", []SensorSample{{Time: 1, CPUEnergyJ: 10}}, 60, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := MeanPower(c.readings, c.avgFps)
+			if c.want == nil || got == nil {
+				if c.want != got {
+					t.Fatalf("MeanPower = %+v, want %+v", got, c.want)
+				}
+				return
+			}
+			if *got != *c.want {
+				t.Errorf("MeanPower = %+v, want %+v", *got, *c.want)
+			}
+		})
+	}
+}
+
+func TestSetSensorsPower(t *testing.T) {
+	test := &TestResult{
+		Score:  50,
+		AvgFps: 100,
+		Samples: []Sample{
+			{Time: 0.5, Stage: 1, AvgFps: 100},
+			{Time: 1.0, Stage: 1, AvgFps: 100},
+			{Time: 1.5, Stage: 2, AvgFps: 100},
+		},
+	}
+	test.SetSensors([]SensorSample{
+		{Time: -0.2, CPUEnergyJ: 1}, // warm-up, left out
+		{Time: 0.2, CPUEnergyJ: 10},
+		{Time: 0.7, CPUEnergyJ: 20},
+		{Time: 1.2, CPUEnergyJ: 30},
+		{Time: 2.0, CPUEnergyJ: 99}, // after the last sample, left out
+	})
+
+	if len(test.Sensors) != 3 {
+		t.Fatalf("kept %d readings, want 3", len(test.Sensors))
+	}
+	for i, stage := range []int{1, 1, 2} {
+		if test.Sensors[i].Stage != stage {
+			t.Errorf("reading %d in stage %d, want %d", i, test.Sensors[i].Stage, stage)
+		}
+	}
+	if test.Power == nil {
+		t.Fatal("no power")
+	}
+	if math.Abs(test.Power.Watts-20) > 1e-9 || math.Abs(test.Power.ScorePerWatt-2.5) > 1e-9 {
+		t.Errorf("Power = %+v, want 20 W and 2.5 score per watt", *test.Power)
+	}
+}
@@ -0,0 +1,65 @@
+package rundoc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"os"
+)
+
+// Screenshots are scaled down to this width, so a run with all of them stays small
+const ScreenshotWidth = 480
+
+// ReadScreenshot loads the PNG screenshot of a test and returns it as a small JPEG data URI
+func ReadScreenshot(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	src, err := png.Decode(file)
+	if err != nil {
+		return "", err
+	}
+
+	var buffer bytes.Buffer
+	if err := jpeg.Encode(&buffer, downscale(src, ScreenshotWidth), &jpeg.Options{Quality: 80}); err != nil {
+		return "", err
+	}
+	return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(buffer.Bytes()), nil
+}
+
+// Box filter down to width, keeping the aspect ratio; smaller images are kept as they are
+func downscale(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+	if bounds.Dx() <= width {
+		return src
+	}
+	height := max(1, bounds.Dy()*width/bounds.Dx())
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+
+			var r, g, b, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, _ := src.At(sx, sy).RGBA()
+					r, g, b, n = r+cr, g+cg, b+cb, n+1
+				}
+			}
+			offset := dst.PixOffset(x, y)
+			dst.Pix[offset] = uint8(r / n >> 8)
+			dst.Pix[offset+1] = uint8(g / n >> 8)
+			dst.Pix[offset+2] = uint8(b / n >> 8)
+			dst.Pix[offset+3] = 255
+		}
+	}
+	return dst
+}
@@ -0,0 +1,49 @@
+package rundoc
+
+import "moddergltest/submit"
+
+// Result converts the document to the upload schema of the sinks
+func (d *Document) Result() submit.Result {
+	result := submit.Result{
+		RunID:          d.RunID,
+		GpuName:        d.System.GpuName,
+		VramSize:       d.System.VramSize,
+		DriverVersion:  d.System.DriverVersion,
+		WindowsVersion: d.System.WindowsVersion,
+		UsesWine:       d.System.UsesWine,
+		TotalScore:     d.TotalScore,
+		RamSize:        d.System.RamSize,
+		CpuName:        d.System.CpuName,
+		CreatedAt:      d.CreatedAt,
+		Noisy:          d.Noisy,
+		Noise:          d.Noise,
+	}
+
+	if test := d.Tests["butterfly"]; test != nil {
+		result.ButterflyScore = test.Score
+		result.ButterflyAvgFps = test.AvgFps
+		result.ButterflyMinFps = test.MinFps
+		result.ButterflyAvgFpsHistory, result.ButterflyMinFpsHistory = test.history()
+	}
+	if test := d.Tests["triangles"]; test != nil {
+		result.TrianglesScore = test.Score
+		result.TrianglesAvgFps = test.AvgFps
+		result.TrianglesMinFps = test.MinFps
+		result.TrianglesAvgFpsHistory, result.TrianglesMinFpsHistory = test.history()
+	}
+	if test := d.Tests["ocean"]; test != nil {
+		result.OceanScore = test.Score
+		result.OceanAvgFps = test.AvgFps
+		result.OceanMinFps = test.MinFps
+		result.OceanAvgFpsHistory, result.OceanMinFpsHistory = test.history()
+	}
+	return result
+}
+
+func (t *TestResult) history() (avgHistory, minHistory []submit.FpsEntry) {
+	for _, sample := range t.Samples {
+		avgHistory = append(avgHistory, submit.FpsEntry{Time: sample.Time, Fps: sample.AvgFps})
+		minHistory = append(minHistory, submit.FpsEntry{Time: sample.Time, Fps: sample.MinFps})
+	}
+	return avgHistory, minHistory
+}
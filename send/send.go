@@ -0,0 +1,99 @@
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+
+    "moddergltest/export"
+    "moddergltest/rundoc"
+    "moddergltest/submit"
+)
+
+// FPS structure
+type FpsEntry = submit.FpsEntry
+
+// Supabase structure
+type BenchmarkResult = submit.Result
+
+// Save results to local file, as the JSON that is uploaded
+func saveToLocalFile(doc *rundoc.Document) error {
+    exePath, err := os.Executable()
+    if err != nil {
+        return fmt.Errorf("Error determining path: %v", err)
+    }
+
+    outputPath := filepath.Join(filepath.Dir(exePath), "send.json")
+    file, err := os.Create(outputPath)
+    if err != nil {
+        return fmt.Errorf("Error creating file: %v", err)
+    }
+    if err := export.WriteJSON(file, doc); err != nil {
+        file.Close()
+        return fmt.Errorf("Error writing to file: %v", err)
+    }
+    return file.Close()
+}
+
+func main() {
+    targetName := flag.String("target", "", "comma-separated names of submission targets in "+submit.ConfigFileName)
+    flag.Parse()
+    args := flag.Args()
+
+    // The run document written by GLTest.exe, or "-" to read it from stdin
+    if len(args) != 1 {
+        exec.Command("msg", "*", "Error: usage: send.exe [--target name] <run.json | ->").Run()
+        os.Exit(2)
+    }
+
+    doc, err := rundoc.Read(args[0])
+    if err != nil {
+        exec.Command("msg", "*", fmt.Sprintf("Error reading run document: %v", err)).Run()
+        os.Exit(1)
+    }
+    if err := doc.Validate(); err != nil {
+        exec.Command("msg", "*", fmt.Sprintf("Error: invalid run document: %v", err)).Run()
+        os.Exit(1)
+    }
+    // Partial scores would skew the statistics
+    if err := doc.Submittable(); err != nil {
+        exec.Command("msg", "*", fmt.Sprintf("Error: %v", err)).Run()
+        os.Exit(1)
+    }
+    benchmarkData := doc.Result()
+
+    if err := saveToLocalFile(doc); err != nil {
+        exec.Command("msg", "*", fmt.Sprintf("Error saving locally: %v", err)).Run()
+    }
+
+    config, err := submit.LoadConfig()
+    if err != nil {
+        exec.Command("msg", "*", fmt.Sprintf("Error loading submission config: %v\nResults saved locally in send.json", err)).Run()
+        return
+    }
+    targets, err := config.Resolve(*targetName)
+    if err != nil {
+        exec.Command("msg", "*", fmt.Sprintf("Error: %v\nResults saved locally in send.json", err)).Run()
+        return
+    }
+    outbox, err := submit.DefaultOutbox()
+    if err != nil {
+        exec.Command("msg", "*", fmt.Sprintf("Error: %v\nResults saved locally in send.json", err)).Run()
+        return
+    }
+
+    // Failed targets are queued in the outbox and retried by GLTest.exe
+    if err := outbox.Submit(benchmarkData, targets); err != nil {
+        exec.Command("msg", "*", fmt.Sprintf("Error sending results: %v\nThe results are queued and will be sent later, or run GLTest.exe flush", err)).Run()
+        return
+    }
+
+    var names []string
+    for _, target := range targets {
+        names = append(names, target.Name)
+    }
+    exec.Command("msg", "*", fmt.Sprintf("Benchmark results successfully sent to %s", strings.Join(names, ", "))).Run()
+}
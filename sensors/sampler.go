@@ -0,0 +1,45 @@
+package sensors
+
+import "time"
+
+// Interval between readings, the cadence of the FPS samples of the tests
+const Interval = 500 * time.Millisecond
+
+// Sample is a reading with the moment it was taken
+type Sample struct {
+	At time.Time
+	Reading
+}
+
+// Sampler reads the sensors in the background until it is stopped
+type Sampler struct {
+	stop    chan struct{}
+	done    chan struct{}
+	samples []Sample // written by the sampling goroutine until done is closed
+}
+
+// Start reads the sensors right away and then every interval
+func (s *Sensors) Start(interval time.Duration) *Sampler {
+	sampler := &Sampler{stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(sampler.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			sampler.samples = append(sampler.samples, Sample{At: time.Now(), Reading: s.Read()})
+			select {
+			case <-ticker.C:
+			case <-sampler.stop:
+				return
+			}
+		}
+	}()
+	return sampler
+}
+
+// Stop ends the sampling and returns every reading taken
+func (s *Sampler) Stop() []Sample {
+	close(s.stop)
+	<-s.done
+	return s.samples
+}
@@ -0,0 +1,262 @@
+// Package sensors reads temperatures, clocks and power from Linux sysfs while a test runs, so
+// that thermal throttling shows up next to the FPS it costs and efficiency can be measured.
+// Under Wine sysfs is reached through the Z: drive; on Windows itself nothing is found and
+// nothing is sampled.
+package sensors
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"moddergltest/sysinfo"
+)
+
+// Environment variable with another root for /sys and /proc, e.g. a fake tree to try the
+// sampler on
+const RootEnv = "GLTEST_SYSFS_ROOT"
+
+// hwmon chips of GPUs and CPUs; other chips (NVMe, Wi-Fi, ACPI zones) are only kept by label
+var (
+	gpuChips = []string{"amdgpu", "radeon", "nouveau", "i915", "xe"}
+	cpuChips = []string{"coretemp", "k10temp", "zenpower", "cpu_thermal"}
+)
+
+// "card0", not a connector like "card0-DP-1"
+var cardPattern = regexp.MustCompile(`^card[0-9]+$`)
+
+// A top-level RAPL zone like "intel-rapl:0"; its subzones ("intel-rapl:0:0") are part of it
+var raplPattern = regexp.MustCompile(`^intel-rapl:[0-9]+$`)
+
+// Reading is what the sensors showed at one moment. Values not available are zero.
+type Reading struct {
+	GPUTempC float64            // hottest GPU sensor
+	CPUTempC float64            // hottest CPU sensor
+	GPUMHz   float64            // shader clock of the fastest GPU
+	CPUMHz   float64            // mean over the cores
+	Temps    map[string]float64 // every temperature in °C, by "chip:label"
+	// RAPL package energy counter in joules, only its differences mean anything
+	CPUEnergyJ float64
+	// Power draw the GPU reports itself
+	GPUWatts float64
+}
+
+// temperature is one temp*_input file of a hwmon chip
+type temperature struct {
+	key  string // "chip:label"
+	chip string
+	path string
+}
+
+// energyZone is a RAPL package counter, in microjoules, that wraps around at its range
+type energyZone struct {
+	path    string
+	rangeUJ float64
+	last    float64
+	wraps   float64
+}
+
+// Sensors are the files found under a sysfs root. Reading them keeps the state of the
+// energy counters, so one Sensors is read by one sampler at a time.
+type Sensors struct {
+	temps     []temperature
+	amdClocks []string // pp_dpm_sclk, the current level is marked with "*"
+	intClocks []string // gt_cur_freq_mhz
+	cpuFreqs  []string // scaling_cur_freq in kHz
+	energy    []*energyZone
+	gpuPower  []string // power1_average or power1_input in microwatts
+}
+
+// Wine maps the Linux root to the Z: drive, while / is the root of the current drive
+const wineRoot = `Z:\`
+
+// Root is the root in GLTEST_SYSFS_ROOT, Z: under Wine, / otherwise
+func Root() string {
+	if root := os.Getenv(RootEnv); root != "" {
+		return root
+	}
+	if runtime.GOOS == "windows" && sysinfo.IsWineUsed() {
+		return wineRoot
+	}
+	return "/"
+}
+
+// Discover looks for hwmon temperatures and GPU power, DRM clocks, CPU frequencies and RAPL
+// energy counters under root
+func Discover(root string) *Sensors {
+	s := &Sensors{}
+
+	chips, _ := filepath.Glob(filepath.Join(root, "sys/class/hwmon/hwmon*"))
+	for _, dir := range chips {
+		chip := readString(filepath.Join(dir, "name"))
+		if chip == "" {
+			chip = filepath.Base(dir)
+		}
+		inputs, _ := filepath.Glob(filepath.Join(dir, "temp*_input"))
+		for _, input := range inputs {
+			sensor := strings.TrimSuffix(filepath.Base(input), "_input")
+			label := readString(filepath.Join(dir, sensor+"_label"))
+			if label == "" {
+				label = sensor
+			}
+			s.temps = append(s.temps, temperature{key: chip + ":" + label, chip: chip, path: input})
+		}
+		if !slices.Contains(gpuChips, chip) {
+			continue
+		}
+		// Older amdgpu kernels average the power, newer ones report it as the input
+		for _, name := range []string{"power1_average", "power1_input"} {
+			if path := filepath.Join(dir, name); exists(path) {
+				s.gpuPower = append(s.gpuPower, path)
+				break
+			}
+		}
+	}
+	sort.Slice(s.temps, func(i, j int) bool { return s.temps[i].key < s.temps[j].key })
+
+	cards, _ := filepath.Glob(filepath.Join(root, "sys/class/drm/card*"))
+	for _, card := range cards {
+		if !cardPattern.MatchString(filepath.Base(card)) {
+			continue
+		}
+		if path := filepath.Join(card, "device/pp_dpm_sclk"); exists(path) {
+			s.amdClocks = append(s.amdClocks, path)
+		}
+		if path := filepath.Join(card, "gt_cur_freq_mhz"); exists(path) {
+			s.intClocks = append(s.intClocks, path)
+		}
+	}
+
+	s.cpuFreqs, _ = filepath.Glob(filepath.Join(root, "sys/devices/system/cpu/cpu[0-9]*/cpufreq/scaling_cur_freq"))
+
+	// Package zones only: psys covers the whole platform and dram is not the CPU. The
+	// counters are often readable by root only, then there is no CPU power.
+	zones, _ := filepath.Glob(filepath.Join(root, "sys/class/powercap/intel-rapl:*"))
+	for _, zone := range zones {
+		if !raplPattern.MatchString(filepath.Base(zone)) || !strings.HasPrefix(readString(filepath.Join(zone, "name")), "package") {
+			continue
+		}
+		path := filepath.Join(zone, "energy_uj")
+		last, ok := readNumber(path)
+		if !ok {
+			continue
+		}
+		rangeUJ, _ := readNumber(filepath.Join(zone, "max_energy_range_uj"))
+		s.energy = append(s.energy, &energyZone{path: path, rangeUJ: rangeUJ, last: last})
+	}
+	return s
+}
+
+// Empty is true when no sensor was found, e.g. on Windows
+func (s *Sensors) Empty() bool {
+	return len(s.temps) == 0 && len(s.amdClocks) == 0 && len(s.intClocks) == 0 && len(s.cpuFreqs) == 0 &&
+		len(s.energy) == 0 && len(s.gpuPower) == 0
+}
+
+// Read reads every sensor once. Files that cannot be read are left out.
+func (s *Sensors) Read() Reading {
+	var reading Reading
+	if len(s.temps) > 0 {
+		reading.Temps = make(map[string]float64, len(s.temps))
+	}
+	for _, temp := range s.temps {
+		milli, ok := readNumber(temp.path)
+		if !ok {
+			continue
+		}
+		celsius := milli / 1000
+		reading.Temps[temp.key] = celsius
+		switch {
+		case slices.Contains(gpuChips, temp.chip):
+			reading.GPUTempC = max(reading.GPUTempC, celsius)
+		case slices.Contains(cpuChips, temp.chip):
+			reading.CPUTempC = max(reading.CPUTempC, celsius)
+		}
+	}
+
+	for _, path := range s.amdClocks {
+		reading.GPUMHz = max(reading.GPUMHz, currentLevel(path))
+	}
+	for _, path := range s.intClocks {
+		if mhz, ok := readNumber(path); ok {
+			reading.GPUMHz = max(reading.GPUMHz, mhz)
+		}
+	}
+
+	var sum float64
+	var cores int
+	for _, path := range s.cpuFreqs {
+		if khz, ok := readNumber(path); ok {
+			sum += khz / 1000
+			cores++
+		}
+	}
+	if cores > 0 {
+		reading.CPUMHz = sum / float64(cores)
+	}
+
+	for _, zone := range s.energy {
+		reading.CPUEnergyJ += zone.read() / 1e6
+	}
+	for _, path := range s.gpuPower {
+		if microwatts, ok := readNumber(path); ok {
+			reading.GPUWatts += microwatts / 1e6
+		}
+	}
+	return reading
+}
+
+// The counter as if it never wrapped around, in microjoules
+func (z *energyZone) read() float64 {
+	if raw, ok := readNumber(z.path); ok {
+		if raw < z.last {
+			z.wraps++
+		}
+		z.last = raw
+	}
+	return z.last + z.wraps*z.rangeUJ
+}
+
+// The level marked current in a DPM table like "1: 1800Mhz *", in MHz
+func currentLevel(path string) float64 {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasSuffix(line, "*") {
+			continue
+		}
+		_, level, _ := strings.Cut(strings.TrimSuffix(line, "*"), ":")
+		level = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(level)), "mhz")
+		mhz, err := strconv.ParseFloat(level, 64)
+		if err == nil {
+			return mhz
+		}
+	}
+	return 0
+}
+
+func readString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func readNumber(path string) (float64, bool) {
+	value, err := strconv.ParseFloat(readString(path), 64)
+	return value, err == nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,164 @@
+package sensors
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeRoot lays out files under a temporary root, paths relative to it
+func fakeRoot(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for path, content := range files {
+		writeFile(t, filepath.Join(root, path), content)
+	}
+	return root
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRootFromEnvironment(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(RootEnv, root)
+	if got := Root(); got != root {
+		t.Errorf("Root() = %q, want %q", got, root)
+	}
+}
+
+func TestReadTemperaturesAndClocks(t *testing.T) {
+	t.Setenv(RootEnv, fakeRoot(t, map[string]string{
+		"sys/class/hwmon/hwmon0/name":        "amdgpu\n",
+		"sys/class/hwmon/hwmon0/temp1_input": "65000\n",
+		"sys/class/hwmon/hwmon0/temp1_label": "edge\n",
+		"sys/class/hwmon/hwmon0/temp2_input": "71000\n",
+		"sys/class/hwmon/hwmon0/temp2_label": "junction\n",
+		"sys/class/hwmon/hwmon1/name":        "k10temp\n",
+		"sys/class/hwmon/hwmon1/temp1_input": "55500\n",
+		"sys/class/hwmon/hwmon1/temp1_label": "Tctl\n",
+		// Neither GPU nor CPU, kept by its sensor name only
+		"sys/class/hwmon/hwmon2/name":        "nvme\n",
+		"sys/class/hwmon/hwmon2/temp1_input": "40000\n",
+
+		"sys/class/drm/card0/device/pp_dpm_sclk": "0: 500Mhz\n1: 1800Mhz *\n2: 2400Mhz\n",
+		"sys/class/drm/card1/gt_cur_freq_mhz":    "1300\n",
+		// A connector, not a card
+		"sys/class/drm/card0-DP-1/device/pp_dpm_sclk": "0: 9999Mhz *\n",
+
+		"sys/devices/system/cpu/cpu0/cpufreq/scaling_cur_freq": "3000000\n",
+		"sys/devices/system/cpu/cpu1/cpufreq/scaling_cur_freq": "4000000\n",
+	}))
+
+	s := Discover(Root())
+	if s.Empty() {
+		t.Fatal("no sensors found in the fake tree")
+	}
+	reading := s.Read()
+	checks := []struct {
+		name      string
+		got, want float64
+	}{
+		{"GPUTempC", reading.GPUTempC, 71},
+		{"CPUTempC", reading.CPUTempC, 55.5},
+		{"GPUMHz", reading.GPUMHz, 1800},
+		{"CPUMHz", reading.CPUMHz, 3500},
+		{"CPUEnergyJ", reading.CPUEnergyJ, 0},
+		{"GPUWatts", reading.GPUWatts, 0},
+	}
+	for _, check := range checks {
+		if check.got != check.want {
+			t.Errorf("%s = %g, want %g", check.name, check.got, check.want)
+		}
+	}
+	for key, want := range map[string]float64{"amdgpu:edge": 65, "amdgpu:junction": 71, "k10temp:Tctl": 55.5, "nvme:temp1": 40} {
+		if got, ok := reading.Temps[key]; !ok || got != want {
+			t.Errorf("Temps[%q] = %g, %v, want %g", key, got, ok, want)
+		}
+	}
+	if len(reading.Temps) != 4 {
+		t.Errorf("Temps has %d sensors, want 4: %v", len(reading.Temps), reading.Temps)
+	}
+}
+
+func TestEmptyRoot(t *testing.T) {
+	s := Discover(t.TempDir())
+	if !s.Empty() {
+		t.Error("sensors found in an empty tree")
+	}
+	if reading := s.Read(); reading.Temps != nil || reading.GPUMHz != 0 || reading.CPUMHz != 0 {
+		t.Errorf("reading of an empty tree = %+v", reading)
+	}
+}
+
+func TestSampler(t *testing.T) {
+	s := Discover(fakeRoot(t, map[string]string{
+		"sys/class/hwmon/hwmon0/name":        "coretemp\n",
+		"sys/class/hwmon/hwmon0/temp1_input": "60000\n",
+	}))
+	samples := s.Start(time.Millisecond).Stop()
+	if len(samples) == 0 {
+		t.Fatal("no samples, want at least the one taken right away")
+	}
+	if samples[0].CPUTempC != 60 || samples[0].At.IsZero() {
+		t.Errorf("first sample = %+v", samples[0])
+	}
+}
+
+func TestEnergyCounterWrapsAround(t *testing.T) {
+	root := fakeRoot(t, map[string]string{
+		"sys/class/powercap/intel-rapl:0/name":                "package-0\n",
+		"sys/class/powercap/intel-rapl:0/energy_uj":           "999000000\n",
+		"sys/class/powercap/intel-rapl:0/max_energy_range_uj": "1000000000\n",
+		// A subzone is part of its package, psys covers more than the CPU
+		"sys/class/powercap/intel-rapl:0:0/name":      "core\n",
+		"sys/class/powercap/intel-rapl:0:0/energy_uj": "5000000\n",
+		"sys/class/powercap/intel-rapl:1/name":        "psys\n",
+		"sys/class/powercap/intel-rapl:1/energy_uj":   "7000000\n",
+	})
+	s := Discover(root)
+	counter := filepath.Join(root, "sys/class/powercap/intel-rapl:0/energy_uj")
+
+	steps := []struct {
+		energyUJ string
+		want     float64 // joules
+	}{
+		{"999000000", 999},
+		{"999500000", 999.5},
+		{"1000000", 1001},  // wrapped at 1000 J
+		{"2000000", 1002},  // still counted from the wrap
+		{"", 1002},         // unreadable, the last value stays
+		{"500000", 2000.5}, // wrapped again
+	}
+	for i, step := range steps {
+		writeFile(t, counter, step.energyUJ)
+		if got := s.Read().CPUEnergyJ; got != step.want {
+			t.Errorf("reading %d with energy_uj %q: CPUEnergyJ = %g, want %g", i, step.energyUJ, got, step.want)
+		}
+	}
+}
+
+func TestGPUPower(t *testing.T) {
+	s := Discover(fakeRoot(t, map[string]string{
+		// Older kernels average, the average wins when both are there
+		"sys/class/hwmon/hwmon0/name":           "amdgpu\n",
+		"sys/class/hwmon/hwmon0/power1_average": "45000000\n",
+		"sys/class/hwmon/hwmon0/power1_input":   "99000000\n",
+		"sys/class/hwmon/hwmon1/name":           "amdgpu\n",
+		"sys/class/hwmon/hwmon1/power1_input":   "12500000\n",
+		// Not a GPU
+		"sys/class/hwmon/hwmon2/name":         "acpi_fan\n",
+		"sys/class/hwmon/hwmon2/power1_input": "3000000\n",
+	}))
+	if got := s.Read().GPUWatts; got != 57.5 {
+		t.Errorf("GPUWatts = %g, want 57.5", got)
+	}
+}
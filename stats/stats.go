@@ -0,0 +1,195 @@
+// Package stats has the descriptive statistics and significance tests used to judge runs.
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// Mean is the arithmetic mean, 0 for no values
+func Mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// Variance is the sample variance (n-1 denominator)
+func Variance(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	mean := Mean(values)
+	var sum float64
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return sum / float64(len(values)-1)
+}
+
+// StdDev is the sample standard deviation
+func StdDev(values []float64) float64 {
+	return math.Sqrt(Variance(values))
+}
+
+// Median is the 50th percentile
+func Median(values []float64) float64 {
+	return Percentile(values, 50)
+}
+
+// Percentile interpolates linearly between the closest ranks, p in 0..100
+func Percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower < 0 {
+		return sorted[0]
+	}
+	if upper >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// CoefficientOfVariation is the standard deviation relative to the mean
+func CoefficientOfVariation(values []float64) float64 {
+	mean := Mean(values)
+	if mean == 0 {
+		return 0
+	}
+	return StdDev(values) / math.Abs(mean)
+}
+
+// ConfidenceInterval returns the half-width of the confidence interval of the mean,
+// e.g. level 0.95, from the Student t distribution
+func ConfidenceInterval(values []float64, level float64) float64 {
+	n := len(values)
+	if n < 2 {
+		return 0
+	}
+	t := StudentTQuantile(1-(1-level)/2, float64(n-1))
+	return t * StdDev(values) / math.Sqrt(float64(n))
+}
+
+// WelchTTest compares the means of two samples without assuming equal variances and returns
+// the two-sided p-value. Samples with fewer than two values, or no variance at all, give 1.
+func WelchTTest(a, b []float64) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return 1
+	}
+	va := Variance(a) / float64(len(a))
+	vb := Variance(b) / float64(len(b))
+	if va+vb == 0 {
+		if Mean(a) == Mean(b) {
+			return 1
+		}
+		return 0
+	}
+
+	t := (Mean(a) - Mean(b)) / math.Sqrt(va+vb)
+	df := (va + vb) * (va + vb) / (va*va/float64(len(a)-1) + vb*vb/float64(len(b)-1))
+	return 2 * (1 - StudentTCDF(math.Abs(t), df))
+}
+
+// StudentTCDF is the cumulative distribution function of the t distribution
+func StudentTCDF(t, df float64) float64 {
+	x := df / (df + t*t)
+	tail := 0.5 * regularizedBeta(x, df/2, 0.5)
+	if t > 0 {
+		return 1 - tail
+	}
+	return tail
+}
+
+// StudentTQuantile inverts StudentTCDF by bisection, p in (0, 1)
+func StudentTQuantile(p, df float64) float64 {
+	low, high := -1000.0, 1000.0
+	for i := 0; i < 200; i++ {
+		mid := (low + high) / 2
+		if StudentTCDF(mid, df) < p {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2
+}
+
+// Regularized incomplete beta function I_x(a, b), continued fraction from Numerical Recipes
+func regularizedBeta(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lbeta, _ := math.Lgamma(a + b)
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	front := math.Exp(lbeta - la - lb + a*math.Log(x) + b*math.Log(1-x))
+
+	// The continued fraction converges quickly only below this point, use the symmetry otherwise
+	if x > (a+1)/(a+b+2) {
+		return 1 - front*betaContinuedFraction(1-x, b, a)/b
+	}
+	return front * betaContinuedFraction(x, a, b) / a
+}
+
+func betaContinuedFraction(x, a, b float64) float64 {
+	const (
+		maxIterations = 300
+		epsilon       = 1e-14
+		tiny          = 1e-300
+	)
+	c := 1.0
+	d := 1 - (a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+
+	for m := 1; m <= maxIterations; m++ {
+		fm := float64(m)
+		// Even step
+		num := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+
+		// Odd step
+		num = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,91 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDescriptive(t *testing.T) {
+	values := []float64{2, 4, 4, 4, 5, 5, 7, 9}
+	cases := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"Mean", Mean(values), 5},
+		{"Mean of nothing", Mean(nil), 0},
+		{"Variance", Variance(values), 32.0 / 7},
+		{"Variance of one value", Variance([]float64{3}), 0},
+		{"StdDev", StdDev(values), math.Sqrt(32.0 / 7)},
+		{"Median of an even count", Median(values), 4.5},
+		{"Median of an odd count", Median([]float64{3, 1, 4, 1, 5}), 3},
+		{"Percentile between ranks", Percentile([]float64{3, 1, 4, 1, 5}, 5), 1},
+		{"Percentile 90", Percentile([]float64{3, 1, 4, 1, 5}, 90), 4.6},
+		{"Percentile 0", Percentile(values, 0), 2},
+		{"Percentile 100", Percentile(values, 100), 9},
+		{"Percentile of nothing", Percentile(nil, 50), 0},
+		{"CoefficientOfVariation", CoefficientOfVariation(values), math.Sqrt(32.0/7) / 5},
+		{"CoefficientOfVariation of a zero mean", CoefficientOfVariation([]float64{-1, 1}), 0},
+	}
+	for _, c := range cases {
+		if math.Abs(c.got-c.want) > 1e-12 {
+			t.Errorf("%s = %g, want %g", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestStudentT(t *testing.T) {
+	cases := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"CDF at 0", StudentTCDF(0, 5), 0.5},
+		{"CDF with 1 degree of freedom is the Cauchy distribution", StudentTCDF(1, 1), 0.75},
+		{"CDF t=2, df=10", StudentTCDF(2, 10), 0.963306},
+		{"CDF t=-1.5, df=3", StudentTCDF(-1.5, 3), 1 - 0.884708},
+		{"quantile 0.975, df=10", StudentTQuantile(0.975, 10), 2.228139},
+		{"quantile 0.975, df=1", StudentTQuantile(0.975, 1), 12.706205},
+		{"quantile 0.975, df=4", StudentTQuantile(0.975, 4), 2.776445},
+		{"quantile 0.5", StudentTQuantile(0.5, 7), 0},
+	}
+	for _, c := range cases {
+		if math.Abs(c.got-c.want) > 1e-5 {
+			t.Errorf("%s = %.6f, want %.6f", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestConfidenceInterval(t *testing.T) {
+	values := []float64{10, 12, 11, 13, 14}
+	want := 2.776445 * StdDev(values) / math.Sqrt(5)
+	if got := ConfidenceInterval(values, 0.95); math.Abs(got-want) > 1e-5 {
+		t.Errorf("ConfidenceInterval = %g, want %g", got, want)
+	}
+	if got := ConfidenceInterval([]float64{10}, 0.95); got != 0 {
+		t.Errorf("ConfidenceInterval of one value = %g, want 0", got)
+	}
+}
+
+func TestWelchTTest(t *testing.T) {
+	cases := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		// t = -3.674 with 4 degrees of freedom
+		{"known value", []float64{1, 2, 3}, []float64{4, 5, 6}, 0.021312},
+		{"the same samples", []float64{1, 2, 3}, []float64{1, 2, 3}, 1},
+		{"too few values", []float64{1}, []float64{4, 5, 6}, 1},
+		{"no variance, equal means", []float64{60, 60}, []float64{60, 60, 60}, 1},
+		{"no variance, different means", []float64{60, 60}, []float64{59, 59}, 0},
+	}
+	for _, c := range cases {
+		if got := WelchTTest(c.a, c.b); math.Abs(got-c.want) > 1e-5 {
+			t.Errorf("%s: WelchTTest = %.6f, want %.6f", c.name, got, c.want)
+		}
+	}
+	if p := WelchTTest([]float64{60.1, 59.8, 60.4, 60.0, 59.9}, []float64{57.2, 57.9, 56.8, 57.5, 57.0}); p > 0.001 {
+		t.Errorf("a 5%% drop over steady samples has p = %g, want it significant", p)
+	}
+}
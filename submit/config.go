@@ -0,0 +1,204 @@
+// Package submit describes where benchmark results are sent.
+package submit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Environment variables read on top of the config file
+const (
+	EnvConfig = "GLTEST_CONFIG" // path of the config file
+	EnvTarget = "GLTEST_TARGET" // name of the target to use
+	EnvURL    = "GLTEST_URL"    // overrides the URL of the selected target
+	EnvKey    = "GLTEST_KEY"    // overrides the key of the selected target
+	EnvTable  = "GLTEST_TABLE"  // overrides the table of the selected target
+)
+
+// Name of the config file, looked up next to the executable and in the user config directory
+const ConfigFileName = "submit.json"
+
+// Secret is an API key. It prints as [redacted] so it never ends up in logs or messages.
+type Secret string
+
+func (Secret) String() string   { return "[redacted]" }
+func (Secret) GoString() string { return `"[redacted]"` }
+
+// Target is a named submission endpoint. Which fields are needed depends on Type,
+// see NewSink.
+type Target struct {
+	Name    string            `json:"-"`
+	Type    string            `json:"type"` // supabase (default), webhook, influxdb, pushgateway or sqlite
+	URL     string            `json:"url"`
+	Key     Secret            `json:"key"`
+	KeyEnv  string            `json:"key_env"` // read the key from this variable instead of the file
+	Table   string            `json:"table"`   // table, measurement or job name
+	Org     string            `json:"org"`     // InfluxDB organization
+	Bucket  string            `json:"bucket"`  // InfluxDB bucket
+	Path    string            `json:"path"`    // SQLite database file
+	Headers map[string]string `json:"headers"` // extra webhook headers
+}
+
+// Config is the content of submit.json
+type Config struct {
+	Default string             `json:"default"`
+	Targets map[string]*Target `json:"targets"`
+	Path    string             `json:"-"`
+}
+
+// Config file candidates in lookup order
+func ConfigPaths() []string {
+	if path := os.Getenv(EnvConfig); path != "" {
+		return []string{path}
+	}
+
+	var paths []string
+	if exePath, err := os.Executable(); err == nil {
+		paths = append(paths, filepath.Join(filepath.Dir(exePath), ConfigFileName))
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, "GLTest", ConfigFileName))
+	}
+	return paths
+}
+
+// LoadConfig reads the first config file found. No file at all is not an error,
+// targets can come from environment variables alone.
+func LoadConfig() (*Config, error) {
+	config := &Config{Targets: make(map[string]*Target)}
+
+	for _, path := range ConfigPaths() {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) && os.Getenv(EnvConfig) == "" {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error reading config %s: %v", path, err)
+		}
+		if err := json.Unmarshal(data, config); err != nil {
+			return nil, fmt.Errorf("Error parsing config %s: %v", path, err)
+		}
+		config.Path = path
+		break
+	}
+
+	if config.Targets == nil {
+		config.Targets = make(map[string]*Target)
+	}
+	for name, target := range config.Targets {
+		if target == nil {
+			return nil, fmt.Errorf("Target %q in %s is empty", name, config.Path)
+		}
+		target.Name = name
+	}
+	return config, nil
+}
+
+// Names of the configured targets, sorted
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Targets))
+	for name := range c.Targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve resolves a comma-separated list of target names, results are fanned out to all of
+// them. An empty list falls back to GLTEST_TARGET, then to the default of the config file.
+// Environment overrides only apply when a single target is selected.
+func (c *Config) Resolve(names string) ([]*Target, error) {
+	if names == "" {
+		names = os.Getenv(EnvTarget)
+	}
+	if names == "" {
+		names = c.Default
+	}
+	if !strings.Contains(names, ",") {
+		target, err := c.Target(names)
+		if err != nil {
+			return nil, err
+		}
+		return []*Target{target}, nil
+	}
+
+	var targets []*Target
+	for _, name := range strings.Split(names, ",") {
+		target, err := c.Named(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// Named resolves a configured target by its name alone, without the environment overrides of
+// a selected target; only its key_env is read. An unnamed "env" target exists only in the
+// environment, so it is defined by the overrides.
+func (c *Config) Named(name string) (*Target, error) {
+	configured, ok := c.Targets[name]
+	if !ok {
+		if name == "env" && os.Getenv(EnvURL) != "" {
+			return c.Target(name)
+		}
+		return nil, fmt.Errorf("Unknown target %q, configured: %v", name, c.Names())
+	}
+	target := *configured
+	if target.KeyEnv != "" {
+		target.Key = Secret(os.Getenv(target.KeyEnv))
+	}
+	return &target, nil
+}
+
+// Target resolves a target by name. An empty name falls back to GLTEST_TARGET, then to the
+// default of the config file, then to the only configured target. With no targets configured,
+// GLTEST_URL defines an unnamed "env" target.
+func (c *Config) Target(name string) (*Target, error) {
+	if name == "" {
+		name = os.Getenv(EnvTarget)
+	}
+	if name == "" {
+		name = c.Default
+	}
+	if name == "" && len(c.Targets) == 1 {
+		name = c.Names()[0]
+	}
+
+	var target Target
+	switch {
+	case name == "env" && c.Targets[name] == nil && os.Getenv(EnvURL) != "":
+		target = Target{Name: "env"}
+	case name != "":
+		configured, ok := c.Targets[name]
+		if !ok {
+			return nil, fmt.Errorf("Unknown target %q, configured: %v", name, c.Names())
+		}
+		target = *configured
+	case os.Getenv(EnvURL) != "":
+		target = Target{Name: "env"}
+	case len(c.Targets) > 1:
+		return nil, fmt.Errorf("Several targets configured %v, choose one with --target or %s", c.Names(), EnvTarget)
+	default:
+		return nil, fmt.Errorf("No submission target configured, create %s or set %s", ConfigFileName, EnvURL)
+	}
+
+	if target.KeyEnv != "" {
+		target.Key = Secret(os.Getenv(target.KeyEnv))
+	}
+	if url := os.Getenv(EnvURL); url != "" {
+		target.URL = url
+	}
+	if key := os.Getenv(EnvKey); key != "" {
+		target.Key = Secret(key)
+	}
+	if table := os.Getenv(EnvTable); table != "" {
+		target.Table = table
+	}
+
+	return &target, nil
+}
@@ -0,0 +1,62 @@
+package submit
+
+import "testing"
+
+func TestResolveOverrides(t *testing.T) {
+	t.Setenv(EnvTarget, "")
+	t.Setenv(EnvURL, "https://override.example")
+	t.Setenv(EnvKey, "override-key")
+	t.Setenv(EnvTable, "override_table")
+	config := &Config{Targets: map[string]*Target{
+		"private": {Name: "private", URL: "https://private.example", Key: "private-key", Table: "results"},
+		"staging": {Name: "staging", URL: "https://staging.example", Key: "staging-key", Table: "results_staging"},
+	}}
+
+	cases := []struct {
+		names string
+		want  []Target
+	}{
+		// A single target is the one the environment overrides
+		{"private", []Target{{URL: "https://override.example", Key: "override-key", Table: "override_table"}}},
+		{"private,staging", []Target{
+			{URL: "https://private.example", Key: "private-key", Table: "results"},
+			{URL: "https://staging.example", Key: "staging-key", Table: "results_staging"},
+		}},
+	}
+	for _, c := range cases {
+		targets, err := config.Resolve(c.names)
+		if err != nil {
+			t.Fatalf("Resolve(%q): %v", c.names, err)
+		}
+		if len(targets) != len(c.want) {
+			t.Fatalf("Resolve(%q) gave %d targets, want %d", c.names, len(targets), len(c.want))
+		}
+		for i, target := range targets {
+			want := c.want[i]
+			if target.URL != want.URL || target.Key != want.Key || target.Table != want.Table {
+				t.Errorf("Resolve(%q)[%d] = %s %s %s, want %s %s %s", c.names, i,
+					target.URL, string(target.Key), target.Table, want.URL, string(want.Key), want.Table)
+			}
+		}
+	}
+	// The configured targets are copies, overrides never change the config
+	if config.Targets["private"].URL != "https://private.example" {
+		t.Error("the config was changed by an override")
+	}
+}
+
+func TestNamed(t *testing.T) {
+	t.Setenv(EnvURL, "")
+	config := &Config{Targets: map[string]*Target{"private": {Name: "private", URL: "https://private.example"}}}
+	if _, err := config.Named("missing"); err == nil {
+		t.Error("Named found a target that is not configured")
+	}
+	if _, err := config.Named("env"); err == nil {
+		t.Errorf("Named found an env target without %s", EnvURL)
+	}
+	t.Setenv(EnvURL, "https://env.example")
+	target, err := config.Named("env")
+	if err != nil || target.URL != "https://env.example" {
+		t.Errorf("Named(env) = %+v, %v, want the target of %s", target, err, EnvURL)
+	}
+}
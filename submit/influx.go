@@ -0,0 +1,85 @@
+package submit
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// InfluxSink writes the result as one point in InfluxDB line protocol through the v2 write API.
+// The measurement is the target table, "gltest" by default.
+type InfluxSink struct {
+	Target *Target
+	Client *http.Client
+}
+
+func (s *InfluxSink) Name() string { return s.Target.Name }
+
+func (s *InfluxSink) Send(result Result) error {
+	query := url.Values{}
+	query.Set("org", s.Target.Org)
+	query.Set("bucket", s.Target.Bucket)
+	query.Set("precision", "s")
+	writeURL := strings.TrimRight(s.Target.URL, "/") + "/api/v2/write?" + query.Encode()
+
+	headers := map[string]string{"Content-Type": "text/plain; charset=utf-8"}
+	if s.Target.Key != "" {
+		headers["Authorization"] = "Token " + string(s.Target.Key)
+	}
+	return doRequest(s.Client, "POST", writeURL, []byte(s.Line(result)), headers)
+}
+
+// Line formats the result as a line protocol point, tags and fields sorted by key
+func (s *InfluxSink) Line(result Result) string {
+	measurement := s.Target.Table
+	if measurement == "" {
+		measurement = "gltest"
+	}
+
+	var line strings.Builder
+	line.WriteString(influxEscape(measurement, ", "))
+
+	tags := result.Tags()
+	for _, key := range sortedKeys(tags) {
+		// Empty tag values are not allowed
+		if tags[key] == "" {
+			continue
+		}
+		fmt.Fprintf(&line, ",%s=%s", influxEscape(key, ",= "), influxEscape(tags[key], ",= "))
+	}
+
+	metrics := result.Metrics()
+	for i, key := range sortedKeys(metrics) {
+		separator := ","
+		if i == 0 {
+			separator = " "
+		}
+		fmt.Fprintf(&line, "%s%s=%s", separator, influxEscape(key, ",= "), strconv.FormatFloat(metrics[key], 'f', -1, 64))
+	}
+
+	fmt.Fprintf(&line, " %d\n", result.CreatedAt.Unix())
+	return line.String()
+}
+
+func influxEscape(value, special string) string {
+	var escaped strings.Builder
+	for _, r := range value {
+		if strings.ContainsRune(special, r) || r == '\\' {
+			escaped.WriteByte('\\')
+		}
+		escaped.WriteRune(r)
+	}
+	return escaped.String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,247 @@
+package submit
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Retry delays double from outboxBaseDelay up to outboxMaxDelay
+const (
+	outboxBaseDelay = time.Minute
+	outboxMaxDelay  = 24 * time.Hour
+)
+
+// OutboxEntry is a result that could not be delivered to some of its targets.
+// Only target names are stored, keys stay in the config.
+type OutboxEntry struct {
+	RunID       string    `json:"run_id"`
+	Targets     []string  `json:"targets"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"`
+	LastError   string    `json:"last_error"`
+	Result      Result    `json:"result"`
+}
+
+// Outbox is a directory of undelivered results, one <run id>.json file per run
+type Outbox struct {
+	Dir string
+}
+
+// OutboxStatus summarizes the outbox for the GUI
+type OutboxStatus struct {
+	Pending     int
+	NextAttempt time.Time
+	LastError   string
+}
+
+// NewRunID returns a random UUID (version 4)
+func NewRunID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// DefaultOutbox is kept in the user config directory, so it survives reinstalling GLTest
+func DefaultOutbox() (*Outbox, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Outbox{Dir: filepath.Join(dir, "GLTest", "outbox")}, nil
+}
+
+// Submit sends the result to every target and queues it for the targets that failed
+func (o *Outbox) Submit(result Result, targets []*Target) error {
+	var failed []string
+	var errs []error
+	for _, target := range targets {
+		sink, err := NewSink(target)
+		if err == nil {
+			err = sink.Send(result)
+		}
+		if err != nil {
+			failed = append(failed, target.Name)
+			errs = append(errs, fmt.Errorf("%s: %v", target.Name, err))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+
+	sendErr := errors.Join(errs...)
+	if err := o.Enqueue(result, failed, sendErr); err != nil {
+		return fmt.Errorf("%v\nFailed to queue result: %v", sendErr, err)
+	}
+	return sendErr
+}
+
+// Enqueue stores a result for later delivery. Queuing the same run again only adds targets.
+func (o *Outbox) Enqueue(result Result, targets []string, sendErr error) error {
+	if result.RunID == "" {
+		return fmt.Errorf("result has no run ID")
+	}
+	if err := os.MkdirAll(o.Dir, 0755); err != nil {
+		return err
+	}
+
+	entry, err := o.read(o.path(result.RunID))
+	if os.IsNotExist(err) {
+		entry = &OutboxEntry{RunID: result.RunID, Result: result}
+	} else if err != nil {
+		return err
+	}
+	for _, name := range targets {
+		if !slices.Contains(entry.Targets, name) {
+			entry.Targets = append(entry.Targets, name)
+		}
+	}
+	entry.Attempts++
+	entry.NextAttempt = time.Now().Add(backoff(entry.Attempts))
+	if sendErr != nil {
+		entry.LastError = sendErr.Error()
+	}
+	return o.write(entry)
+}
+
+// Flush retries every entry whose backoff has expired, or all of them with force.
+// Delivered targets are removed from their entry and empty entries are deleted. Targets are
+// looked up by name as configured now: the environment overrides of a selected target would
+// send every queued target to the same endpoint.
+func (o *Outbox) Flush(config *Config, force bool) (int, error) {
+	entries, err := o.Entries()
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	var errs []error
+	for _, entry := range entries {
+		if !force && time.Now().Before(entry.NextAttempt) {
+			continue
+		}
+
+		var remaining []string
+		var entryErrs []error
+		for _, name := range entry.Targets {
+			target, err := config.Named(name)
+			var sink ResultSink
+			if err == nil {
+				sink, err = NewSink(target)
+			}
+			if err == nil {
+				err = sink.Send(entry.Result)
+			}
+			if err != nil {
+				remaining = append(remaining, name)
+				entryErrs = append(entryErrs, fmt.Errorf("%s: %v", name, err))
+			}
+		}
+
+		if len(remaining) == 0 {
+			delivered++
+			if err := os.Remove(o.path(entry.RunID)); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+
+		entryErr := errors.Join(entryErrs...)
+		errs = append(errs, fmt.Errorf("run %s: %v", entry.RunID, entryErr))
+		entry.Targets = remaining
+		entry.Attempts++
+		entry.NextAttempt = time.Now().Add(backoff(entry.Attempts))
+		entry.LastError = entryErr.Error()
+		if err := o.write(entry); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return delivered, errors.Join(errs...)
+}
+
+// Entries lists the queued results, oldest first
+func (o *Outbox) Entries() ([]*OutboxEntry, error) {
+	paths, err := filepath.Glob(filepath.Join(o.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*OutboxEntry
+	for _, path := range paths {
+		entry, err := o.read(path)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading %s: %v", path, err)
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Result.CreatedAt.Before(entries[j].Result.CreatedAt)
+	})
+	return entries, nil
+}
+
+// Status counts the pending results and finds the next retry
+func (o *Outbox) Status() (OutboxStatus, error) {
+	status := OutboxStatus{}
+	entries, err := o.Entries()
+	if err != nil {
+		return status, err
+	}
+
+	status.Pending = len(entries)
+	for _, entry := range entries {
+		if status.NextAttempt.IsZero() || entry.NextAttempt.Before(status.NextAttempt) {
+			status.NextAttempt = entry.NextAttempt
+			status.LastError = entry.LastError
+		}
+	}
+	return status, nil
+}
+
+func (o *Outbox) path(runID string) string {
+	return filepath.Join(o.Dir, runID+".json")
+}
+
+func (o *Outbox) read(path string) (*OutboxEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entry := &OutboxEntry{}
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Write through a temporary file, so a crash never leaves half an entry behind
+func (o *Outbox) write(entry *OutboxEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := o.path(entry.RunID) + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, o.path(entry.RunID))
+}
+
+func backoff(attempts int) time.Duration {
+	delay := outboxBaseDelay
+	for i := 1; i < attempts && delay < outboxMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, outboxMaxDelay)
+}
@@ -0,0 +1,158 @@
+package submit
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	cases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Minute},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{11, 1024 * time.Minute},
+		{12, 24 * time.Hour}, // 2048 minutes is more than a day
+		{1000, 24 * time.Hour},
+	}
+	for _, c := range cases {
+		if got := backoff(c.attempts); got != c.want {
+			t.Errorf("backoff(%d) = %v, want %v", c.attempts, got, c.want)
+		}
+	}
+}
+
+func TestEnqueueMergesTargets(t *testing.T) {
+	outbox := &Outbox{Dir: filepath.Join(t.TempDir(), "outbox")}
+	result := sampleResult()
+	if err := outbox.Enqueue(result, []string{"a"}, errors.New("down")); err != nil {
+		t.Fatal(err)
+	}
+	if err := outbox.Enqueue(result, []string{"a", "b"}, errors.New("still down")); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := outbox.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("%d entries, want 1", len(entries))
+	}
+	entry := entries[0]
+	if strings.Join(entry.Targets, ",") != "a,b" || entry.Attempts != 2 || entry.LastError != "still down" {
+		t.Errorf("entry = %+v", entry)
+	}
+	if wait := time.Until(entry.NextAttempt); wait < time.Minute || wait > 2*time.Minute {
+		t.Errorf("next attempt in %v, want 2 minutes", wait)
+	}
+	// Entries are written through a temporary file that is renamed in place
+	files, _ := filepath.Glob(filepath.Join(outbox.Dir, "*"))
+	if len(files) != 1 || filepath.Base(files[0]) != result.RunID+".json" {
+		t.Errorf("outbox holds %v, want only %s.json", files, result.RunID)
+	}
+
+	result.RunID = ""
+	if err := outbox.Enqueue(result, []string{"a"}, nil); err == nil {
+		t.Error("queued a result without a run ID")
+	}
+}
+
+func TestFlush(t *testing.T) {
+	up, upRequests := standIn(t, http.StatusNoContent)
+	down, _ := standIn(t, http.StatusServiceUnavailable)
+	config := &Config{Targets: map[string]*Target{
+		"up":   {Name: "up", Type: "webhook", URL: up.URL},
+		"down": {Name: "down", Type: "webhook", URL: down.URL},
+	}}
+	outbox := &Outbox{Dir: t.TempDir()}
+
+	due := sampleResult()
+	if err := outbox.Enqueue(due, []string{"up", "down"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	later := sampleResult()
+	later.RunID = "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+	if err := outbox.Enqueue(later, []string{"up"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	// Only the first entry is due
+	entry, err := outbox.read(outbox.path(due.RunID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.NextAttempt = time.Now().Add(-time.Second)
+	if err := outbox.write(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	delivered, err := outbox.Flush(config, false)
+	if delivered != 0 || err == nil || !strings.Contains(err.Error(), "down") {
+		t.Errorf("Flush = %d, %v, want 0 and the error of down", delivered, err)
+	}
+	if len(*upRequests) != 1 {
+		t.Errorf("up got %d requests, want 1 for the due entry", len(*upRequests))
+	}
+	entry, err = outbox.read(outbox.path(due.RunID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(entry.Targets, ",") != "down" || entry.Attempts != 2 || !strings.Contains(entry.LastError, "503") {
+		t.Errorf("entry after the flush = %+v, want only down left after 2 attempts", entry)
+	}
+
+	// Forced, with down back up: everything goes and the outbox is empty
+	config.Targets["down"].URL = up.URL
+	delivered, err = outbox.Flush(config, true)
+	if delivered != 2 || err != nil {
+		t.Errorf("forced Flush = %d, %v, want 2 delivered", delivered, err)
+	}
+	if entries, _ := outbox.Entries(); len(entries) != 0 {
+		t.Errorf("%d entries left", len(entries))
+	}
+}
+
+func TestFlushIgnoresEnvironmentOverrides(t *testing.T) {
+	first, firstRequests := standIn(t, http.StatusNoContent)
+	second, secondRequests := standIn(t, http.StatusNoContent)
+	elsewhere, elsewhereRequests := standIn(t, http.StatusNoContent)
+	t.Setenv(EnvURL, elsewhere.URL)
+	t.Setenv(EnvKey, "override")
+	t.Setenv("GLTEST_TEST_SECOND_KEY", "second-key")
+
+	config := &Config{Targets: map[string]*Target{
+		"first":  {Name: "first", Type: "webhook", URL: first.URL, Key: "first-key"},
+		"second": {Name: "second", Type: "webhook", URL: second.URL, KeyEnv: "GLTEST_TEST_SECOND_KEY"},
+	}}
+	outbox := &Outbox{Dir: t.TempDir()}
+	if err := outbox.Enqueue(sampleResult(), []string{"first", "second"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := outbox.Flush(config, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*elsewhereRequests) != 0 {
+		t.Errorf("%d queued results went to the %s override", len(*elsewhereRequests), EnvURL)
+	}
+	for _, c := range []struct {
+		requests *[]request
+		key      string
+	}{{firstRequests, "first-key"}, {secondRequests, "second-key"}} {
+		got := onlyRequest(t, c.requests)
+		if auth := got.header.Get("Authorization"); auth != "Bearer "+c.key {
+			t.Errorf("Authorization = %q, want the key of the target %q", auth, c.key)
+		}
+	}
+	if _, err := os.Stat(outbox.path(sampleResult().RunID)); !os.IsNotExist(err) {
+		t.Errorf("delivered entry still queued: %v", err)
+	}
+}
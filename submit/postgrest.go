@@ -0,0 +1,36 @@
+package submit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// PostgRESTSink inserts the result as a row through the Supabase REST API, /rest/v1/<table>
+type PostgRESTSink struct {
+	Target *Target
+	Client *http.Client
+}
+
+func (s *PostgRESTSink) Name() string { return s.Target.Name }
+
+func (s *PostgRESTSink) Send(result Result) error {
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("Error marshaling JSON: %v", err)
+	}
+
+	// A retried run is ignored instead of inserted twice, this needs a unique run_id column
+	url := fmt.Sprintf("%s/rest/v1/%s", strings.TrimRight(s.Target.URL, "/"), s.Target.Table)
+	prefer := "return=minimal"
+	if result.RunID != "" {
+		url += "?on_conflict=run_id"
+		prefer += ",resolution=ignore-duplicates"
+	}
+	return doRequest(s.Client, "POST", url, jsonData, map[string]string{
+		"Content-Type": "application/json",
+		"apikey":       string(s.Target.Key),
+		"Prefer":       prefer,
+	})
+}
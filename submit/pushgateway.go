@@ -0,0 +1,62 @@
+package submit
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PushgatewaySink pushes the metrics of the result to a Prometheus Pushgateway in the text
+// exposition format, grouped by job (the target table, "gltest" by default) and GPU name.
+type PushgatewaySink struct {
+	Target *Target
+	Client *http.Client
+}
+
+func (s *PushgatewaySink) Name() string { return s.Target.Name }
+
+func (s *PushgatewaySink) Send(result Result) error {
+	job := s.Target.Table
+	if job == "" {
+		job = "gltest"
+	}
+	// GPU names may contain slashes, the base64 form of a grouping label avoids them
+	gpuName := base64.RawURLEncoding.EncodeToString([]byte(result.GpuName))
+	if gpuName == "" {
+		gpuName = "=" // empty label value
+	}
+	pushURL := fmt.Sprintf("%s/metrics/job/%s/gpu_name@base64/%s", strings.TrimRight(s.Target.URL, "/"),
+		url.PathEscape(job), gpuName)
+
+	headers := map[string]string{"Content-Type": "text/plain; version=0.0.4"}
+	if s.Target.Key != "" {
+		headers["Authorization"] = "Bearer " + string(s.Target.Key)
+	}
+	// PUT replaces all metrics of the group with those of this run
+	return doRequest(s.Client, "PUT", pushURL, []byte(s.Exposition(result)), headers)
+}
+
+// Exposition formats the metrics as gltest_<name>{labels} value lines
+func (s *PushgatewaySink) Exposition(result Result) string {
+	tags := result.Tags()
+	delete(tags, "gpu_name") // already part of the grouping key
+
+	var labels []string
+	for _, key := range sortedKeys(tags) {
+		labels = append(labels, fmt.Sprintf("%s=\"%s\"", key, labelEscaper.Replace(tags[key])))
+	}
+	labelSet := "{" + strings.Join(labels, ",") + "}"
+
+	var text strings.Builder
+	metrics := result.Metrics()
+	for _, key := range sortedKeys(metrics) {
+		fmt.Fprintf(&text, "# TYPE gltest_%s gauge\n", key)
+		fmt.Fprintf(&text, "gltest_%s%s %s\n", key, labelSet, strconv.FormatFloat(metrics[key], 'g', -1, 64))
+	}
+	return text.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
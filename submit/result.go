@@ -0,0 +1,82 @@
+package submit
+
+import (
+	"strconv"
+	"time"
+)
+
+// FPS structure
+type FpsEntry struct {
+	Time float64 `json:"time"`
+	Fps  float64 `json:"fps"`
+}
+
+// Result is one benchmark run as it is submitted. The JSON names are the columns of the
+// Supabase table.
+type Result struct {
+	RunID                  string     `json:"run_id"` // UUID of the run, sinks use it to drop duplicates
+	GpuName                string     `json:"gpu_name"`
+	VramSize               string     `json:"vram_size"`
+	DriverVersion          string     `json:"driver_version"`
+	WindowsVersion         string     `json:"windows_version"`
+	UsesWine               bool       `json:"uses_wine"`
+	ButterflyScore         float64    `json:"butterfly_score"`
+	TrianglesScore         float64    `json:"triangles_score"`
+	OceanScore             float64    `json:"ocean_score"`
+	TotalScore             float64    `json:"total_score"`
+	ButterflyAvgFps        float64    `json:"butterfly_avg_fps"`
+	ButterflyMinFps        float64    `json:"butterfly_min_fps"`
+	TrianglesAvgFps        float64    `json:"triangles_avg_fps"`
+	TrianglesMinFps        float64    `json:"triangles_min_fps"`
+	OceanAvgFps            float64    `json:"ocean_avg_fps"`
+	OceanMinFps            float64    `json:"ocean_min_fps"`
+	ButterflyAvgFpsHistory []FpsEntry `json:"butterfly_avg_fps_history"`
+	ButterflyMinFpsHistory []FpsEntry `json:"butterfly_min_fps_history"`
+	TrianglesAvgFpsHistory []FpsEntry `json:"triangles_avg_fps_history"`
+	TrianglesMinFpsHistory []FpsEntry `json:"triangles_min_fps_history"`
+	OceanAvgFpsHistory     []FpsEntry `json:"ocean_avg_fps_history"`
+	OceanMinFpsHistory     []FpsEntry `json:"ocean_min_fps_history"`
+	RamSize                string     `json:"ram_size"`
+	CpuName                string     `json:"cpu_name"`
+	CreatedAt              time.Time  `json:"created_at"`
+	// Other work kept the machine busy, the scores may be low
+	Noisy bool    `json:"noisy"`
+	Noise float64 `json:"noise"` // highest CPU share of other work while a test ran
+}
+
+// Tags describe the machine, for sinks that keep labels apart from values
+func (r Result) Tags() map[string]string {
+	return map[string]string{
+		"gpu_name":        r.GpuName,
+		"driver_version":  r.DriverVersion,
+		"windows_version": r.WindowsVersion,
+		"cpu_name":        r.CpuName,
+		"uses_wine":       strconv.FormatBool(r.UsesWine),
+		"noisy":           strconv.FormatBool(r.Noisy),
+	}
+}
+
+// Metrics are the numeric values of the result, keyed by their column names
+func (r Result) Metrics() map[string]float64 {
+	metrics := map[string]float64{
+		"butterfly_score":   r.ButterflyScore,
+		"triangles_score":   r.TrianglesScore,
+		"ocean_score":       r.OceanScore,
+		"total_score":       r.TotalScore,
+		"butterfly_avg_fps": r.ButterflyAvgFps,
+		"butterfly_min_fps": r.ButterflyMinFps,
+		"triangles_avg_fps": r.TrianglesAvgFps,
+		"triangles_min_fps": r.TrianglesMinFps,
+		"ocean_avg_fps":     r.OceanAvgFps,
+		"ocean_min_fps":     r.OceanMinFps,
+		"noise":             r.Noise,
+	}
+	// Sizes are strings in MB, "Unknown" when they could not be read
+	if vram, err := strconv.ParseFloat(r.VramSize, 64); err == nil {
+		metrics["vram_mb"] = vram
+	}
+	if ram, err := strconv.ParseFloat(r.RamSize, 64); err == nil {
+		metrics["ram_mb"] = ram
+	}
+	return metrics
+}
@@ -0,0 +1,120 @@
+package submit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ResultSink receives finished benchmark results
+type ResultSink interface {
+	// Name of the target the sink writes to, for messages
+	Name() string
+	Send(result Result) error
+}
+
+// Client used by the HTTP sinks unless they are given their own
+var DefaultClient = &http.Client{Timeout: 30 * time.Second}
+
+// NewSink creates the sink for a target and checks the fields its type needs
+func NewSink(target *Target) (ResultSink, error) {
+	switch target.Type {
+	case "", "supabase", "postgrest":
+		if target.URL == "" || target.Table == "" {
+			return nil, fmt.Errorf("Target %q needs url and table", target.Name)
+		}
+		return &PostgRESTSink{Target: target}, nil
+	case "webhook":
+		if target.URL == "" {
+			return nil, fmt.Errorf("Target %q needs url", target.Name)
+		}
+		return &WebhookSink{Target: target}, nil
+	case "influxdb":
+		if target.URL == "" || target.Bucket == "" {
+			return nil, fmt.Errorf("Target %q needs url and bucket", target.Name)
+		}
+		return &InfluxSink{Target: target}, nil
+	case "pushgateway":
+		if target.URL == "" {
+			return nil, fmt.Errorf("Target %q needs url", target.Name)
+		}
+		return &PushgatewaySink{Target: target}, nil
+	case "sqlite":
+		if target.Path == "" {
+			return nil, fmt.Errorf("Target %q needs path", target.Name)
+		}
+		return &SQLiteSink{Target: target}, nil
+	}
+	return nil, fmt.Errorf("Target %q has unknown type %q", target.Name, target.Type)
+}
+
+// NewSinks creates one sink per target, fanned out when there are several
+func NewSinks(targets []*Target) (ResultSink, error) {
+	var sinks MultiSink
+	for _, target := range targets {
+		sink, err := NewSink(target)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if len(sinks) == 1 {
+		return sinks[0], nil
+	}
+	return sinks, nil
+}
+
+// MultiSink sends every result to all of its sinks. One failing sink does not stop the others.
+type MultiSink []ResultSink
+
+func (m MultiSink) Name() string {
+	names := make([]string, len(m))
+	for i, sink := range m {
+		names[i] = sink.Name()
+	}
+	return strings.Join(names, ", ")
+}
+
+func (m MultiSink) Send(result Result) error {
+	var errs []error
+	for _, sink := range m {
+		if err := sink.Send(result); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", sink.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Send a request body and treat any non-2xx status as an error
+func doRequest(client *http.Client, method, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("Error creating request: %v", err)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	if client == nil {
+		client = DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Error sending request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// Include the start of the response, servers usually say what was wrong
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if text := strings.TrimSpace(string(message)); text != "" {
+			return fmt.Errorf("HTTP error: %s: %s", resp.Status, text)
+		}
+		return fmt.Errorf("HTTP error: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package submit
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// request is what a stand-in server received
+type request struct {
+	method string
+	path   string
+	query  string
+	header http.Header
+	body   string
+}
+
+// standIn records the requests it gets and answers them with status
+func standIn(t *testing.T, status int) (*httptest.Server, *[]request) {
+	t.Helper()
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, request{r.Method, r.URL.Path, r.URL.RawQuery, r.Header.Clone(), string(body)})
+		w.WriteHeader(status)
+		if status >= 300 {
+			io.WriteString(w, "stand-in says no")
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func sampleResult() Result {
+	return Result{
+		RunID:           "0f8fad5b-d9cb-469f-a165-70867728950e",
+		GpuName:         "Radeon RX 7600",
+		VramSize:        "8192",
+		DriverVersion:   "Mesa 24.1",
+		WindowsVersion:  "Windows 10",
+		UsesWine:        true,
+		ButterflyScore:  40,
+		TrianglesScore:  30,
+		OceanScore:      20,
+		TotalScore:      90,
+		ButterflyAvgFps: 144.5,
+		RamSize:         "Unknown",
+		CpuName:         "Ryzen 5 7600",
+		CreatedAt:       time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func onlyRequest(t *testing.T, requests *[]request) request {
+	t.Helper()
+	if len(*requests) != 1 {
+		t.Fatalf("server got %d requests, want 1", len(*requests))
+	}
+	return (*requests)[0]
+}
+
+func TestWebhookSink(t *testing.T) {
+	server, requests := standIn(t, http.StatusNoContent)
+	sink, err := NewSink(&Target{Name: "hook", Type: "webhook", URL: server.URL + "/hook", Key: "secret",
+		Headers: map[string]string{"X-Team": "gpu"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(sampleResult()); err != nil {
+		t.Fatal(err)
+	}
+
+	got := onlyRequest(t, requests)
+	if got.method != "POST" || got.path != "/hook" {
+		t.Errorf("request %s %s, want POST /hook", got.method, got.path)
+	}
+	for name, want := range map[string]string{
+		"Authorization":   "Bearer secret",
+		"Idempotency-Key": sampleResult().RunID,
+		"X-Team":          "gpu",
+		"Content-Type":    "application/json",
+	} {
+		if value := got.header.Get(name); value != want {
+			t.Errorf("header %s = %q, want %q", name, value, want)
+		}
+	}
+	var sent Result
+	if err := json.Unmarshal([]byte(got.body), &sent); err != nil {
+		t.Fatalf("body is not a result: %v", err)
+	}
+	if sent.RunID != sampleResult().RunID || sent.TotalScore != 90 {
+		t.Errorf("sent %+v", sent)
+	}
+}
+
+func TestPostgRESTSink(t *testing.T) {
+	server, requests := standIn(t, http.StatusCreated)
+	sink, err := NewSink(&Target{Name: "supabase", URL: server.URL + "/", Key: "anon", Table: "benchmark_results"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(sampleResult()); err != nil {
+		t.Fatal(err)
+	}
+
+	got := onlyRequest(t, requests)
+	if got.path != "/rest/v1/benchmark_results" || got.query != "on_conflict=run_id" {
+		t.Errorf("request to %s?%s", got.path, got.query)
+	}
+	if got.header.Get("apikey") != "anon" {
+		t.Errorf("apikey = %q", got.header.Get("apikey"))
+	}
+	if prefer := got.header.Get("Prefer"); prefer != "return=minimal,resolution=ignore-duplicates" {
+		t.Errorf("Prefer = %q", prefer)
+	}
+}
+
+func TestPostgRESTSinkWithoutRunID(t *testing.T) {
+	server, requests := standIn(t, http.StatusCreated)
+	result := sampleResult()
+	result.RunID = ""
+	sink := &PostgRESTSink{Target: &Target{URL: server.URL, Table: "results"}}
+	if err := sink.Send(result); err != nil {
+		t.Fatal(err)
+	}
+	got := onlyRequest(t, requests)
+	if got.query != "" || got.header.Get("Prefer") != "return=minimal" {
+		t.Errorf("request without run ID: query %q, Prefer %q", got.query, got.header.Get("Prefer"))
+	}
+}
+
+func TestInfluxSink(t *testing.T) {
+	server, requests := standIn(t, http.StatusNoContent)
+	sink, err := NewSink(&Target{Name: "influx", Type: "influxdb", URL: server.URL, Key: "token", Org: "lab", Bucket: "bench"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(sampleResult()); err != nil {
+		t.Fatal(err)
+	}
+
+	got := onlyRequest(t, requests)
+	if got.path != "/api/v2/write" || got.query != "bucket=bench&org=lab&precision=s" {
+		t.Errorf("request to %s?%s", got.path, got.query)
+	}
+	if got.header.Get("Authorization") != "Token token" {
+		t.Errorf("Authorization = %q", got.header.Get("Authorization"))
+	}
+	prefix := `gltest,cpu_name=Ryzen\ 5\ 7600,driver_version=Mesa\ 24.1,gpu_name=Radeon\ RX\ 7600,noisy=false,uses_wine=true,windows_version=Windows\ 10 butterfly_avg_fps=144.5,`
+	if !strings.HasPrefix(got.body, prefix) {
+		t.Errorf("line %q\ndoes not start with %q", got.body, prefix)
+	}
+	if !strings.HasSuffix(got.body, ",vram_mb=8192 1792324800\n") {
+		t.Errorf("line %q does not end with the VRAM and the time in seconds", got.body)
+	}
+	if strings.Contains(got.body, ",ram_mb=") {
+		t.Errorf("line %q has a RAM size that was not known", got.body)
+	}
+}
+
+func TestPushgatewaySink(t *testing.T) {
+	server, requests := standIn(t, http.StatusOK)
+	sink, err := NewSink(&Target{Name: "prometheus", Type: "pushgateway", URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Send(sampleResult()); err != nil {
+		t.Fatal(err)
+	}
+
+	got := onlyRequest(t, requests)
+	wantPath := "/metrics/job/gltest/gpu_name@base64/" + base64.RawURLEncoding.EncodeToString([]byte("Radeon RX 7600"))
+	if got.method != "PUT" || got.path != wantPath {
+		t.Errorf("request %s %s, want PUT %s", got.method, got.path, wantPath)
+	}
+	labels := `{cpu_name="Ryzen 5 7600",driver_version="Mesa 24.1",noisy="false",uses_wine="true",windows_version="Windows 10"}`
+	for _, line := range []string{
+		"# TYPE gltest_total_score gauge\n",
+		"gltest_total_score" + labels + " 90\n",
+		"gltest_butterfly_avg_fps" + labels + " 144.5\n",
+	} {
+		if !strings.Contains(got.body, line) {
+			t.Errorf("exposition has no line %q:\n%s", line, got.body)
+		}
+	}
+	if strings.Contains(got.body, "gpu_name=") {
+		t.Error("the GPU name is a label although it is part of the grouping key")
+	}
+}
+
+func TestSinkHTTPError(t *testing.T) {
+	server, _ := standIn(t, http.StatusBadRequest)
+	err := (&WebhookSink{Target: &Target{URL: server.URL}}).Send(sampleResult())
+	if err == nil || !strings.Contains(err.Error(), "400") || !strings.Contains(err.Error(), "stand-in says no") {
+		t.Errorf("error = %v, want the status and the start of the response", err)
+	}
+}
+
+func TestNewSinkNeedsFields(t *testing.T) {
+	for _, target := range []*Target{
+		{Name: "a", Type: "supabase", URL: "http://x"},
+		{Name: "b", Type: "webhook"},
+		{Name: "c", Type: "influxdb", URL: "http://x"},
+		{Name: "d", Type: "pushgateway"},
+		{Name: "e", Type: "sqlite"},
+		{Name: "f", Type: "carrier-pigeon", URL: "http://x"},
+	} {
+		if _, err := NewSink(target); err == nil {
+			t.Errorf("NewSink(%+v) accepted an incomplete target", *target)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package submit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	// Pure Go, so no cgo and no sqlite3 executable are needed on Windows or under Wine
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteSink appends the result to a table in a local SQLite file. The scores and machine
+// fields get their own columns; the full result is kept as JSON in the document column.
+type SQLiteSink struct {
+	Target *Target
+}
+
+// sqliteColumn is a column of the results table with its type and constraints
+type sqliteColumn struct {
+	name       string
+	definition string
+}
+
+// Columns of the results table, the same for every result: a value a result does not have is
+// NULL. A table created by an earlier version only lacks some of them, Send adds those; any
+// other field is still in the document column.
+var sqliteColumns = []sqliteColumn{
+	{"run_id", "TEXT UNIQUE"},
+	{"created_at", "TEXT NOT NULL"},
+	{"gpu_name", "TEXT"},
+	{"driver_version", "TEXT"},
+	{"windows_version", "TEXT"},
+	{"cpu_name", "TEXT"},
+	{"uses_wine", "TEXT"},
+	{"noisy", "TEXT"},
+	{"butterfly_score", "REAL"},
+	{"triangles_score", "REAL"},
+	{"ocean_score", "REAL"},
+	{"total_score", "REAL"},
+	{"butterfly_avg_fps", "REAL"},
+	{"butterfly_min_fps", "REAL"},
+	{"triangles_avg_fps", "REAL"},
+	{"triangles_min_fps", "REAL"},
+	{"ocean_avg_fps", "REAL"},
+	{"ocean_min_fps", "REAL"},
+	{"noise", "REAL"},
+	{"vram_mb", "REAL"},
+	{"ram_mb", "REAL"},
+	{"document", "TEXT"},
+}
+
+func (s *SQLiteSink) Name() string { return s.Target.Name }
+
+func (s *SQLiteSink) Send(result Result) error {
+	document, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("Error marshaling JSON: %v", err)
+	}
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	existing, err := sqliteTableColumns(db, s.table())
+	if err != nil {
+		return err
+	}
+	// One transaction, so no result is inserted into a table left half migrated
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("SQLite error: %v", err)
+	}
+	for _, statement := range s.Statements(result, document, existing) {
+		if _, err := tx.Exec(statement); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("SQLite error: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("SQLite error: %v", err)
+	}
+	return nil
+}
+
+// Statements creates the table if needed, adds the columns an existing table lacks and
+// inserts the result. existing are the columns of the table, none when there is no table yet.
+func (s *SQLiteSink) Statements(result Result, document []byte, existing []string) []string {
+	table := sqlIdentifier(s.table())
+	tags := result.Tags()
+	metrics := result.Metrics()
+
+	var statements []string
+	var columns, definitions, values []string
+	for _, column := range sqliteColumns {
+		name := sqlIdentifier(column.name)
+		columns = append(columns, name)
+		definitions = append(definitions, name+" "+column.definition)
+		if len(existing) > 0 && !slices.Contains(existing, column.name) {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, name, column.definition))
+		}
+
+		tag, isTag := tags[column.name]
+		metric, isMetric := metrics[column.name]
+		switch {
+		case column.name == "run_id":
+			values = append(values, sqlNullable(result.RunID))
+		case column.name == "created_at":
+			values = append(values, sqlQuote(result.CreatedAt.UTC().Format(time.RFC3339)))
+		case column.name == "document":
+			values = append(values, sqlQuote(string(document)))
+		case isTag:
+			values = append(values, sqlQuote(tag))
+		case isMetric:
+			values = append(values, strconv.FormatFloat(metric, 'g', -1, 64))
+		default:
+			values = append(values, "NULL")
+		}
+	}
+
+	// A run that is already stored is skipped
+	return append(statements,
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", table, strings.Join(definitions, ", ")),
+		fmt.Sprintf("INSERT OR IGNORE INTO %s (%s) VALUES (%s)", table, strings.Join(columns, ", "), strings.Join(values, ", ")))
+}
+
+func (s *SQLiteSink) table() string {
+	if s.Target.Table == "" {
+		return "results"
+	}
+	return s.Target.Table
+}
+
+func (s *SQLiteSink) open() (*sql.DB, error) {
+	db, err := sql.Open("sqlite", s.Target.Path)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening %s: %v", s.Target.Path, err)
+	}
+	return db, nil
+}
+
+// Columns of a table, none when there is no such table
+func sqliteTableColumns(db *sql.DB, table string) ([]string, error) {
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return nil, fmt.Errorf("SQLite error: %v", err)
+	}
+	defer rows.Close()
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("SQLite error: %v", err)
+		}
+		columns = append(columns, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("SQLite error: %v", err)
+	}
+	return columns, nil
+}
+
+func sqlQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// Runs without an ID are stored as NULL, which never conflicts
+func sqlNullable(value string) string {
+	if value == "" {
+		return "NULL"
+	}
+	return sqlQuote(value)
+}
+
+func sqlIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,94 @@
+package submit
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSQLiteColumnsCoverTheResult(t *testing.T) {
+	result := sampleResult()
+	result.RamSize = "32768"
+	var names []string
+	for _, column := range sqliteColumns {
+		names = append(names, column.name)
+	}
+	for _, key := range append(sortedKeys(result.Tags()), sortedKeys(result.Metrics())...) {
+		if !slices.Contains(names, key) {
+			t.Errorf("%s has no column in the SQLite table", key)
+		}
+	}
+}
+
+func TestSQLiteStatementsQuoteIdentifiers(t *testing.T) {
+	sink := &SQLiteSink{Target: &Target{Table: `my "results"`}}
+	statements := strings.Join(sink.Statements(sampleResult(), []byte(`{"note":"it's"}`), nil), ";\n")
+	for _, want := range []string{
+		`CREATE TABLE IF NOT EXISTS "my ""results""" (`,
+		`"run_id" TEXT UNIQUE`,
+		`'{"note":"it''s"}'`,
+	} {
+		if !strings.Contains(statements, want) {
+			t.Errorf("statements have no %s:\n%s", want, statements)
+		}
+	}
+	if strings.Contains(statements, "ALTER TABLE") {
+		t.Errorf("a new table is altered:\n%s", statements)
+	}
+}
+
+func TestSQLiteSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.db")
+	sink := &SQLiteSink{Target: &Target{Name: "local", Path: path}}
+	db, err := sink.open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// A table as an earlier version created it, for a result whose VRAM size was unknown
+	if _, err := db.Exec(`CREATE TABLE "results" (run_id TEXT UNIQUE, created_at TEXT NOT NULL, gpu_name TEXT, total_score REAL, document TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+
+	first := sampleResult()
+	if err := sink.Send(first); err != nil {
+		t.Fatalf("sending to an old table: %v", err)
+	}
+	// Sizes that are not known are NULL, the same run again is skipped
+	second := sampleResult()
+	second.RunID = "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+	second.VramSize = "Unknown"
+	second.Noisy, second.Noise = true, 0.25
+	for _, result := range []Result{second, first} {
+		if err := sink.Send(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rows, err := db.Query("SELECT run_id, total_score, vram_mb, noisy, noise FROM results ORDER BY rowid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var runID, noisy string
+		var total, noise float64
+		var vram sql.NullFloat64
+		if err := rows.Scan(&runID, &total, &vram, &noisy, &noise); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%s|%g|%v|%s|%g", runID, total, vram, noisy, noise))
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{first.RunID + "|90|{8192 true}|false|0", second.RunID + "|90|{0 false}|true|0.25"}
+	if !slices.Equal(got, want) {
+		t.Errorf("rows\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,36 @@
+package submit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// WebhookSink posts the result as JSON to any URL. The key, if set, goes into a bearer
+// Authorization header; further headers come from the target. The run ID is sent as
+// Idempotency-Key so receivers can drop retries.
+type WebhookSink struct {
+	Target *Target
+	Client *http.Client
+}
+
+func (s *WebhookSink) Name() string { return s.Target.Name }
+
+func (s *WebhookSink) Send(result Result) error {
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("Error marshaling JSON: %v", err)
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	if result.RunID != "" {
+		headers["Idempotency-Key"] = result.RunID
+	}
+	if s.Target.Key != "" {
+		headers["Authorization"] = "Bearer " + string(s.Target.Key)
+	}
+	for name, value := range s.Target.Headers {
+		headers[name] = value
+	}
+	return doRequest(s.Client, "POST", s.Target.URL, jsonData, headers)
+}
@@ -0,0 +1,10 @@
+// Package sysinfo describes the machine a benchmark runs on.
+package sysinfo
+
+import "os"
+
+// IsWineUsed reports whether GLTest runs under Wine
+func IsWineUsed() bool {
+	_, exists := os.LookupEnv("WINEDEBUG")
+	return exists || hasWineExports()
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package sysinfo
+
+// GetSystemInfo has no source of hardware information outside Windows
+func GetSystemInfo() (gpuName, vramSize, driverVersion, ramSize, cpuName string) {
+	return "Unknown GPU", "Unknown", "Unknown", "Unknown", "Unknown CPU"
+}
+
+func GetWindowsVersion() string {
+	return "Unknown"
+}
+
+// Outside Windows there is no Wine to run under
+func hasWineExports() bool {
+	return false
+}
@@ -0,0 +1,181 @@
+package sysinfo
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// WinAPI structures
+type DISPLAY_DEVICE struct {
+	cb           uint32
+	DeviceName   [32]uint16
+	DeviceString [128]uint16
+	StateFlags   uint32
+	DeviceID     [128]uint16
+	DeviceKey    [128]uint16
+}
+
+const (
+	DISPLAY_DEVICE_PRIMARY_DEVICE = 0x00000004
+)
+
+// GetSystemInfo reads the hardware description: GPU, VRAM and RAM in MB, driver and CPU
+func GetSystemInfo() (gpuName, vramSize, driverVersion, ramSize, cpuName string) {
+	gpuName = "Unknown GPU"
+	vramSize = "Unknown"
+	driverVersion = "Unknown"
+	ramSize = "Unknown"
+	cpuName = "Unknown CPU"
+
+	// GPU
+	var dd DISPLAY_DEVICE
+	dd.cb = uint32(unsafe.Sizeof(dd))
+	user32 := syscall.NewLazyDLL("user32.dll")
+	enumDisplayDevices := user32.NewProc("EnumDisplayDevicesW")
+
+	for i := uint32(0); ; i++ {
+		ret, _, _ := enumDisplayDevices.Call(0, uintptr(i), uintptr(unsafe.Pointer(&dd)), 0)
+		if ret == 0 {
+			break
+		}
+		if dd.StateFlags&DISPLAY_DEVICE_PRIMARY_DEVICE != 0 {
+			gpuName = syscall.UTF16ToString(dd.DeviceString[:])
+			break
+		}
+	}
+
+	// VRAM
+	cmdVRAM := exec.Command("wmic", "path", "Win32_VideoController", "get", "AdapterRAM")
+	cmdVRAM.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	var outVRAM bytes.Buffer
+	cmdVRAM.Stdout = &outVRAM
+	if err := cmdVRAM.Run(); err == nil {
+		vramBytes, err := strconv.ParseInt(strings.TrimSpace(string(outVRAM.Bytes()[10:])), 10, 64)
+		if err == nil {
+			vramMB := vramBytes / (1024 * 1024)
+			vramSize = fmt.Sprintf("%d", vramMB)
+		}
+	}
+
+	// Driver Version
+	cmdDriver := exec.Command("wmic", "path", "Win32_VideoController", "get", "DriverVersion")
+	cmdDriver.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	var outDriver bytes.Buffer
+	cmdDriver.Stdout = &outDriver
+	if err := cmdDriver.Run(); err == nil {
+		driverVersion = strings.TrimSpace(string(outDriver.Bytes()[14:]))
+	}
+
+	// RAM
+	cmdRAM := exec.Command("wmic", "OS", "get", "TotalVisibleMemorySize")
+	cmdRAM.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	var outRAM bytes.Buffer
+	cmdRAM.Stdout = &outRAM
+	if err := cmdRAM.Run(); err == nil {
+		ramKB, err := strconv.ParseInt(strings.TrimSpace(string(outRAM.Bytes()[22:])), 10, 64)
+		if err == nil {
+			ramMB := ramKB / 1024
+			ramSize = fmt.Sprintf("%d", ramMB)
+		}
+	}
+
+	// CPU
+	cmdCPU := exec.Command("wmic", "CPU", "get", "Name")
+	cmdCPU.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	var outCPU bytes.Buffer
+	cmdCPU.Stdout = &outCPU
+	if err := cmdCPU.Run(); err == nil {
+		cpuName = strings.TrimSpace(string(outCPU.Bytes()[5:]))
+	}
+
+	return gpuName, vramSize, driverVersion, ramSize, cpuName
+}
+
+// GetWindowsVersion returns the edition and build, e.g. "Windows 10 Pro (19045.3803)"
+func GetWindowsVersion() string {
+	cmdEdition := exec.Command("reg", "query", "HKLM\\SOFTWARE\\Microsoft\\Windows NT\\CurrentVersion", "/v", "ProductName")
+	cmdEdition.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	var outEdition bytes.Buffer
+	cmdEdition.Stdout = &outEdition
+
+	var edition string
+	if err := cmdEdition.Run(); err == nil {
+		output := strings.TrimSpace(outEdition.String())
+		parts := strings.Split(output, "    ")
+		if len(parts) >= 3 {
+			edition = strings.TrimSpace(parts[len(parts)-1])
+		}
+	}
+
+	if edition == "" {
+		edition = "Windows"
+	}
+
+	cmdBuild := exec.Command("reg", "query", "HKLM\\SOFTWARE\\Microsoft\\Windows NT\\CurrentVersion", "/v", "CurrentBuildNumber")
+	cmdBuild.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	var outBuild bytes.Buffer
+	cmdBuild.Stdout = &outBuild
+
+	var build string
+	if err := cmdBuild.Run(); err == nil {
+		output := strings.TrimSpace(outBuild.String())
+		parts := strings.Split(output, "    ")
+		if len(parts) >= 3 {
+			build = strings.TrimSpace(parts[len(parts)-1])
+		}
+	}
+
+	cmdUBR := exec.Command("reg", "query", "HKLM\\SOFTWARE\\Microsoft\\Windows NT\\CurrentVersion", "/v", "UBR")
+	cmdUBR.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	var outUBR bytes.Buffer
+	cmdUBR.Stdout = &outUBR
+
+	var ubr string
+	if err := cmdUBR.Run(); err == nil {
+		output := strings.TrimSpace(outUBR.String())
+		parts := strings.Split(output, "    ")
+		if len(parts) >= 3 {
+			ubr = strings.TrimSpace(parts[len(parts)-1])
+		}
+	}
+
+	var version string
+	if build != "" {
+		if ubr != "" {
+			version = fmt.Sprintf("%s (%s.%s)", edition, build, ubr)
+		} else {
+			version = fmt.Sprintf("%s (%s)", edition, build)
+		}
+	} else {
+		cmd := exec.Command("cmd", "/c", "ver")
+		cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+		var out bytes.Buffer
+		cmd.Stdout = &out
+		if err := cmd.Run(); err == nil {
+			verOutput := strings.TrimSpace(out.String())
+			re := regexp.MustCompile(`\[(.*?)\]`)
+			matches := re.FindStringSubmatch(verOutput)
+			if len(matches) > 1 {
+				version = fmt.Sprintf("%s (%s)", edition, strings.TrimPrefix(matches[1], "Version "))
+			} else {
+				version = edition
+			}
+		} else {
+			version = edition
+		}
+	}
+
+	return version
+}
+
+// The ntdll of Wine exports wine_get_version, the one of Windows does not. WINEDEBUG is only
+// set when someone asked for debug output.
+func hasWineExports() bool {
+	return syscall.NewLazyDLL("ntdll.dll").NewProc("wine_get_version").Find() == nil
+}
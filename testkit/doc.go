@@ -0,0 +1,4 @@
+// Package testkit holds what every GL test program shares with the others: the overrides the
+// runner passes on the command line, the way the runner stops a test early, the screenshot
+// of the last stage for the report and GPU timing that never stalls a frame.
+package testkit
@@ -0,0 +1,145 @@
+package testkit
+
+import "github.com/go-gl/gl/v4.1-core/gl"
+
+// Frames of timer queries in flight. Drivers queue two or three frames, a frame whose queries
+// are still in flight when its turn comes again is not timed.
+const timerFrames = 4
+
+// GL entry points of the timer, replaced in tests
+var (
+	genQueries  = gl.GenQueries
+	beginQuery  = gl.BeginQuery
+	endQuery    = gl.EndQuery
+	queryObject = gl.GetQueryObjectui64v
+)
+
+// GPUTimer measures the GPU time of named sections of every frame with TIME_ELAPSED queries.
+// Results are only read once the driver has them, so timing never makes a frame wait for the
+// GPU; frames that could not be timed are left out of the means.
+type GPUTimer struct {
+	queries [timerFrames][]uint32
+	frames  [timerFrames]timedFrame
+	current int
+	open    bool
+	// Reset starts a new generation, results of queries issued before it are dropped
+	generation int
+
+	sums  map[string]float64
+	order []string
+	timed int
+}
+
+type timedFrame struct {
+	names      []string
+	pending    bool
+	generation int
+}
+
+// Section is the mean GPU time of one named section per timed frame
+type Section struct {
+	Name string
+	Ms   float64
+}
+
+// NewGPUTimer makes a timer for up to sections queries per frame; call it with a current context
+func NewGPUTimer(sections int) *GPUTimer {
+	t := &GPUTimer{sums: map[string]float64{}}
+	for i := range t.queries {
+		t.queries[i] = make([]uint32, sections)
+		genQueries(int32(sections), &t.queries[i][0])
+	}
+	return t
+}
+
+// Begin starts timing a section of the current frame, End ends it
+func (t *GPUTimer) Begin(name string) {
+	frame := &t.frames[t.current]
+	if frame.pending || len(frame.names) == len(t.queries[t.current]) {
+		return
+	}
+	beginQuery(gl.TIME_ELAPSED, t.queries[t.current][len(frame.names)])
+	frame.names = append(frame.names, name)
+	t.open = true
+}
+
+func (t *GPUTimer) End() {
+	if t.open {
+		endQuery(gl.TIME_ELAPSED)
+		t.open = false
+	}
+}
+
+// EndFrame moves on to the next frame and collects the frames the GPU has finished
+func (t *GPUTimer) EndFrame() {
+	frame := &t.frames[t.current]
+	if !frame.pending && len(frame.names) > 0 {
+		frame.pending = true
+		frame.generation = t.generation
+	}
+	t.current = (t.current + 1) % timerFrames
+	t.collect()
+}
+
+func (t *GPUTimer) collect() {
+	for i := range t.frames {
+		frame := &t.frames[i]
+		if !frame.pending {
+			continue
+		}
+		ready := true
+		for _, query := range t.queries[i][:len(frame.names)] {
+			var available uint64
+			queryObject(query, gl.QUERY_RESULT_AVAILABLE, &available)
+			if available == 0 {
+				ready = false
+				break
+			}
+		}
+		if !ready {
+			continue
+		}
+		if frame.generation == t.generation {
+			for j, name := range frame.names {
+				var elapsed uint64
+				queryObject(t.queries[i][j], gl.QUERY_RESULT, &elapsed)
+				if _, ok := t.sums[name]; !ok {
+					t.order = append(t.order, name)
+				}
+				t.sums[name] += float64(elapsed) / 1e6
+			}
+			t.timed++
+		}
+		frame.pending = false
+		frame.names = frame.names[:0]
+	}
+}
+
+// Frames is the number of frames timed since the last Reset
+func (t *GPUTimer) Frames() int {
+	return t.timed
+}
+
+// Mean returns the GPU time per timed frame in ms and its sections in the order they were
+// first seen, nothing when no frame was timed
+func (t *GPUTimer) Mean() (float64, []Section) {
+	if t.timed == 0 {
+		return 0, nil
+	}
+	var total float64
+	sections := make([]Section, len(t.order))
+	for i, name := range t.order {
+		ms := t.sums[name] / float64(t.timed)
+		total += ms
+		sections[i] = Section{name, ms}
+	}
+	return total, sections
+}
+
+// Reset starts over, e.g. for the next sample or stage; frames still in flight are dropped
+func (t *GPUTimer) Reset() {
+	t.generation++
+	t.sums = map[string]float64{}
+	t.order = nil
+	t.timed = 0
+}
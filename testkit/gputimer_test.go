@@ -0,0 +1,112 @@
+package testkit
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// fakeGPU finishes a query latency frames after it was issued, every query taking 2 ms
+type fakeGPU struct {
+	latency int
+	frame   int
+	next    uint32
+	issued  map[uint32]int
+	reads   int // results read before they were available
+}
+
+func useFakeGPU(t *testing.T, latency int) *fakeGPU {
+	gpu := &fakeGPU{latency: latency, issued: map[uint32]int{}}
+	savedGen, savedBegin, savedEnd, savedQuery := genQueries, beginQuery, endQuery, queryObject
+	genQueries = func(n int32, ids *uint32) {
+		for i := range unsafe.Slice(ids, n) {
+			gpu.next++
+			unsafe.Slice(ids, n)[i] = gpu.next
+		}
+	}
+	beginQuery = func(target, id uint32) { gpu.issued[id] = gpu.frame }
+	endQuery = func(target uint32) {}
+	queryObject = func(id, pname uint32, params *uint64) {
+		done := gpu.frame-gpu.issued[id] >= gpu.latency
+		switch pname {
+		case gl.QUERY_RESULT_AVAILABLE:
+			*params = 0
+			if done {
+				*params = 1
+			}
+		case gl.QUERY_RESULT:
+			if !done {
+				gpu.reads++
+			}
+			*params = 2e6
+		}
+	}
+	t.Cleanup(func() {
+		genQueries, beginQuery, endQuery, queryObject = savedGen, savedBegin, savedEnd, savedQuery
+	})
+	return gpu
+}
+
+// Renders frames with a scene and a post section, the GPU moving on one frame each time
+func (gpu *fakeGPU) render(timer *GPUTimer, frames int) {
+	for i := 0; i < frames; i++ {
+		for _, name := range []string{"scene", "post"} {
+			timer.Begin(name)
+			timer.End()
+		}
+		timer.EndFrame()
+		gpu.frame++
+	}
+}
+
+func TestGPUTimer(t *testing.T) {
+	cases := []struct {
+		name    string
+		latency int
+		frames  int
+		timed   int
+	}{
+		{"results of the frame before", 1, 10, 9},
+		{"a driver queueing three frames", 3, 12, 9},
+		// With more frames in flight than queries, every frame whose slot is busy goes untimed
+		{"a driver queueing six frames", 6, 12, 4},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			gpu := useFakeGPU(t, c.latency)
+			timer := NewGPUTimer(4)
+			gpu.render(timer, c.frames)
+			if gpu.reads != 0 {
+				t.Errorf("%d results read before they were available", gpu.reads)
+			}
+			if timer.Frames() != c.timed {
+				t.Errorf("%d frames timed, want %d", timer.Frames(), c.timed)
+			}
+			total, sections := timer.Mean()
+			if total != 4 || len(sections) != 2 || sections[0] != (Section{"scene", 2}) || sections[1] != (Section{"post", 2}) {
+				t.Errorf("Mean() = %g, %+v, want 4 ms of scene and post", total, sections)
+			}
+		})
+	}
+}
+
+func TestGPUTimerReset(t *testing.T) {
+	gpu := useFakeGPU(t, 2)
+	timer := NewGPUTimer(1)
+	gpu.render(timer, 5)
+	// Frames issued before the reset still come in, and are dropped
+	timer.Reset()
+	gpu.render(timer, 1)
+	if timer.Frames() != 0 {
+		t.Errorf("%d frames timed after the reset, want the old ones dropped", timer.Frames())
+	}
+	gpu.render(timer, 3)
+	if timer.Frames() != 2 {
+		t.Errorf("%d frames timed, want 2", timer.Frames())
+	}
+	timer.Reset()
+	if total, sections := timer.Mean(); total != 0 || sections != nil {
+		t.Errorf("Mean() after a reset = %g, %v", total, sections)
+	}
+}
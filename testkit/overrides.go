@@ -0,0 +1,27 @@
+package testkit
+
+import "flag"
+
+// Overrides of the runner, for a shorter test or one that stops at an earlier stage
+var (
+	durationFlag = flag.Float64("duration", 0, "seconds of measurement, 0 for the test's own")
+	maxStageFlag = flag.Int("max-stage", 0, "last stage to run, 0 for all")
+)
+
+// Overrides returns the stages and seconds of measurement after the overrides. A test stopped
+// at an earlier stage also ends with that stage, unless a duration is given. Call it after
+// flag.Parse.
+func Overrides(stages int, stageTime, duration float64) (int, float64) {
+	return overrides(*maxStageFlag, *durationFlag, stages, stageTime, duration)
+}
+
+func overrides(maxStage int, overrideDuration float64, stages int, stageTime, duration float64) (int, float64) {
+	if maxStage > 0 && maxStage < stages {
+		stages = maxStage
+		duration = min(duration, stageTime*float64(stages))
+	}
+	if overrideDuration > 0 {
+		duration = overrideDuration
+	}
+	return stages, duration
+}
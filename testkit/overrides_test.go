@@ -0,0 +1,28 @@
+package testkit
+
+import "testing"
+
+func TestOverrides(t *testing.T) {
+	cases := []struct {
+		name             string
+		maxStage         int
+		overrideDuration float64
+		stages           int
+		duration         float64
+	}{
+		{"none", 0, 0, 6, 60},
+		{"an earlier stage ends the test with it", 2, 0, 2, 20},
+		{"the last stage or beyond changes nothing", 6, 0, 6, 60},
+		{"a duration alone", 0, 15, 6, 15},
+		{"a duration beyond the earlier stage", 2, 45, 2, 45},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// Six stages of 10 seconds
+			stages, duration := overrides(c.maxStage, c.overrideDuration, 6, 10, 60)
+			if stages != c.stages || duration != c.duration {
+				t.Errorf("overrides = %d stages, %g s, want %d stages, %g s", stages, duration, c.stages, c.duration)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package testkit
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// SaveScreenshot saves the back buffer as a PNG for the report; call it before SwapBuffers
+func SaveScreenshot(window *glfw.Window, fileName string) {
+	width, height := window.GetFramebufferSize()
+	pixels := make([]byte, width*height*4)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	gl.ReadBuffer(gl.BACK)
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.ReadPixels(0, 0, int32(width), int32(height), gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(pixels))
+
+	file, err := os.Create(fileName)
+	if err != nil {
+		fmt.Printf("Failed to save screenshot: %v\n", err)
+		return
+	}
+	defer file.Close()
+	if err := png.Encode(file, flipOpaque(pixels, width, height)); err != nil {
+		fmt.Printf("Failed to save screenshot: %v\n", err)
+	}
+}
+
+// OpenGL rows start at the bottom, and the alpha of the window means nothing
+func flipOpaque(pixels []byte, width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		copy(img.Pix[y*img.Stride:y*img.Stride+width*4], pixels[(height-1-y)*width*4:(height-y)*width*4])
+	}
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+	return img
+}
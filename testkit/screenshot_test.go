@@ -0,0 +1,12 @@
+package testkit
+
+import "testing"
+
+func TestFlipOpaque(t *testing.T) {
+	// Two rows of one pixel, bottom row first as OpenGL reads them
+	img := flipOpaque([]byte{1, 2, 3, 0, 4, 5, 6, 7}, 1, 2)
+	want := []byte{4, 5, 6, 255, 1, 2, 3, 255}
+	if string(img.Pix) != string(want) {
+		t.Errorf("pixels %v, want %v", img.Pix, want)
+	}
+}
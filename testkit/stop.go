@@ -0,0 +1,42 @@
+package testkit
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+)
+
+// Set once the runner asks the test to stop early
+var stopRequested atomic.Bool
+
+// WatchStop lets the runner stop the test by writing "stop" to its stdin, Ctrl+C in a console
+// does the same. The test then leaves its loop and exits normally, so the CSV keeps what was
+// measured.
+func WatchStop() {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		stopRequested.Store(true)
+	}()
+	go watchInput(os.Stdin)
+}
+
+// Sets stopRequested on the first "stop" line of input
+func watchInput(input io.Reader) {
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "stop" {
+			stopRequested.Store(true)
+			return
+		}
+	}
+}
+
+// StopRequested tells whether the runner asked the test to stop
+func StopRequested() bool {
+	return stopRequested.Load()
+}
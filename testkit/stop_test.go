@@ -0,0 +1,27 @@
+package testkit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWatchInput(t *testing.T) {
+	cases := []struct {
+		input string
+		stop  bool
+	}{
+		{"", false},
+		{"status\nstopped\n", false},
+		{"stop\n", true},
+		{"status\n  stop \n", true},
+		{"stop", true}, // the runner closed stdin after the line
+	}
+	for _, c := range cases {
+		stopRequested.Store(false)
+		watchInput(strings.NewReader(c.input))
+		if StopRequested() != c.stop {
+			t.Errorf("input %q: StopRequested() = %v, want %v", c.input, StopRequested(), c.stop)
+		}
+	}
+	stopRequested.Store(false)
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+
+	"moddergltest/testkit"
+)
+
+const (
+	windowWidth  = 1024
+	windowHeight = 768
+	stageTime    = 3
+	warmUpTime   = 2
+)
+
+// Fragment shader microbenchmark: loop body and the scalar operations it performs per iteration
+type aluKernel struct {
+	name         string
+	body         string
+	opsPerLoop   int
+	program      uint32
+	iterationLoc int32
+}
+
+type aluStage struct {
+	kernel     *aluKernel
+	iterations int
+}
+
+var (
+	quadVAO    uint32
+	startTime  time.Time
+	loopCounts = []int{128, 512, 2048}
+	gpuTimer   *testkit.GPUTimer
+
+	// Four independent vec4 accumulators in every kernel keep the ALUs busy instead of
+	// serialising on one dependency chain
+	kernels = []*aluKernel{
+		{name: "mad", opsPerLoop: 16, body: `
+			a = a * scale + bias; b = b * scale + bias;
+			c = c * scale + bias; d = d * scale + bias;`},
+		{name: "sin", opsPerLoop: 16, body: `
+			a = sin(a + bias); b = sin(b + bias);
+			c = sin(c + bias); d = sin(d + bias);`},
+		{name: "exp", opsPerLoop: 16, body: `
+			a = exp(-a); b = exp(-b);
+			c = exp(-c); d = exp(-d);`},
+		{name: "pow", opsPerLoop: 16, body: `
+			a = pow(a, scale) + bias; b = pow(b, scale) + bias;
+			c = pow(c, scale) + bias; d = pow(d, scale) + bias;`},
+		{name: "int", opsPerLoop: 64, body: `
+			ia = ia * 1664525u + 1013904223u; ia ^= ia >> 13u;
+			ib = ib * 1664525u + 1013904223u; ib ^= ib >> 13u;
+			ic = ic * 1664525u + 1013904223u; ic ^= ic >> 13u;
+			id = id * 1664525u + 1013904223u; id ^= id >> 13u;`},
+		// Both branch kernels run the same work; only the granularity of the condition differs
+		{name: "branch_coherent", opsPerLoop: 16, body: `
+			if (((uint(gl_FragCoord.x) >> 6u) & 1u) != 0u) {
+				a = sin(a + bias); b = sin(b + bias); c = sin(c + bias); d = sin(d + bias);
+			} else {
+				a = exp(-a); b = exp(-b); c = exp(-c); d = exp(-d);
+			}`},
+		{name: "branch_divergent", opsPerLoop: 16, body: `
+			if (((uint(gl_FragCoord.x) ^ uint(gl_FragCoord.y)) & 1u) != 0u) {
+				a = sin(a + bias); b = sin(b + bias); c = sin(c + bias); d = sin(d + bias);
+			} else {
+				a = exp(-a); b = exp(-b); c = exp(-c); d = exp(-d);
+			}`},
+	}
+	aluStages = createStages()
+)
+
+func createStages() []aluStage {
+	stages := make([]aluStage, 0, len(kernels)*len(loopCounts))
+	for _, kernel := range kernels {
+		for _, iterations := range loopCounts {
+			stages = append(stages, aluStage{kernel, iterations})
+		}
+	}
+	return stages
+}
+
+func getWindowsInfo() (string, string) {
+	return "Windows", "alu.csv"
+}
+
+func createWindow() *glfw.Window {
+	if err := glfw.Init(); err != nil {
+		panic(err)
+	}
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.Resizable, glfw.False)
+
+	// Create window
+	window, err := glfw.CreateWindow(windowWidth, windowHeight, "GLTest | Shader ALU", nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	window.MakeContextCurrent()
+
+	// Center
+	monitor := glfw.GetPrimaryMonitor()
+	if monitor == nil {
+		panic("Failed to get the main monitor")
+	}
+	mode := monitor.GetVideoMode()
+	if mode == nil {
+		panic("Failed to get the video mode of the monitor")
+	}
+
+	// Get coordinates
+	xPos := (mode.Width - windowWidth) / 2
+	yPos := (mode.Height - windowHeight) / 2
+	window.SetPos(xPos, yPos)
+
+	return window
+}
+
+func compileShader(shader uint32, source string) {
+	csource, free := gl.Strs(source + "\x00")
+	defer free()
+	gl.ShaderSource(shader, 1, csource, nil)
+	gl.CompileShader(shader)
+
+	var status int32
+	gl.GetShaderiv(shader, gl.COMPILE_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &logLength)
+		log := make([]byte, logLength)
+		gl.GetShaderInfoLog(shader, logLength, nil, &log[0])
+		panic(fmt.Errorf("failed to compile shader: %v", string(log)))
+	}
+}
+
+func notifyWindows(title, message string) {
+	user32 := syscall.NewLazyDLL("user32.dll")
+	messageBox := user32.NewProc("MessageBoxW")
+	titlePtr, _ := syscall.UTF16PtrFromString(title)
+	messagePtr, _ := syscall.UTF16PtrFromString(message)
+	messageBox.Call(0, uintptr(unsafe.Pointer(messagePtr)), uintptr(unsafe.Pointer(titlePtr)), 0)
+}
+
+func initGL() {
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+
+	// Full-screen triangle generated from gl_VertexID
+	vertexSource := `#version 410 core
+		void main() {
+			vec2 pos = vec2((gl_VertexID << 1) & 2, gl_VertexID & 2);
+			gl_Position = vec4(pos * 2.0 - 1.0, 0.0, 1.0);
+		}`
+
+	for _, kernel := range kernels {
+		// scale and bias are uniforms so the compiler cannot fold the loop away
+		fragmentSource := `#version 410 core
+			uniform int iterations;
+			uniform vec4 scale;
+			uniform vec4 bias;
+			out vec4 FragColor;
+
+			void main() {
+				vec4 a = fract(gl_FragCoord.xyxy * 0.001) + 0.1;
+				vec4 b = a + 0.1;
+				vec4 c = a + 0.2;
+				vec4 d = a + 0.3;
+				uvec4 ia = uvec4(gl_FragCoord.xyxy);
+				uvec4 ib = ia + 1u;
+				uvec4 ic = ia + 2u;
+				uvec4 id = ia + 3u;
+				for (int i = 0; i < iterations; ++i) {` + kernel.body + `
+				}
+				vec4 f = a + b + c + d;
+				uvec4 u = ia ^ ib ^ ic ^ id;
+				FragColor = fract(f + vec4(u & 255u) / 255.0);
+			}`
+
+		vertexShader := gl.CreateShader(gl.VERTEX_SHADER)
+		compileShader(vertexShader, vertexSource)
+		fragmentShader := gl.CreateShader(gl.FRAGMENT_SHADER)
+		compileShader(fragmentShader, fragmentSource)
+
+		kernel.program = gl.CreateProgram()
+		gl.AttachShader(kernel.program, vertexShader)
+		gl.AttachShader(kernel.program, fragmentShader)
+		gl.LinkProgram(kernel.program)
+
+		gl.DeleteShader(vertexShader)
+		gl.DeleteShader(fragmentShader)
+
+		gl.UseProgram(kernel.program)
+		kernel.iterationLoc = gl.GetUniformLocation(kernel.program, gl.Str("iterations\x00"))
+		gl.Uniform4f(gl.GetUniformLocation(kernel.program, gl.Str("scale\x00")), 0.9999, 0.9998, 0.9997, 0.9996)
+		gl.Uniform4f(gl.GetUniformLocation(kernel.program, gl.Str("bias\x00")), 0.0001, 0.0002, 0.0003, 0.0004)
+	}
+
+	// Core profile needs a bound VAO even for attribute-less draws
+	gl.GenVertexArrays(1, &quadVAO)
+	gpuTimer = testkit.NewGPUTimer(1)
+}
+
+func drawKernel(stage aluStage) {
+	gpuTimer.Begin("kernel")
+	gl.UseProgram(stage.kernel.program)
+	gl.Uniform1i(stage.kernel.iterationLoc, int32(stage.iterations))
+	gl.BindVertexArray(quadVAO)
+	gl.DrawArrays(gl.TRIANGLES, 0, 3)
+	gpuTimer.End()
+}
+
+// Operations per second in billions for one frame of the stage taking gpuTimeMs on the GPU
+func gigaOpsPerSecond(stage aluStage, gpuTimeMs float64) float64 {
+	if gpuTimeMs <= 0 {
+		return 0
+	}
+	ops := float64(windowWidth*windowHeight) * float64(stage.iterations) * float64(stage.kernel.opsPerLoop)
+	return ops / (gpuTimeMs / 1000) / 1e9
+}
+
+func checkGLError() {
+	if err := gl.GetError(); err != gl.NO_ERROR {
+		fmt.Printf("OpenGL error: %d\n", err)
+	}
+}
+
+func main() {
+	runtime.LockOSThread()
+	window := createWindow()
+	defer glfw.Terminate()
+	initGL()
+	gl.ClearColor(0, 0, 0, 1)
+
+	_, fileName := getWindowsInfo()
+	file, err := os.Create(fileName)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	writer.Write([]string{"Time (s)", "Stage", "Iterations", "Avg FPS", "Min FPS", "Kernel", "GPU Time (ms)", "Gops/s"})
+
+	// Warming
+	fmt.Println("Warming up...")
+	warmUpStart := time.Now()
+	for time.Since(warmUpStart).Seconds() < warmUpTime && !testkit.StopRequested() {
+		for _, kernel := range kernels {
+			drawKernel(aluStage{kernel, loopCounts[0]})
+			gpuTimer.EndFrame()
+		}
+		window.SwapBuffers()
+		glfw.PollEvents()
+		checkGLError()
+	}
+	gpuTimer.Reset()
+
+	// Main test
+	startTime = time.Now()
+	testStart := startTime
+	lastRecordTime := testStart
+	var frameTimes []float64
+	currentStage := 0
+	testDuration := float64(len(aluStages) * stageTime)
+
+	testkit.WatchStop()
+
+	// Announced for the progress display of the runner
+	fmt.Printf("Duration: %.0fs, Stages: %d\n", testDuration, len(aluStages))
+
+	for !window.ShouldClose() && !testkit.StopRequested() && time.Since(testStart).Seconds() < testDuration {
+		frameStart := time.Now()
+
+		drawKernel(aluStages[currentStage])
+		window.SwapBuffers()
+		glfw.PollEvents()
+		gpuTimer.EndFrame()
+		checkGLError()
+
+		frameTime := time.Since(frameStart).Seconds()
+		frameTimes = append(frameTimes, frameTime)
+
+		timeElapsed := time.Since(testStart).Seconds()
+		newStage := int(timeElapsed / stageTime)
+		if newStage != currentStage && newStage < len(aluStages) {
+			currentStage = newStage
+			frameTimes = nil
+			gpuTimer.Reset() // queries still in flight belong to the previous kernel
+			lastRecordTime = time.Now()
+			fmt.Printf("\nStarting stage %d: %s with %d iterations\n",
+				currentStage+1, aluStages[currentStage].kernel.name, aluStages[currentStage].iterations)
+			continue
+		}
+
+		if time.Since(lastRecordTime).Seconds() >= 0.5 && gpuTimer.Frames() > 0 {
+			var totalFrameTime float64
+			for _, ft := range frameTimes {
+				totalFrameTime += ft
+			}
+			avgFPS := float64(len(frameTimes)) / totalFrameTime
+			minFPS := 1.0 / maxFrameTime(frameTimes)
+			stage := aluStages[currentStage]
+			gpuTime, _ := gpuTimer.Mean()
+			gops := gigaOpsPerSecond(stage, gpuTime)
+
+			writer.Write([]string{
+				strconv.FormatFloat(timeElapsed, 'f', 1, 64),
+				strconv.Itoa(currentStage + 1),
+				strconv.Itoa(stage.iterations),
+				strconv.FormatFloat(avgFPS, 'f', 1, 64),
+				strconv.FormatFloat(minFPS, 'f', 1, 64),
+				stage.kernel.name,
+				strconv.FormatFloat(gpuTime, 'f', 3, 64),
+				strconv.FormatFloat(gops, 'f', 2, 64),
+			})
+			writer.Flush()
+
+			fmt.Printf("Time: %.1fs, Stage: %d, Iterations: %d, Avg FPS: %.1f, Min FPS: %.1f, Kernel: %s, Gops/s: %.2f\n",
+				timeElapsed, currentStage+1, stage.iterations, avgFPS, minFPS, stage.kernel.name, gops)
+
+			frameTimes = nil
+			gpuTimer.Reset()
+			lastRecordTime = time.Now()
+		}
+	}
+
+	//notifyWindows("Shader ALU Benchmark", "Test completed. Data saved in "+fileName)
+}
+
+func maxFrameTime(frameTimes []float64) float64 {
+	max := frameTimes[0]
+	for _, ft := range frameTimes {
+		if ft > max {
+			max = ft
+		}
+	}
+	return max
+}
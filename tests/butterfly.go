@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/csv"
+	"flag"
 	"fmt"
 	"math"
 	"math/rand"
@@ -14,6 +15,8 @@ import (
 
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/glfw/v3.3/glfw"
+
+	"moddergltest/testkit"
 )
 
 const (
@@ -276,6 +279,8 @@ func checkGLError() {
 }
 
 func main() {
+	flag.Parse()
+
 	runtime.LockOSThread()
 	window := createWindow()
 	defer glfw.Terminate()
@@ -293,11 +298,19 @@ func main() {
 
 	writer.Write([]string{"Time (s)", "Stage", "Particles", "Avg FPS", "Min FPS"})
 
+	stageCount, duration := testkit.Overrides(len(particleCounts), stageTime, testDuration)
+	particleCounts = particleCounts[:stageCount]
+
+	testkit.WatchStop()
+
+	// Announced for the progress display of the runner
+	fmt.Printf("Duration: %.0fs, Stages: %d\n", duration, len(particleCounts))
+
 	// Warming
 	fmt.Println("Warming up...")
 	warmUpStart := time.Now()
 	particles := createButterflyParticles(particleCounts[0])
-	for time.Since(warmUpStart).Seconds() < warmUpTime {
+	for time.Since(warmUpStart).Seconds() < warmUpTime && !testkit.StopRequested() {
 		gl.Clear(gl.COLOR_BUFFER_BIT)
 		drawParticles(particles, float32(time.Since(warmUpStart).Seconds()))
 		window.SwapBuffers()
@@ -314,7 +327,7 @@ func main() {
 
 	gl.Enable(gl.PROGRAM_POINT_SIZE)
 
-	for !window.ShouldClose() && time.Since(testStart).Seconds() < testDuration {
+	for !window.ShouldClose() && !testkit.StopRequested() && time.Since(testStart).Seconds() < duration {
 		frameStart := time.Now()
 
 		gl.Clear(gl.COLOR_BUFFER_BIT)
@@ -360,6 +373,11 @@ func main() {
 		}
 	}
 
+	// Screenshot of the last stage for the report, drawn once more outside the measured frames
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+	drawParticles(particles, float32(time.Since(startTime).Seconds()))
+	testkit.SaveScreenshot(window, "butterfly.png")
+
 	//notifyWindows(“Butterfly GPU Benchmark”, “Test completed. Data saved in ”+fileName)
 }
 
@@ -0,0 +1,541 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"moddergltest/testkit"
+)
+
+const (
+	windowWidth  = 1024
+	windowHeight = 768
+	warmUpTime   = 2
+)
+
+// manifest.json of a custom test directory
+type Manifest struct {
+	Name      string                 `json:"name"`
+	Title     string                 `json:"title"`
+	Vertex    string                 `json:"vertex"`
+	Fragment  string                 `json:"fragment"`
+	Geometry  string                 `json:"geometry"`  // fullscreen, grid or points
+	Size      int                    `json:"size"`      // grid side or point count
+	Uniforms  map[string]interface{} `json:"uniforms"`  // numbers or arrays of 2-4 numbers
+	Stage     StageParameter         `json:"stage"`     // uniform changed per stage
+	StageTime float64                `json:"stageTime"` // seconds per stage
+	Load      string                 `json:"load"`      // stage, vertices or pixels
+	LoadLabel string                 `json:"loadLabel"`
+	Normalize float64                `json:"normalize"`
+}
+
+type StageParameter struct {
+	Uniform string    `json:"uniform"`
+	Values  []float64 `json:"values"`
+}
+
+var (
+	vao, vbo, ebo uint32
+	shaderProgram uint32
+	vertexCount   int32
+	indexCount    int32
+	uniformTypes  = map[string]uint32{}
+	startTime     time.Time
+	manifest      Manifest
+)
+
+// Attribute-less full-screen triangle, used when a fullscreen test has no vertex shader
+const fullscreenVertexSource = `#version 410 core
+	out vec2 uv;
+	void main() {
+		vec2 pos = vec2((gl_VertexID << 1) & 2, gl_VertexID & 2);
+		uv = pos;
+		gl_Position = vec4(pos * 2.0 - 1.0, 0.0, 1.0);
+	}`
+
+func getWindowsInfo() (string, string) {
+	return "Windows", manifest.Name + ".csv"
+}
+
+func createWindow(title string) *glfw.Window {
+	if err := glfw.Init(); err != nil {
+		panic(err)
+	}
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.Resizable, glfw.False)
+
+	// Create window
+	window, err := glfw.CreateWindow(windowWidth, windowHeight, "GLTest | "+title, nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	window.MakeContextCurrent()
+
+	// Center
+	monitor := glfw.GetPrimaryMonitor()
+	if monitor == nil {
+		panic("Failed to get the main monitor")
+	}
+	mode := monitor.GetVideoMode()
+	if mode == nil {
+		panic("Failed to get the video mode of the monitor")
+	}
+
+	// Get coordinates
+	xPos := (mode.Width - windowWidth) / 2
+	yPos := (mode.Height - windowHeight) / 2
+	window.SetPos(xPos, yPos)
+
+	return window
+}
+
+// Shaders come from users, so a compile error is returned with the info log instead of panicking
+func compileShader(shader uint32, source string) error {
+	csource, free := gl.Strs(source + "\x00")
+	defer free()
+	gl.ShaderSource(shader, 1, csource, nil)
+	gl.CompileShader(shader)
+
+	var status int32
+	gl.GetShaderiv(shader, gl.COMPILE_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &logLength)
+		log := make([]byte, logLength+1)
+		gl.GetShaderInfoLog(shader, logLength, nil, &log[0])
+		return fmt.Errorf("failed to compile shader: %v", gl.GoStr(&log[0]))
+	}
+	return nil
+}
+
+func notifyWindows(title, message string) {
+	user32 := syscall.NewLazyDLL("user32.dll")
+	messageBox := user32.NewProc("MessageBoxW")
+	titlePtr, _ := syscall.UTF16PtrFromString(title)
+	messagePtr, _ := syscall.UTF16PtrFromString(message)
+	messageBox.Call(0, uintptr(unsafe.Pointer(messagePtr)), uintptr(unsafe.Pointer(titlePtr)), 0)
+}
+
+func loadManifest(dir string) error {
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("invalid manifest: %v", err)
+	}
+
+	if manifest.Name == "" {
+		manifest.Name = filepath.Base(dir)
+	}
+	if manifest.Title == "" {
+		manifest.Title = manifest.Name
+	}
+	if manifest.Geometry == "" {
+		manifest.Geometry = "fullscreen"
+	}
+	if manifest.Fragment == "" {
+		manifest.Fragment = "fragment.glsl"
+	}
+	if manifest.Vertex == "" && manifest.Geometry != "fullscreen" {
+		manifest.Vertex = "vertex.glsl"
+	}
+	if manifest.StageTime <= 0 {
+		manifest.StageTime = 10
+	}
+	if manifest.Load == "" {
+		manifest.Load = "stage"
+	}
+	if manifest.LoadLabel == "" {
+		manifest.LoadLabel = "Load"
+	}
+
+	switch {
+	case manifest.Geometry != "fullscreen" && manifest.Geometry != "grid" && manifest.Geometry != "points":
+		return fmt.Errorf("unknown geometry %q", manifest.Geometry)
+	case manifest.Load != "stage" && manifest.Load != "vertices" && manifest.Load != "pixels":
+		return fmt.Errorf("unknown load metric %q", manifest.Load)
+	case manifest.Stage.Uniform == "" || len(manifest.Stage.Values) == 0:
+		return fmt.Errorf("manifest needs a stage uniform with at least one value")
+	}
+	return nil
+}
+
+func readShader(dir, name string) (string, error) {
+	if name == "" {
+		return fullscreenVertexSource, nil
+	}
+	source, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	return string(source), nil
+}
+
+func createGeometry() {
+	gl.GenVertexArrays(1, &vao)
+	gl.BindVertexArray(vao)
+
+	size := manifest.Size
+	var vertices []float32
+	switch manifest.Geometry {
+	case "fullscreen":
+		vertexCount = 3
+		return
+	case "grid":
+		if size < 2 {
+			size = 256
+		}
+		// Grid on the XZ plane, same layout as the ocean test
+		vertices = make([]float32, 0, size*size*3)
+		indices := make([]uint32, 0, (size-1)*(size-1)*6)
+		for z := 0; z < size; z++ {
+			for x := 0; x < size; x++ {
+				nx := float32(x)/float32(size-1) - 0.5
+				nz := float32(z)/float32(size-1) - 0.5
+				vertices = append(vertices, nx*10, 0, nz*10)
+			}
+		}
+		for z := 0; z < size-1; z++ {
+			for x := 0; x < size-1; x++ {
+				topLeft := uint32(z*size + x)
+				bottomLeft := uint32((z+1)*size + x)
+				indices = append(indices, topLeft, bottomLeft, topLeft+1)
+				indices = append(indices, topLeft+1, bottomLeft, bottomLeft+1)
+			}
+		}
+		gl.GenBuffers(1, &ebo)
+		gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, ebo)
+		gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, len(indices)*4, gl.Ptr(indices), gl.STATIC_DRAW)
+		indexCount = int32(len(indices))
+	case "points":
+		if size < 1 {
+			size = 100000
+		}
+		vertices = make([]float32, size*3)
+		for i := range vertices {
+			vertices[i] = rand.Float32()*2 - 1
+		}
+	}
+	vertexCount = int32(len(vertices) / 3)
+
+	gl.GenBuffers(1, &vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(vertices)*4, gl.Ptr(vertices), gl.STATIC_DRAW)
+	gl.EnableVertexAttribArray(0)
+	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 3*4, gl.PtrOffset(0))
+}
+
+func initGL(dir string) error {
+	if err := gl.Init(); err != nil {
+		return err
+	}
+
+	vertexSource, err := readShader(dir, manifest.Vertex)
+	if err != nil {
+		return err
+	}
+	fragmentSource, err := readShader(dir, manifest.Fragment)
+	if err != nil {
+		return err
+	}
+
+	vertexShader := gl.CreateShader(gl.VERTEX_SHADER)
+	defer gl.DeleteShader(vertexShader)
+	if err := compileShader(vertexShader, vertexSource); err != nil {
+		return fmt.Errorf("%s: %v", manifest.Vertex, err)
+	}
+	fragmentShader := gl.CreateShader(gl.FRAGMENT_SHADER)
+	defer gl.DeleteShader(fragmentShader)
+	if err := compileShader(fragmentShader, fragmentSource); err != nil {
+		return fmt.Errorf("%s: %v", manifest.Fragment, err)
+	}
+
+	shaderProgram = gl.CreateProgram()
+	gl.AttachShader(shaderProgram, vertexShader)
+	gl.AttachShader(shaderProgram, fragmentShader)
+	gl.LinkProgram(shaderProgram)
+
+	var status int32
+	gl.GetProgramiv(shaderProgram, gl.LINK_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetProgramiv(shaderProgram, gl.INFO_LOG_LENGTH, &logLength)
+		log := make([]byte, logLength+1)
+		gl.GetProgramInfoLog(shaderProgram, logLength, nil, &log[0])
+		return fmt.Errorf("failed to link program: %v", gl.GoStr(&log[0]))
+	}
+
+	// Remember uniform types so manifest values go through the matching glUniform call
+	var count int32
+	gl.GetProgramiv(shaderProgram, gl.ACTIVE_UNIFORMS, &count)
+	for i := int32(0); i < count; i++ {
+		var length, size int32
+		var xtype uint32
+		name := make([]byte, 256)
+		gl.GetActiveUniform(shaderProgram, uint32(i), int32(len(name)), &length, &size, &xtype, &name[0])
+		uniformTypes[string(name[:length])] = xtype
+	}
+
+	gl.UseProgram(shaderProgram)
+	for name, value := range manifest.Uniforms {
+		if _, ok := uniformTypes[name]; !ok {
+			fmt.Printf("Warning: uniform %q is not used by the shaders\n", name)
+			continue
+		}
+		if err := setUniform(name, value); err != nil {
+			return err
+		}
+	}
+	if _, ok := uniformTypes[manifest.Stage.Uniform]; !ok {
+		return fmt.Errorf("stage uniform %q is not used by the shaders", manifest.Stage.Uniform)
+	}
+
+	createGeometry()
+	return nil
+}
+
+func setUniform(name string, value interface{}) error {
+	xtype, ok := uniformTypes[name]
+	if !ok {
+		return fmt.Errorf("uniform %q is not used by the shaders", name)
+	}
+	location := gl.GetUniformLocation(shaderProgram, gl.Str(name+"\x00"))
+
+	var v []float32
+	switch value := value.(type) {
+	case float64:
+		v = []float32{float32(value)}
+	case []interface{}:
+		for _, component := range value {
+			f, ok := component.(float64)
+			if !ok {
+				return fmt.Errorf("uniform %q: %v is not a number", name, component)
+			}
+			v = append(v, float32(f))
+		}
+	default:
+		return fmt.Errorf("uniform %q: unsupported value %v", name, value)
+	}
+
+	components := map[uint32]int{
+		gl.FLOAT: 1, gl.FLOAT_VEC2: 2, gl.FLOAT_VEC3: 3, gl.FLOAT_VEC4: 4,
+		gl.INT: 1, gl.BOOL: 1, gl.UNSIGNED_INT: 1,
+	}
+	if n, ok := components[xtype]; !ok {
+		return fmt.Errorf("uniform %q: unsupported type 0x%x", name, xtype)
+	} else if len(v) != n {
+		return fmt.Errorf("uniform %q needs %d values, got %d", name, n, len(v))
+	}
+
+	switch xtype {
+	case gl.FLOAT:
+		gl.Uniform1f(location, v[0])
+	case gl.FLOAT_VEC2:
+		gl.Uniform2f(location, v[0], v[1])
+	case gl.FLOAT_VEC3:
+		gl.Uniform3f(location, v[0], v[1], v[2])
+	case gl.FLOAT_VEC4:
+		gl.Uniform4f(location, v[0], v[1], v[2], v[3])
+	case gl.INT, gl.BOOL:
+		gl.Uniform1i(location, int32(v[0]))
+	case gl.UNSIGNED_INT:
+		gl.Uniform1ui(location, uint32(v[0]))
+	}
+	return nil
+}
+
+// Built-in uniforms, set when the shaders declare them: time, resolution and mvp
+func setBuiltinUniforms(currentTime float32) {
+	if _, ok := uniformTypes["time"]; ok {
+		gl.Uniform1f(gl.GetUniformLocation(shaderProgram, gl.Str("time\x00")), currentTime)
+	}
+	if _, ok := uniformTypes["resolution"]; ok {
+		gl.Uniform2f(gl.GetUniformLocation(shaderProgram, gl.Str("resolution\x00")), windowWidth, windowHeight)
+	}
+	if _, ok := uniformTypes["mvp"]; ok {
+		projection := mgl32.Perspective(mgl32.DegToRad(45.0), float32(windowWidth)/windowHeight, 0.1, 100.0)
+		view := mgl32.LookAtV(mgl32.Vec3{-15, 5, 0}, mgl32.Vec3{0, 0, 0}, mgl32.Vec3{0, 1, 0})
+		mvp := projection.Mul4(view)
+		gl.UniformMatrix4fv(gl.GetUniformLocation(shaderProgram, gl.Str("mvp\x00")), 1, false, &mvp[0])
+	}
+}
+
+func drawCustom(currentTime float32, stageValue float64) {
+	gl.UseProgram(shaderProgram)
+	setBuiltinUniforms(currentTime)
+	setUniform(manifest.Stage.Uniform, stageValue)
+
+	gl.BindVertexArray(vao)
+	switch manifest.Geometry {
+	case "grid":
+		gl.Enable(gl.DEPTH_TEST)
+		gl.DrawElements(gl.TRIANGLES, indexCount, gl.UNSIGNED_INT, gl.PtrOffset(0))
+	case "points":
+		gl.Enable(gl.PROGRAM_POINT_SIZE)
+		gl.DrawArrays(gl.POINTS, 0, vertexCount)
+	default:
+		gl.DrawArrays(gl.TRIANGLES, 0, vertexCount)
+	}
+}
+
+// Load of a stage: the stage value, optionally multiplied by vertices or pixels per frame
+func stageLoad(stageValue float64) float64 {
+	switch manifest.Load {
+	case "vertices":
+		return stageValue * float64(vertexCount)
+	case "pixels":
+		return stageValue * windowWidth * windowHeight
+	}
+	return stageValue
+}
+
+func checkGLError() {
+	if err := gl.GetError(); err != gl.NO_ERROR {
+		fmt.Printf("OpenGL error: %d\n", err)
+	}
+}
+
+// Report a broken test on stderr, where the runner picks it up
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "%s: %v\n", manifest.Name, err)
+	os.Exit(1)
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: custom [-duration seconds] [-max-stage n] <test directory>")
+		os.Exit(2)
+	}
+	dir := flag.Arg(0)
+	if err := loadManifest(dir); err != nil {
+		fail(err)
+	}
+
+	runtime.LockOSThread()
+	window := createWindow(manifest.Title)
+	defer glfw.Terminate()
+	if err := initGL(dir); err != nil {
+		glfw.Terminate()
+		fail(err)
+	}
+	gl.ClearColor(0.1, 0.1, 0.1, 1.0)
+
+	_, fileName := getWindowsInfo()
+	file, err := os.Create(fileName)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	writer.Write([]string{"Time (s)", "Stage", manifest.LoadLabel, "Avg FPS", "Min FPS"})
+
+	stages := manifest.Stage.Values
+	stageCount, testDuration := testkit.Overrides(len(stages), manifest.StageTime, manifest.StageTime*float64(len(stages)))
+	stages = stages[:stageCount]
+
+	testkit.WatchStop()
+
+	// Announced for the progress display of the runner
+	fmt.Printf("Duration: %.0fs, Stages: %d\n", testDuration, len(stages))
+
+	// Warming
+	fmt.Println("Warming up...")
+	warmUpStart := time.Now()
+	for time.Since(warmUpStart).Seconds() < warmUpTime && !testkit.StopRequested() {
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+		drawCustom(float32(time.Since(warmUpStart).Seconds()), stages[0])
+		window.SwapBuffers()
+		glfw.PollEvents()
+		checkGLError()
+	}
+
+	// Main test
+	startTime = time.Now()
+	testStart := startTime
+	lastRecordTime := testStart
+	var frameTimes []float64
+	currentStage := 0
+
+	for !window.ShouldClose() && !testkit.StopRequested() && time.Since(testStart).Seconds() < testDuration {
+		frameStart := time.Now()
+
+		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+		currentTime := float32(time.Since(startTime).Seconds())
+		drawCustom(currentTime, stages[currentStage])
+		window.SwapBuffers()
+		glfw.PollEvents()
+		checkGLError()
+
+		frameTime := time.Since(frameStart).Seconds()
+		frameTimes = append(frameTimes, frameTime)
+
+		timeElapsed := time.Since(testStart).Seconds()
+		newStage := int(timeElapsed / manifest.StageTime)
+		if newStage != currentStage && newStage < len(stages) {
+			currentStage = newStage
+			fmt.Printf("\nStarting stage %d with %s %g\n", currentStage+1, manifest.Stage.Uniform, stages[currentStage])
+		}
+
+		if time.Since(lastRecordTime).Seconds() >= 0.5 {
+			var totalFrameTime float64
+			for _, ft := range frameTimes {
+				totalFrameTime += ft
+			}
+			avgFPS := float64(len(frameTimes)) / totalFrameTime
+			minFPS := 1.0 / maxFrameTime(frameTimes)
+			load := stageLoad(stages[currentStage])
+
+			writer.Write([]string{
+				strconv.FormatFloat(timeElapsed, 'f', 1, 64),
+				strconv.Itoa(currentStage + 1),
+				strconv.FormatFloat(load, 'f', -1, 64),
+				strconv.FormatFloat(avgFPS, 'f', 1, 64),
+				strconv.FormatFloat(minFPS, 'f', 1, 64),
+			})
+			writer.Flush()
+
+			fmt.Printf("Time: %.1fs, Stage: %d, %s: %g, Avg FPS: %.1f, Min FPS: %.1f\n",
+				timeElapsed, currentStage+1, manifest.LoadLabel, load, avgFPS, minFPS)
+
+			frameTimes = nil
+			lastRecordTime = time.Now()
+		}
+	}
+
+	// Screenshot of the last stage for the report, drawn once more outside the measured frames
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	drawCustom(float32(time.Since(startTime).Seconds()), stages[currentStage])
+	testkit.SaveScreenshot(window, manifest.Name+".png")
+
+	//notifyWindows(manifest.Title, "Test completed. Data saved in "+fileName)
+}
+
+func maxFrameTime(frameTimes []float64) float64 {
+	max := frameTimes[0]
+	for _, ft := range frameTimes {
+		if ft > max {
+			max = ft
+		}
+	}
+	return max
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+
+	"moddergltest/testkit"
+)
+
+const (
+	windowWidth  = 1024
+	windowHeight = 768
+	minProbeTime = 0.25  // seconds of transfers per bandwidth measurement
+	chunkSize    = 4096  // VRAM chunks are chunkSize x chunkSize RGBA8 textures (64 MB)
+	maxProbeMB   = 65536 // stop allocating here if neither VRAM nor RAM size is known
+	sampleChunks = 8     // earlier chunks cleared again with each new one
+	cliffFactor  = 4.0   // per-MB clear time this many times the baseline means memory spilled
+)
+
+// Vendor extensions reporting video memory directly, in KB
+const (
+	GPU_MEMORY_INFO_DEDICATED_VIDMEM_NVX = 0x9047
+	TEXTURE_FREE_MEMORY_ATI              = 0x87FC
+)
+
+var (
+	transferSizesMB = []int{1, 4, 16, 64, 256}
+	chunkMB         = chunkSize * chunkSize * 4 / (1024 * 1024)
+)
+
+func getWindowsInfo() (string, string) {
+	return "Windows", "memory.csv"
+}
+
+func createWindow() *glfw.Window {
+	if err := glfw.Init(); err != nil {
+		panic(err)
+	}
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.Resizable, glfw.False)
+
+	// Create window
+	window, err := glfw.CreateWindow(windowWidth, windowHeight, "GLTest | Memory", nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	window.MakeContextCurrent()
+
+	// Center
+	monitor := glfw.GetPrimaryMonitor()
+	if monitor == nil {
+		panic("Failed to get the main monitor")
+	}
+	mode := monitor.GetVideoMode()
+	if mode == nil {
+		panic("Failed to get the video mode of the monitor")
+	}
+
+	// Get coordinates
+	xPos := (mode.Width - windowWidth) / 2
+	yPos := (mode.Height - windowHeight) / 2
+	window.SetPos(xPos, yPos)
+
+	return window
+}
+
+func notifyWindows(title, message string) {
+	user32 := syscall.NewLazyDLL("user32.dll")
+	messageBox := user32.NewProc("MessageBoxW")
+	titlePtr, _ := syscall.UTF16PtrFromString(title)
+	messagePtr, _ := syscall.UTF16PtrFromString(message)
+	messageBox.Call(0, uintptr(unsafe.Pointer(messagePtr)), uintptr(unsafe.Pointer(titlePtr)), 0)
+}
+
+func hasExtension(name string) bool {
+	var count int32
+	gl.GetIntegerv(gl.NUM_EXTENSIONS, &count)
+	for i := int32(0); i < count; i++ {
+		if gl.GoStr(gl.GetStringi(gl.EXTENSIONS, uint32(i))) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Strings, limits and extensions of the GL implementation, written to glcaps.csv for the report
+func writeCapabilities(fileName string) {
+	file, err := os.Create(fileName)
+	if err != nil {
+		fmt.Printf("Failed to write capabilities: %v\n", err)
+		return
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	writer.Write([]string{"Capability", "Value"})
+	for _, s := range []struct {
+		name  string
+		value uint32
+	}{
+		{"vendor", gl.VENDOR},
+		{"renderer", gl.RENDERER},
+		{"version", gl.VERSION},
+		{"glsl_version", gl.SHADING_LANGUAGE_VERSION},
+	} {
+		writer.Write([]string{s.name, strings.TrimSpace(gl.GoStr(gl.GetString(s.value)))})
+	}
+
+	for _, limit := range []struct {
+		name  string
+		value uint32
+	}{
+		{"max_texture_size", gl.MAX_TEXTURE_SIZE},
+		{"max_3d_texture_size", gl.MAX_3D_TEXTURE_SIZE},
+		{"max_array_texture_layers", gl.MAX_ARRAY_TEXTURE_LAYERS},
+		{"max_renderbuffer_size", gl.MAX_RENDERBUFFER_SIZE},
+		{"max_viewport_dims", gl.MAX_VIEWPORT_DIMS},
+		{"max_samples", gl.MAX_SAMPLES},
+		{"max_color_attachments", gl.MAX_COLOR_ATTACHMENTS},
+		{"max_draw_buffers", gl.MAX_DRAW_BUFFERS},
+		{"max_vertex_attribs", gl.MAX_VERTEX_ATTRIBS},
+		{"max_texture_image_units", gl.MAX_TEXTURE_IMAGE_UNITS},
+		{"max_combined_texture_image_units", gl.MAX_COMBINED_TEXTURE_IMAGE_UNITS},
+		{"max_uniform_block_size", gl.MAX_UNIFORM_BLOCK_SIZE},
+		{"max_fragment_uniform_components", gl.MAX_FRAGMENT_UNIFORM_COMPONENTS},
+		{"max_geometry_output_vertices", gl.MAX_GEOMETRY_OUTPUT_VERTICES},
+		{"max_transform_feedback_separate_components", gl.MAX_TRANSFORM_FEEDBACK_SEPARATE_COMPONENTS},
+	} {
+		// MAX_VIEWPORT_DIMS returns two values
+		values := [2]int32{-1, -1}
+		gl.GetIntegerv(limit.value, &values[0])
+		value := strconv.Itoa(int(values[0]))
+		if limit.value == gl.MAX_VIEWPORT_DIMS {
+			value += "x" + strconv.Itoa(int(values[1]))
+		}
+		writer.Write([]string{limit.name, value})
+	}
+
+	var count int32
+	gl.GetIntegerv(gl.NUM_EXTENSIONS, &count)
+	extensions := make([]string, 0, count)
+	for i := int32(0); i < count; i++ {
+		extensions = append(extensions, gl.GoStr(gl.GetStringi(gl.EXTENSIONS, uint32(i))))
+	}
+	sort.Strings(extensions)
+	writer.Write([]string{"extensions", strings.Join(extensions, " ")})
+	checkGLError()
+}
+
+// Keep the window responsive between probes and show which one is running
+func showProgress(window *glfw.Window, r, g, b float32) {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	gl.Viewport(0, 0, windowWidth, windowHeight)
+	gl.ClearColor(r, g, b, 1.0)
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+	window.SwapBuffers()
+	glfw.PollEvents()
+}
+
+// Repeat a transfer of sizeMB until minProbeTime has passed and return MB/s and ms per transfer
+func measureTransfer(sizeMB int, transfer func()) (float64, float64) {
+	transfer() // first call pays for allocation and mapping
+	gl.Finish()
+
+	iterations := 0
+	start := time.Now()
+	for time.Since(start).Seconds() < minProbeTime || iterations < 3 {
+		transfer()
+		iterations++
+	}
+	gl.Finish()
+	elapsed := time.Since(start).Seconds()
+
+	return float64(sizeMB*iterations) / elapsed, elapsed * 1000 / float64(iterations)
+}
+
+func probeBandwidth(window *glfw.Window, writer *csv.Writer) {
+	var buffers [2]uint32
+	gl.GenBuffers(2, &buffers[0])
+	defer gl.DeleteBuffers(2, &buffers[0])
+
+	for _, sizeMB := range transferSizesMB {
+		if testkit.StopRequested() {
+			return
+		}
+		size := sizeMB * 1024 * 1024
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i)
+		}
+
+		gl.BindBuffer(gl.COPY_READ_BUFFER, buffers[0])
+		gl.BufferData(gl.COPY_READ_BUFFER, size, nil, gl.STREAM_DRAW)
+		gl.BindBuffer(gl.COPY_WRITE_BUFFER, buffers[1])
+		gl.BufferData(gl.COPY_WRITE_BUFFER, size, nil, gl.STREAM_COPY)
+
+		probes := []struct {
+			name     string
+			transfer func()
+		}{
+			{"upload", func() { gl.BufferSubData(gl.COPY_READ_BUFFER, 0, size, gl.Ptr(data)) }},
+			{"download", func() { gl.GetBufferSubData(gl.COPY_READ_BUFFER, 0, size, gl.Ptr(data)) }},
+			{"copy", func() { gl.CopyBufferSubData(gl.COPY_READ_BUFFER, gl.COPY_WRITE_BUFFER, 0, 0, size) }},
+		}
+		for _, probe := range probes {
+			showProgress(window, 0.1, 0.1, 0.2)
+			bandwidth, ms := measureTransfer(sizeMB, probe.transfer)
+			checkGLError()
+
+			writer.Write([]string{
+				probe.name,
+				strconv.Itoa(sizeMB),
+				strconv.FormatFloat(ms, 'f', 3, 64),
+				strconv.FormatFloat(bandwidth, 'f', 1, 64),
+				"",
+			})
+			writer.Flush()
+
+			fmt.Printf("Probe: %s, Size: %d MB, Time: %.3f ms, Bandwidth: %.1f MB/s\n", probe.name, sizeMB, ms, bandwidth)
+		}
+	}
+}
+
+// Allocate 64 MB textures up to limitMB, clearing each new one and a rotating sample of the
+// earlier ones. Once the driver starts evicting to system memory the clear time per MB jumps,
+// and that is taken as usable VRAM.
+func probeVRAM(window *glfw.Window, writer *csv.Writer, limitMB int) {
+	var fbo uint32
+	gl.GenFramebuffers(1, &fbo)
+	var textures []uint32
+	defer func() {
+		gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+		gl.DeleteFramebuffers(1, &fbo)
+		if len(textures) > 0 {
+			gl.DeleteTextures(int32(len(textures)), &textures[0])
+		}
+	}()
+
+	var perMB []float64
+	reason := "probe limit"
+	for len(textures)*chunkMB+chunkMB <= limitMB {
+		var texture uint32
+		gl.GenTextures(1, &texture)
+		gl.BindTexture(gl.TEXTURE_2D, texture)
+		gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, chunkSize, chunkSize, 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+		if err := gl.GetError(); err == gl.OUT_OF_MEMORY {
+			gl.DeleteTextures(1, &texture)
+			reason = "out of memory"
+			break
+		}
+		textures = append(textures, texture)
+
+		// Touch the new allocation and a sample of the earlier ones so they have to be resident,
+		// the sample moves on with each allocation so evicted chunks are found sooner or later
+		touched := sampleTextures(textures)
+		gl.BindFramebuffer(gl.FRAMEBUFFER, fbo)
+		gl.Viewport(0, 0, chunkSize, chunkSize)
+		start := time.Now()
+		for i, t := range touched {
+			gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, t, 0)
+			gl.ClearColor(float32(i%7)/7, float32(len(textures)%5)/5, 0.5, 1.0)
+			gl.Clear(gl.COLOR_BUFFER_BIT)
+		}
+		gl.Finish()
+		elapsed := time.Since(start).Seconds()
+		if err := gl.GetError(); err == gl.OUT_OF_MEMORY {
+			reason = "out of memory"
+			textures = textures[:len(textures)-1]
+			gl.DeleteTextures(1, &texture)
+			break
+		}
+
+		allocatedMB := len(textures) * chunkMB
+		touchedMB := len(touched) * chunkMB
+		msPerMB := elapsed * 1000 / float64(touchedMB)
+		perMB = append(perMB, msPerMB)
+		writer.Write([]string{
+			"vram",
+			strconv.Itoa(allocatedMB),
+			strconv.FormatFloat(elapsed*1000, 'f', 3, 64),
+			strconv.FormatFloat(float64(touchedMB)/elapsed, 'f', 1, 64),
+			"",
+		})
+		writer.Flush()
+		fmt.Printf("Probe: vram, Allocated: %d MB, Clear: %.3f ms\n", allocatedMB, elapsed*1000)
+
+		if baseline := baselineMsPerMB(perMB); baseline > 0 && msPerMB > baseline*cliffFactor {
+			reason = "performance cliff"
+			textures = textures[:len(textures)-1]
+			gl.DeleteTextures(1, &texture)
+			break
+		}
+
+		showProgress(window, 0.2, 0.1, 0.1)
+		if window.ShouldClose() || testkit.StopRequested() {
+			reason = "cancelled"
+			break
+		}
+	}
+
+	estimateMB := len(textures) * chunkMB
+	writer.Write([]string{"vram_estimate", strconv.Itoa(estimateMB), "", "", reason})
+	writer.Flush()
+	fmt.Printf("Estimated usable VRAM: %d MB (%s)\n", estimateMB, reason)
+}
+
+// The newest texture and up to sampleChunks earlier ones, spread over all of them and shifted
+// by one with every allocation
+func sampleTextures(textures []uint32) []uint32 {
+	last := len(textures) - 1
+	sample := []uint32{textures[last]}
+	if last <= sampleChunks {
+		return append(sample, textures[:last]...)
+	}
+	step := last / sampleChunks
+	for i := 0; i < sampleChunks; i++ {
+		sample = append(sample, textures[i*step+last%step])
+	}
+	return sample
+}
+
+// Fastest clear seen before the latest one, once there are enough measurements to trust
+func baselineMsPerMB(perMB []float64) float64 {
+	const samples = 4
+	if len(perMB) <= samples {
+		return 0
+	}
+	previous := append([]float64(nil), perMB[:len(perMB)-1]...)
+	sort.Float64s(previous)
+	return previous[0]
+}
+
+// Dedicated and free memory in MB as reported by vendor extensions, 0 for what they do not
+// report: GL_NVX_gpu_memory_info has the dedicated size, GL_ATI_meminfo only the free memory
+func driverReportedVRAM(writer *csv.Writer) (int, int) {
+	var kb [4]int32
+	switch {
+	case hasExtension("GL_NVX_gpu_memory_info"):
+		gl.GetIntegerv(GPU_MEMORY_INFO_DEDICATED_VIDMEM_NVX, &kb[0])
+		writer.Write([]string{"vram_driver", strconv.Itoa(int(kb[0] / 1024)), "", "", "GL_NVX_gpu_memory_info dedicated"})
+		writer.Flush()
+		checkGLError()
+		return int(kb[0] / 1024), 0
+	case hasExtension("GL_ATI_meminfo"):
+		gl.GetIntegerv(TEXTURE_FREE_MEMORY_ATI, &kb[0])
+		writer.Write([]string{"vram_driver_free", strconv.Itoa(int(kb[0] / 1024)), "", "", "GL_ATI_meminfo free"})
+		writer.Flush()
+		checkGLError()
+		return 0, int(kb[0] / 1024)
+	}
+	return 0, 0
+}
+
+// Physical memory in MB from GlobalMemoryStatusEx, 0 if it cannot be read
+func systemMemoryMB() int {
+	var status struct {
+		length               uint32
+		memoryLoad           uint32
+		totalPhys            uint64
+		availPhys            uint64
+		totalPageFile        uint64
+		availPageFile        uint64
+		totalVirtual         uint64
+		availVirtual         uint64
+		availExtendedVirtual uint64
+	}
+	status.length = uint32(unsafe.Sizeof(status))
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	globalMemoryStatus := kernel32.NewProc("GlobalMemoryStatusEx")
+	if ok, _, _ := globalMemoryStatus.Call(uintptr(unsafe.Pointer(&status))); ok == 0 {
+		return 0
+	}
+	return int(status.totalPhys / (1024 * 1024))
+}
+
+// The most the VRAM probe may allocate: the driver's VRAM size, or its free VRAM, when it
+// reports one, otherwise half the system memory, which an integrated GPU shares with everything else
+func probeLimitMB(driverMB, systemMB int) int {
+	switch {
+	case driverMB > 0:
+		return min(driverMB, maxProbeMB)
+	case systemMB > 0:
+		return min(systemMB/2, maxProbeMB)
+	}
+	return maxProbeMB
+}
+
+func checkGLError() {
+	if err := gl.GetError(); err != gl.NO_ERROR {
+		fmt.Printf("OpenGL error: %d\n", err)
+	}
+}
+
+func main() {
+	runtime.LockOSThread()
+	window := createWindow()
+	defer glfw.Terminate()
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+
+	_, fileName := getWindowsInfo()
+	file, err := os.Create(fileName)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	writer.Write([]string{"Probe", "Size (MB)", "Time (ms)", "Bandwidth (MB/s)", "Note"})
+
+	renderer := gl.GoStr(gl.GetString(gl.RENDERER))
+	fmt.Printf("Renderer: %s\n", strings.TrimSpace(renderer))
+
+	testkit.WatchStop()
+	writeCapabilities("glcaps.csv")
+	driverMB, freeMB := driverReportedVRAM(writer)
+	probeBandwidth(window, writer)
+	probeVRAM(window, writer, probeLimitMB(max(driverMB, freeMB), systemMemoryMB()))
+
+	//notifyWindows("Memory Probe", "Probe completed. Data saved in "+fileName)
+}
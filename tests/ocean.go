@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/csv"
+	"flag"
 	"fmt"
 	"os"
 	"runtime"
@@ -13,6 +14,8 @@ import (
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/glfw/v3.3/glfw"
 	"github.com/go-gl/mathgl/mgl32"
+
+	"moddergltest/testkit"
 )
 
 const (
@@ -249,6 +252,8 @@ func checkGLError() {
 }
 
 func main() {
+	flag.Parse()
+
 	runtime.LockOSThread()
 	window := createWindow()
 	defer glfw.Terminate()
@@ -266,11 +271,19 @@ func main() {
 
 	writer.Write([]string{"Time (s)", "Stage", "Wave Octaves", "Avg FPS", "Min FPS"})
 
+	stageCount, duration := testkit.Overrides(len(waveStages), stageTime, testDuration)
+	waveStages = waveStages[:stageCount]
+
+	testkit.WatchStop()
+
+	// Announced for the progress display of the runner
+	fmt.Printf("Duration: %.0fs, Stages: %d\n", duration, len(waveStages))
+
 	// Warming
 	fmt.Println("Warming up...")
 	warmUpStart := time.Now()
 	vertices, indices := createOceanGrid()
-	for time.Since(warmUpStart).Seconds() < warmUpTime {
+	for time.Since(warmUpStart).Seconds() < warmUpTime && !testkit.StopRequested() {
 		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
 		drawOcean(vertices, indices, float32(time.Since(warmUpStart).Seconds()), waveStages[0])
 		window.SwapBuffers()
@@ -285,7 +298,7 @@ func main() {
 	var frameTimes []float64
 	currentStage := 0
 
-	for !window.ShouldClose() && time.Since(testStart).Seconds() < testDuration {
+	for !window.ShouldClose() && !testkit.StopRequested() && time.Since(testStart).Seconds() < duration {
 		frameStart := time.Now()
 
 		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
@@ -330,6 +343,11 @@ func main() {
 		}
 	}
 
+	// Screenshot of the last stage for the report, drawn once more outside the measured frames
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	drawOcean(vertices, indices, float32(time.Since(startTime).Seconds()), waveStages[currentStage])
+	testkit.SaveScreenshot(window, "ocean.png")
+
 	//notifyWindows(“Ocean Wave Benchmark”, “Test completed. Data saved in ”+fileName)
 }
 
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"strconv"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"moddergltest/testkit"
+)
+
+const (
+	windowWidth    = 1024
+	windowHeight   = 768
+	stageTime      = 10
+	totalStages    = 6
+	testDuration   = 60
+	warmUpTime     = 2
+	particleFloats = 7 // position, velocity, life
+)
+
+var (
+	vaos, vbos     [2]uint32
+	source         int // buffer holding the current particle state
+	updateProgram  uint32
+	renderProgram  uint32
+	particleCount  int
+	startTime      time.Time
+	particleCounts = []int{
+		16000,
+		64000,
+		256000,
+		1024000,
+		2048000,
+		4096000,
+	}
+)
+
+func getWindowsInfo() (string, string) {
+	return "Windows", "particles.csv"
+}
+
+func createWindow() *glfw.Window {
+	if err := glfw.Init(); err != nil {
+		panic(err)
+	}
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.Resizable, glfw.False)
+
+	// Create window
+	window, err := glfw.CreateWindow(windowWidth, windowHeight, "GLTest | GPU Particles", nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	window.MakeContextCurrent()
+
+	// Center
+	monitor := glfw.GetPrimaryMonitor()
+	if monitor == nil {
+		panic("Failed to get the main monitor")
+	}
+	mode := monitor.GetVideoMode()
+	if mode == nil {
+		panic("Failed to get the video mode of the monitor")
+	}
+
+	// Get coordinates
+	xPos := (mode.Width - windowWidth) / 2
+	yPos := (mode.Height - windowHeight) / 2
+	window.SetPos(xPos, yPos)
+
+	return window
+}
+
+func compileShader(shader uint32, source string) {
+	csource, free := gl.Strs(source + "\x00")
+	defer free()
+	gl.ShaderSource(shader, 1, csource, nil)
+	gl.CompileShader(shader)
+
+	var status int32
+	gl.GetShaderiv(shader, gl.COMPILE_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &logLength)
+		log := make([]byte, logLength)
+		gl.GetShaderInfoLog(shader, logLength, nil, &log[0])
+		panic(fmt.Errorf("failed to compile shader: %v", string(log)))
+	}
+}
+
+func notifyWindows(title, message string) {
+	user32 := syscall.NewLazyDLL("user32.dll")
+	messageBox := user32.NewProc("MessageBoxW")
+	titlePtr, _ := syscall.UTF16PtrFromString(title)
+	messagePtr, _ := syscall.UTF16PtrFromString(message)
+	messageBox.Call(0, uintptr(unsafe.Pointer(messagePtr)), uintptr(unsafe.Pointer(titlePtr)), 0)
+}
+
+func checkLinkStatus(program uint32) {
+	var status int32
+	gl.GetProgramiv(program, gl.LINK_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetProgramiv(program, gl.INFO_LOG_LENGTH, &logLength)
+		log := make([]byte, logLength)
+		gl.GetProgramInfoLog(program, logLength, nil, &log[0])
+		panic(fmt.Errorf("failed to link program: %v", string(log)))
+	}
+}
+
+// Random particles around the emitter with staggered lifetimes so they do not respawn in lockstep
+func createParticles(count int) []float32 {
+	data := make([]float32, count*particleFloats)
+	for i := 0; i < count; i++ {
+		angle := rand.Float64() * 2 * math.Pi
+		radius := rand.Float32() * 1.5
+		base := i * particleFloats
+		data[base] = float32(math.Cos(angle)) * radius
+		data[base+1] = rand.Float32()*4 - 2
+		data[base+2] = float32(math.Sin(angle)) * radius
+		data[base+3] = 0
+		data[base+4] = rand.Float32() * 2
+		data[base+5] = 0
+		data[base+6] = rand.Float32() * 5
+	}
+	return data
+}
+
+func initGL() {
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+
+	// Transform feedback pass: advance the simulation without rasterising anything
+	updateShader := gl.CreateShader(gl.VERTEX_SHADER)
+	updateSource := `#version 410 core
+		layout (location = 0) in vec3 position;
+		layout (location = 1) in vec3 velocity;
+		layout (location = 2) in float life;
+		uniform float dt;
+		uniform float time;
+		out vec3 outPosition;
+		out vec3 outVelocity;
+		out float outLife;
+
+		float hash(float n) {
+			return fract(sin(n) * 43758.5453);
+		}
+
+		void main() {
+			vec3 p = position;
+			vec3 v = velocity;
+			float l = life - dt;
+			if (l <= 0.0) {
+				// Respawn at the emitter ring
+				float seed = float(gl_VertexID) * 0.0013 + time;
+				float a = hash(seed) * 6.28318;
+				float r = hash(seed + 1.0) * 0.5;
+				p = vec3(cos(a) * r, -2.0, sin(a) * r);
+				v = vec3(cos(a) * 0.5, 3.0 + hash(seed + 2.0) * 2.0, sin(a) * 0.5);
+				l = 2.0 + hash(seed + 3.0) * 3.0;
+			} else {
+				// Swirl around the vertical axis under gravity
+				vec3 swirl = vec3(-p.z, 0.0, p.x) * 0.8;
+				v += (swirl - vec3(0.0, 1.5, 0.0)) * dt;
+				p += v * dt;
+			}
+			outPosition = p;
+			outVelocity = v;
+			outLife = l;
+		}`
+	compileShader(updateShader, updateSource)
+
+	updateProgram = gl.CreateProgram()
+	gl.AttachShader(updateProgram, updateShader)
+	varyings, free := gl.Strs("outPosition\x00", "outVelocity\x00", "outLife\x00")
+	gl.TransformFeedbackVaryings(updateProgram, 3, varyings, gl.INTERLEAVED_ATTRIBS)
+	free()
+	gl.LinkProgram(updateProgram)
+	checkLinkStatus(updateProgram)
+	gl.DeleteShader(updateShader)
+
+	// Render pass: the geometry shader expands every point into a camera-facing quad
+	vertexShader := gl.CreateShader(gl.VERTEX_SHADER)
+	vertexSource := `#version 410 core
+		layout (location = 0) in vec3 position;
+		layout (location = 2) in float life;
+		out float vLife;
+		void main() {
+			gl_Position = vec4(position, 1.0);
+			vLife = life;
+		}`
+	compileShader(vertexShader, vertexSource)
+
+	geometryShader := gl.CreateShader(gl.GEOMETRY_SHADER)
+	geometrySource := `#version 410 core
+		layout (points) in;
+		layout (triangle_strip, max_vertices = 4) out;
+		in float vLife[];
+		uniform mat4 viewProj;
+		uniform vec3 cameraRight;
+		uniform vec3 cameraUp;
+		uniform float size;
+		out vec2 corner;
+		out float fade;
+
+		void main() {
+			vec3 center = gl_in[0].gl_Position.xyz;
+			for (int i = 0; i < 4; ++i) {
+				vec2 c = vec2(i & 1, i >> 1) * 2.0 - 1.0;
+				corner = c;
+				fade = clamp(vLife[0], 0.0, 1.0);
+				gl_Position = viewProj * vec4(center + (cameraRight * c.x + cameraUp * c.y) * size, 1.0);
+				EmitVertex();
+			}
+			EndPrimitive();
+		}`
+	compileShader(geometryShader, geometrySource)
+
+	fragmentShader := gl.CreateShader(gl.FRAGMENT_SHADER)
+	fragmentSource := `#version 410 core
+		in vec2 corner;
+		in float fade;
+		out vec4 FragColor;
+		void main() {
+			float alpha = (1.0 - smoothstep(0.0, 1.0, length(corner))) * fade;
+			FragColor = vec4(mix(vec3(1.0, 0.3, 0.1), vec3(1.0, 0.9, 0.5), fade), alpha * 0.6);
+		}`
+	compileShader(fragmentShader, fragmentSource)
+
+	renderProgram = gl.CreateProgram()
+	gl.AttachShader(renderProgram, vertexShader)
+	gl.AttachShader(renderProgram, geometryShader)
+	gl.AttachShader(renderProgram, fragmentShader)
+	gl.LinkProgram(renderProgram)
+	checkLinkStatus(renderProgram)
+
+	gl.DeleteShader(vertexShader)
+	gl.DeleteShader(geometryShader)
+	gl.DeleteShader(fragmentShader)
+
+	gl.GenVertexArrays(2, &vaos[0])
+	gl.GenBuffers(2, &vbos[0])
+	for i := range vaos {
+		gl.BindVertexArray(vaos[i])
+		gl.BindBuffer(gl.ARRAY_BUFFER, vbos[i])
+		gl.EnableVertexAttribArray(0)
+		gl.VertexAttribPointer(0, 3, gl.FLOAT, false, particleFloats*4, gl.PtrOffset(0))
+		gl.EnableVertexAttribArray(1)
+		gl.VertexAttribPointer(1, 3, gl.FLOAT, false, particleFloats*4, gl.PtrOffset(3*4))
+		gl.EnableVertexAttribArray(2)
+		gl.VertexAttribPointer(2, 1, gl.FLOAT, false, particleFloats*4, gl.PtrOffset(6*4))
+	}
+	gl.BindVertexArray(0)
+}
+
+// Upload the initial state once per stage; from then on it only lives on the GPU
+func uploadParticles(count int) {
+	data := createParticles(count)
+	for i := range vbos {
+		gl.BindBuffer(gl.ARRAY_BUFFER, vbos[i])
+		gl.BufferData(gl.ARRAY_BUFFER, len(data)*4, gl.Ptr(data), gl.DYNAMIC_COPY)
+	}
+	particleCount = count
+	source = 0
+}
+
+func updateParticles(dt, currentTime float32) {
+	target := 1 - source
+
+	gl.UseProgram(updateProgram)
+	gl.Uniform1f(gl.GetUniformLocation(updateProgram, gl.Str("dt\x00")), dt)
+	gl.Uniform1f(gl.GetUniformLocation(updateProgram, gl.Str("time\x00")), currentTime)
+
+	gl.Enable(gl.RASTERIZER_DISCARD)
+	gl.BindVertexArray(vaos[source])
+	gl.BindBufferBase(gl.TRANSFORM_FEEDBACK_BUFFER, 0, vbos[target])
+	gl.BeginTransformFeedback(gl.POINTS)
+	gl.DrawArrays(gl.POINTS, 0, int32(particleCount))
+	gl.EndTransformFeedback()
+	gl.BindBufferBase(gl.TRANSFORM_FEEDBACK_BUFFER, 0, 0)
+	gl.Disable(gl.RASTERIZER_DISCARD)
+
+	source = target
+}
+
+func drawParticles(currentTime float32) {
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.SRC_ALPHA, gl.ONE)
+
+	gl.UseProgram(renderProgram)
+
+	angle := float64(currentTime) * 0.2
+	eye := mgl32.Vec3{8 * float32(math.Cos(angle)), 2, 8 * float32(math.Sin(angle))}
+	projection := mgl32.Perspective(mgl32.DegToRad(45.0), float32(windowWidth)/windowHeight, 0.1, 100.0)
+	view := mgl32.LookAtV(eye, mgl32.Vec3{0, 0, 0}, mgl32.Vec3{0, 1, 0})
+	viewProj := projection.Mul4(view)
+	right := view.Row(0).Vec3()
+	up := view.Row(1).Vec3()
+
+	gl.UniformMatrix4fv(gl.GetUniformLocation(renderProgram, gl.Str("viewProj\x00")), 1, false, &viewProj[0])
+	gl.Uniform3fv(gl.GetUniformLocation(renderProgram, gl.Str("cameraRight\x00")), 1, &right[0])
+	gl.Uniform3fv(gl.GetUniformLocation(renderProgram, gl.Str("cameraUp\x00")), 1, &up[0])
+	gl.Uniform1f(gl.GetUniformLocation(renderProgram, gl.Str("size\x00")), 0.02)
+
+	gl.BindVertexArray(vaos[source])
+	gl.DrawArrays(gl.POINTS, 0, int32(particleCount))
+}
+
+func checkGLError() {
+	if err := gl.GetError(); err != gl.NO_ERROR {
+		fmt.Printf("OpenGL error: %d\n", err)
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	runtime.LockOSThread()
+	window := createWindow()
+	defer glfw.Terminate()
+	initGL()
+	gl.ClearColor(0, 0, 0, 1)
+
+	_, fileName := getWindowsInfo()
+	file, err := os.Create(fileName)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	writer.Write([]string{"Time (s)", "Stage", "Particles", "Avg FPS", "Min FPS"})
+
+	stageCount, duration := testkit.Overrides(len(particleCounts), stageTime, testDuration)
+	particleCounts = particleCounts[:stageCount]
+
+	testkit.WatchStop()
+
+	// Announced for the progress display of the runner
+	fmt.Printf("Duration: %.0fs, Stages: %d\n", duration, len(particleCounts))
+
+	// Warming
+	fmt.Println("Warming up...")
+	warmUpStart := time.Now()
+	lastFrame := warmUpStart
+	uploadParticles(particleCounts[0])
+	for time.Since(warmUpStart).Seconds() < warmUpTime && !testkit.StopRequested() {
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		currentTime := float32(time.Since(warmUpStart).Seconds())
+		updateParticles(float32(time.Since(lastFrame).Seconds()), currentTime)
+		lastFrame = time.Now()
+		drawParticles(currentTime)
+		window.SwapBuffers()
+		glfw.PollEvents()
+		checkGLError()
+	}
+
+	// Main test
+	startTime = time.Now()
+	testStart := startTime
+	lastRecordTime := testStart
+	var frameTimes []float64
+	currentStage := 0
+	dt := float32(0)
+
+	for !window.ShouldClose() && !testkit.StopRequested() && time.Since(testStart).Seconds() < duration {
+		frameStart := time.Now()
+
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		currentTime := float32(time.Since(startTime).Seconds())
+		updateParticles(dt, currentTime)
+		drawParticles(currentTime)
+		window.SwapBuffers()
+		glfw.PollEvents()
+		checkGLError()
+
+		frameTime := time.Since(frameStart).Seconds()
+		frameTimes = append(frameTimes, frameTime)
+		// Keep the simulation stable on very slow frames
+		dt = float32(math.Min(frameTime, 0.05))
+
+		timeElapsed := time.Since(testStart).Seconds()
+		newStage := int(timeElapsed / stageTime)
+		if newStage != currentStage && newStage < len(particleCounts) {
+			currentStage = newStage
+			uploadParticles(particleCounts[currentStage])
+			fmt.Printf("\nStarting stage %d with %d particles\n", currentStage+1, particleCount)
+		}
+
+		if time.Since(lastRecordTime).Seconds() >= 0.5 {
+			var totalFrameTime float64
+			for _, ft := range frameTimes {
+				totalFrameTime += ft
+			}
+			avgFPS := float64(len(frameTimes)) / totalFrameTime
+			minFPS := 1.0 / maxFrameTime(frameTimes)
+
+			writer.Write([]string{
+				strconv.FormatFloat(timeElapsed, 'f', 1, 64),
+				strconv.Itoa(currentStage + 1),
+				strconv.Itoa(particleCount),
+				strconv.FormatFloat(avgFPS, 'f', 1, 64),
+				strconv.FormatFloat(minFPS, 'f', 1, 64),
+			})
+			writer.Flush()
+
+			fmt.Printf("Time: %.1fs, Stage: %d, Particles: %d, Avg FPS: %.1f, Min FPS: %.1f\n",
+				timeElapsed, currentStage+1, particleCount, avgFPS, minFPS)
+
+			frameTimes = nil
+			lastRecordTime = time.Now()
+		}
+	}
+
+	// Screenshot of the last stage for the report, drawn once more outside the measured frames
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+	drawParticles(float32(time.Since(startTime).Seconds()))
+	testkit.SaveScreenshot(window, "particles.png")
+
+	//notifyWindows("GPU Particles Benchmark", "Test completed. Data saved in "+fileName)
+}
+
+func maxFrameTime(frameTimes []float64) float64 {
+	max := frameTimes[0]
+	for _, ft := range frameTimes {
+		if ft > max {
+			max = ft
+		}
+	}
+	return max
+}
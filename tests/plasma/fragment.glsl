@@ -0,0 +1,33 @@
+#version 410 core
+in vec2 uv;
+out vec4 fragColor;
+
+uniform float time;
+uniform vec2 resolution;
+uniform int octaves;
+uniform vec3 tint;
+uniform float scale;
+
+float hash(vec2 p) {
+    return fract(sin(dot(p, vec2(127.1, 311.7))) * 43758.5453);
+}
+
+float noise(vec2 p) {
+    vec2 i = floor(p);
+    vec2 f = fract(p);
+    vec2 u = f * f * (3.0 - 2.0 * f);
+    return mix(mix(hash(i), hash(i + vec2(1.0, 0.0)), u.x),
+               mix(hash(i + vec2(0.0, 1.0)), hash(i + vec2(1.0, 1.0)), u.x), u.y);
+}
+
+void main() {
+    vec2 p = uv * scale * vec2(resolution.x / resolution.y, 1.0);
+    float value = 0.0;
+    float amplitude = 0.5;
+    for (int i = 0; i < octaves; i++) {
+        value += amplitude * noise(p + time * 0.3);
+        p *= 2.0;
+        amplitude *= 0.5;
+    }
+    fragColor = vec4(tint * (0.5 + 0.5 * sin(value * 12.0 + time)), 1.0);
+}
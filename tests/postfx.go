@@ -0,0 +1,649 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"moddergltest/testkit"
+)
+
+const (
+	windowWidth  = 1024
+	windowHeight = 768
+	stageTime    = 10
+	totalStages  = 6
+	testDuration = 60
+	warmUpTime   = 2
+	gridSide     = 48
+)
+
+// Full-screen pass chain and the resolution scale of the offscreen targets
+type effectStage struct {
+	passes []string
+	scale  float32
+}
+
+type renderTarget struct {
+	fbo, texture uint32
+}
+
+var (
+	sceneVAO, sceneVBO, quadVAO uint32
+	sceneProgram                uint32
+	passPrograms                = map[string]uint32{}
+	startTime                   time.Time
+
+	sceneFBO, sceneColor, sceneDepth uint32
+	pingPong                         [2]renderTarget
+	aoTarget                         renderTarget
+	renderWidth, renderHeight        int32
+
+	bloomChain   = []string{"bright", "blur_h", "blur_v", "blur_h", "blur_v", "composite", "tonemap"}
+	ssaoChain    = append([]string{"ssao"}, bloomChain...)
+	effectStages = []effectStage{
+		{[]string{"tonemap"}, 1.0},
+		{[]string{"blur_h", "blur_v", "tonemap"}, 1.0},
+		{bloomChain, 1.0},
+		{ssaoChain, 1.0},
+		{ssaoChain, 1.5},
+		{ssaoChain, 2.0},
+	}
+
+	// Pass times are read back only once the driver has them, so timing never stalls a frame
+	gpuTimer *testkit.GPUTimer
+)
+
+// Timer queries per frame: the scene and up to 15 passes
+const maxTimedPasses = 16
+
+func getWindowsInfo() (string, string) {
+	return "Windows", "postfx.csv"
+}
+
+func createWindow() *glfw.Window {
+	if err := glfw.Init(); err != nil {
+		panic(err)
+	}
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.Resizable, glfw.False)
+
+	// Create window
+	window, err := glfw.CreateWindow(windowWidth, windowHeight, "GLTest | Post-processing", nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	window.MakeContextCurrent()
+
+	// Center
+	monitor := glfw.GetPrimaryMonitor()
+	if monitor == nil {
+		panic("Failed to get the main monitor")
+	}
+	mode := monitor.GetVideoMode()
+	if mode == nil {
+		panic("Failed to get the video mode of the monitor")
+	}
+
+	// Get coordinates
+	xPos := (mode.Width - windowWidth) / 2
+	yPos := (mode.Height - windowHeight) / 2
+	window.SetPos(xPos, yPos)
+
+	return window
+}
+
+func compileShader(shader uint32, source string) {
+	csource, free := gl.Strs(source + "\x00")
+	defer free()
+	gl.ShaderSource(shader, 1, csource, nil)
+	gl.CompileShader(shader)
+
+	var status int32
+	gl.GetShaderiv(shader, gl.COMPILE_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &logLength)
+		log := make([]byte, logLength)
+		gl.GetShaderInfoLog(shader, logLength, nil, &log[0])
+		panic(fmt.Errorf("failed to compile shader: %v", string(log)))
+	}
+}
+
+func notifyWindows(title, message string) {
+	user32 := syscall.NewLazyDLL("user32.dll")
+	messageBox := user32.NewProc("MessageBoxW")
+	titlePtr, _ := syscall.UTF16PtrFromString(title)
+	messagePtr, _ := syscall.UTF16PtrFromString(message)
+	messageBox.Call(0, uintptr(unsafe.Pointer(messagePtr)), uintptr(unsafe.Pointer(titlePtr)), 0)
+}
+
+func linkProgram(vertexSource, fragmentSource string) uint32 {
+	vertexShader := gl.CreateShader(gl.VERTEX_SHADER)
+	compileShader(vertexShader, vertexSource)
+	fragmentShader := gl.CreateShader(gl.FRAGMENT_SHADER)
+	compileShader(fragmentShader, fragmentSource)
+
+	program := gl.CreateProgram()
+	gl.AttachShader(program, vertexShader)
+	gl.AttachShader(program, fragmentShader)
+	gl.LinkProgram(program)
+
+	gl.DeleteShader(vertexShader)
+	gl.DeleteShader(fragmentShader)
+	return program
+}
+
+// Unit cube, position + normal per vertex
+func createCube() []float32 {
+	faces := []struct {
+		normal    [3]float32
+		tangent   [3]float32
+		bitangent [3]float32
+	}{
+		{[3]float32{1, 0, 0}, [3]float32{0, 0, -1}, [3]float32{0, 1, 0}},
+		{[3]float32{-1, 0, 0}, [3]float32{0, 0, 1}, [3]float32{0, 1, 0}},
+		{[3]float32{0, 1, 0}, [3]float32{1, 0, 0}, [3]float32{0, 0, -1}},
+		{[3]float32{0, -1, 0}, [3]float32{1, 0, 0}, [3]float32{0, 0, 1}},
+		{[3]float32{0, 0, 1}, [3]float32{1, 0, 0}, [3]float32{0, 1, 0}},
+		{[3]float32{0, 0, -1}, [3]float32{-1, 0, 0}, [3]float32{0, 1, 0}},
+	}
+	corners := [][2]float32{{-1, -1}, {1, -1}, {1, 1}, {-1, -1}, {1, 1}, {-1, 1}}
+
+	vertices := make([]float32, 0, len(faces)*len(corners)*6)
+	for _, f := range faces {
+		for _, c := range corners {
+			for i := 0; i < 3; i++ {
+				vertices = append(vertices, 0.5*(f.normal[i]+c[0]*f.tangent[i]+c[1]*f.bitangent[i]))
+			}
+			vertices = append(vertices, f.normal[0], f.normal[1], f.normal[2])
+		}
+	}
+	return vertices
+}
+
+func initGL() {
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+
+	sceneProgram = linkProgram(`#version 410 core
+		layout (location = 0) in vec3 position;
+		layout (location = 1) in vec3 normal;
+		uniform mat4 viewProj;
+		uniform float time;
+		uniform int gridSide;
+		out vec3 fragNormal;
+		out vec3 fragColor;
+		out float emissive;
+
+		void main() {
+			vec2 cell = vec2(gl_InstanceID % gridSide, gl_InstanceID / gridSide) - float(gridSide - 1) * 0.5;
+			float h = sin(cell.x * 0.35 + time) * cos(cell.y * 0.35 + time * 0.7);
+			vec3 pos = position * (0.6 + 0.3 * h) + vec3(cell.x * 1.5, h * 2.0, cell.y * 1.5);
+			gl_Position = viewProj * vec4(pos, 1.0);
+			fragNormal = normal;
+
+			// Palette colour per cube, a few of them glow brighter than 1.0
+			float hue = fract(float(gl_InstanceID) * 0.618034);
+			fragColor = 0.5 + 0.5 * cos(6.28318 * (hue + vec3(0.0, 0.33, 0.67)));
+			emissive = step(0.9, fract(hue * 7.0 + time * 0.2)) * 6.0;
+		}`, `#version 410 core
+		in vec3 fragNormal;
+		in vec3 fragColor;
+		in float emissive;
+		out vec4 FragColor;
+
+		void main() {
+			vec3 lightDir = normalize(vec3(0.4, 1.0, 0.3));
+			float diff = max(dot(normalize(fragNormal), lightDir), 0.0);
+			vec3 color = fragColor * (0.15 + 1.5 * diff) + fragColor * emissive;
+			FragColor = vec4(color, 1.0);
+		}`)
+
+	// Full-screen triangle generated from gl_VertexID
+	quadSource := `#version 410 core
+		out vec2 uv;
+		void main() {
+			vec2 pos = vec2((gl_VertexID << 1) & 2, gl_VertexID & 2);
+			uv = pos;
+			gl_Position = vec4(pos * 2.0 - 1.0, 0.0, 1.0);
+		}`
+
+	passPrograms["bright"] = linkProgram(quadSource, `#version 410 core
+		in vec2 uv;
+		uniform sampler2D source;
+		out vec4 FragColor;
+		void main() {
+			vec3 color = texture(source, uv).rgb;
+			float luma = dot(color, vec3(0.2126, 0.7152, 0.0722));
+			FragColor = vec4(color * max(luma - 1.0, 0.0) / max(luma, 0.0001), 1.0);
+		}`)
+
+	blurSource := `#version 410 core
+		in vec2 uv;
+		uniform sampler2D source;
+		uniform vec2 direction;
+		out vec4 FragColor;
+		const float weights[5] = float[](0.227027, 0.1945946, 0.1216216, 0.054054, 0.016216);
+		void main() {
+			vec2 texel = direction / vec2(textureSize(source, 0));
+			vec3 color = texture(source, uv).rgb * weights[0];
+			for (int i = 1; i < 5; ++i) {
+				color += texture(source, uv + texel * float(i)).rgb * weights[i];
+				color += texture(source, uv - texel * float(i)).rgb * weights[i];
+			}
+			FragColor = vec4(color, 1.0);
+		}`
+	passPrograms["blur_h"] = linkProgram(quadSource, blurSource)
+	passPrograms["blur_v"] = linkProgram(quadSource, blurSource)
+
+	passPrograms["ssao"] = linkProgram(quadSource, `#version 410 core
+		in vec2 uv;
+		uniform sampler2D depthMap;
+		uniform float near;
+		uniform float far;
+		out vec4 FragColor;
+
+		float linearDepth(vec2 p) {
+			float z = texture(depthMap, p).r * 2.0 - 1.0;
+			return (2.0 * near * far) / (far + near - z * (far - near));
+		}
+
+		float hash(vec2 p) {
+			return fract(sin(dot(p, vec2(12.9898, 78.233))) * 43758.5453);
+		}
+
+		void main() {
+			float center = linearDepth(uv);
+			vec2 texel = 1.0 / vec2(textureSize(depthMap, 0));
+			float angle = hash(uv) * 6.28318;
+			float occlusion = 0.0;
+			for (int i = 0; i < 16; ++i) {
+				float r = 4.0 + float(i) * 1.5;
+				float a = angle + float(i) * 2.39996;
+				float sampleDepth = linearDepth(uv + vec2(cos(a), sin(a)) * r * texel);
+				float diff = center - sampleDepth;
+				occlusion += step(0.05, diff) * smoothstep(0.0, 1.0, 1.0 / abs(diff));
+			}
+			FragColor = vec4(vec3(1.0 - occlusion / 16.0), 1.0);
+		}`)
+
+	passPrograms["composite"] = linkProgram(quadSource, `#version 410 core
+		in vec2 uv;
+		uniform sampler2D scene;
+		uniform sampler2D source;
+		uniform sampler2D aoMap;
+		uniform int useAO;
+		out vec4 FragColor;
+		void main() {
+			vec3 color = texture(scene, uv).rgb;
+			if (useAO != 0) {
+				color *= texture(aoMap, uv).r;
+			}
+			FragColor = vec4(color + texture(source, uv).rgb * 0.8, 1.0);
+		}`)
+
+	passPrograms["tonemap"] = linkProgram(quadSource, `#version 410 core
+		in vec2 uv;
+		uniform sampler2D source;
+		out vec4 FragColor;
+		void main() {
+			// ACES filmic approximation
+			vec3 x = texture(source, uv).rgb;
+			vec3 color = clamp((x * (2.51 * x + 0.03)) / (x * (2.43 * x + 0.59) + 0.14), 0.0, 1.0);
+			FragColor = vec4(pow(color, vec3(1.0 / 2.2)), 1.0);
+		}`)
+
+	cube := createCube()
+	gl.GenVertexArrays(1, &sceneVAO)
+	gl.BindVertexArray(sceneVAO)
+	gl.GenBuffers(1, &sceneVBO)
+	gl.BindBuffer(gl.ARRAY_BUFFER, sceneVBO)
+	gl.BufferData(gl.ARRAY_BUFFER, len(cube)*4, gl.Ptr(cube), gl.STATIC_DRAW)
+	gl.EnableVertexAttribArray(0)
+	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 6*4, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(1)
+	gl.VertexAttribPointer(1, 3, gl.FLOAT, false, 6*4, gl.PtrOffset(3*4))
+
+	// Core profile needs a bound VAO even for attribute-less draws
+	gl.GenVertexArrays(1, &quadVAO)
+
+	gpuTimer = testkit.NewGPUTimer(maxTimedPasses)
+}
+
+func createColorTexture(width, height int32) uint32 {
+	var texture uint32
+	gl.GenTextures(1, &texture)
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA16F, width, height, 0, gl.RGBA, gl.FLOAT, nil)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	return texture
+}
+
+func createRenderTarget(width, height int32) renderTarget {
+	var target renderTarget
+	target.texture = createColorTexture(width, height)
+	gl.GenFramebuffers(1, &target.fbo)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, target.fbo)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, target.texture, 0)
+	if status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+		panic(fmt.Errorf("incomplete framebuffer: 0x%x", status))
+	}
+	return target
+}
+
+func deleteRenderTarget(target renderTarget) {
+	gl.DeleteFramebuffers(1, &target.fbo)
+	gl.DeleteTextures(1, &target.texture)
+}
+
+// (Re)create the HDR scene buffer and intermediate targets at the stage resolution
+func createTargets(scale float32) {
+	if sceneFBO != 0 {
+		gl.DeleteFramebuffers(1, &sceneFBO)
+		gl.DeleteTextures(1, &sceneColor)
+		gl.DeleteTextures(1, &sceneDepth)
+		deleteRenderTarget(pingPong[0])
+		deleteRenderTarget(pingPong[1])
+		deleteRenderTarget(aoTarget)
+	}
+
+	renderWidth = int32(float32(windowWidth) * scale)
+	renderHeight = int32(float32(windowHeight) * scale)
+
+	sceneColor = createColorTexture(renderWidth, renderHeight)
+	gl.GenTextures(1, &sceneDepth)
+	gl.BindTexture(gl.TEXTURE_2D, sceneDepth)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.DEPTH_COMPONENT24, renderWidth, renderHeight, 0, gl.DEPTH_COMPONENT, gl.FLOAT, nil)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.NEAREST)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+
+	gl.GenFramebuffers(1, &sceneFBO)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, sceneFBO)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, sceneColor, 0)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, gl.TEXTURE_2D, sceneDepth, 0)
+	if status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER); status != gl.FRAMEBUFFER_COMPLETE {
+		panic(fmt.Errorf("incomplete scene framebuffer: 0x%x", status))
+	}
+
+	pingPong[0] = createRenderTarget(renderWidth, renderHeight)
+	pingPong[1] = createRenderTarget(renderWidth, renderHeight)
+	aoTarget = createRenderTarget(renderWidth, renderHeight)
+
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+}
+
+// Pixels shaded per frame in millions: scene and offscreen passes at render size, tonemap at window size
+func stageMegapixels(stage effectStage) float64 {
+	width := float64(int32(float32(windowWidth) * stage.scale))
+	height := float64(int32(float32(windowHeight) * stage.scale))
+	offscreen := float64(len(stage.passes)) // scene + all passes but the final one
+	return (offscreen*width*height + windowWidth*windowHeight) / 1e6
+}
+
+func bindTexture(unit uint32, program uint32, name string, texture uint32) {
+	gl.ActiveTexture(gl.TEXTURE0 + unit)
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	gl.Uniform1i(gl.GetUniformLocation(program, gl.Str(name+"\x00")), int32(unit))
+}
+
+func drawScene(currentTime float32) {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, sceneFBO)
+	gl.Viewport(0, 0, renderWidth, renderHeight)
+	gl.Enable(gl.DEPTH_TEST)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+
+	gl.UseProgram(sceneProgram)
+
+	angle := float64(currentTime) * 0.1
+	eye := mgl32.Vec3{40 * float32(math.Cos(angle)), 18, 40 * float32(math.Sin(angle))}
+	projection := mgl32.Perspective(mgl32.DegToRad(45.0), float32(windowWidth)/windowHeight, 0.1, 200.0)
+	view := mgl32.LookAtV(eye, mgl32.Vec3{0, 0, 0}, mgl32.Vec3{0, 1, 0})
+	viewProj := projection.Mul4(view)
+	gl.UniformMatrix4fv(gl.GetUniformLocation(sceneProgram, gl.Str("viewProj\x00")), 1, false, &viewProj[0])
+	gl.Uniform1f(gl.GetUniformLocation(sceneProgram, gl.Str("time\x00")), currentTime)
+	gl.Uniform1i(gl.GetUniformLocation(sceneProgram, gl.Str("gridSide\x00")), gridSide)
+
+	gl.BindVertexArray(sceneVAO)
+	gl.DrawArraysInstanced(gl.TRIANGLES, 0, 36, gridSide*gridSide)
+	gl.Disable(gl.DEPTH_TEST)
+}
+
+func drawPasses(passes []string) {
+	gl.BindVertexArray(quadVAO)
+	source := sceneColor
+	next := 0
+	useAO := int32(0)
+
+	for _, name := range passes {
+		program := passPrograms[name]
+		gpuTimer.Begin(name)
+		gl.UseProgram(program)
+
+		target := pingPong[next]
+		switch name {
+		case "ssao":
+			target = aoTarget
+			bindTexture(0, program, "depthMap", sceneDepth)
+			gl.Uniform1f(gl.GetUniformLocation(program, gl.Str("near\x00")), 0.1)
+			gl.Uniform1f(gl.GetUniformLocation(program, gl.Str("far\x00")), 200.0)
+			useAO = 1
+		case "blur_h", "blur_v":
+			bindTexture(0, program, "source", source)
+			direction := [2]float32{1, 0}
+			if name == "blur_v" {
+				direction = [2]float32{0, 1}
+			}
+			gl.Uniform2f(gl.GetUniformLocation(program, gl.Str("direction\x00")), direction[0], direction[1])
+		case "composite":
+			bindTexture(0, program, "scene", sceneColor)
+			bindTexture(1, program, "source", source)
+			bindTexture(2, program, "aoMap", aoTarget.texture)
+			gl.Uniform1i(gl.GetUniformLocation(program, gl.Str("useAO\x00")), useAO)
+		default:
+			bindTexture(0, program, "source", source)
+		}
+
+		if name == "tonemap" {
+			gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+			gl.Viewport(0, 0, windowWidth, windowHeight)
+		} else {
+			gl.BindFramebuffer(gl.FRAMEBUFFER, target.fbo)
+			gl.Viewport(0, 0, renderWidth, renderHeight)
+		}
+		gl.DrawArrays(gl.TRIANGLES, 0, 3)
+		gpuTimer.End()
+
+		if name != "ssao" && name != "tonemap" {
+			source = target.texture
+			next = 1 - next
+		}
+	}
+}
+
+func drawFrame(stage effectStage, currentTime float32) {
+	gpuTimer.Begin("scene")
+	drawScene(currentTime)
+	gpuTimer.End()
+	drawPasses(stage.passes)
+}
+
+// Average GPU time per timed frame and the per-pass breakdown as "pass=ms" pairs
+func passTimeSummary() (float64, string) {
+	total, sections := gpuTimer.Mean()
+	parts := make([]string, 0, len(sections))
+	for _, section := range sections {
+		parts = append(parts, fmt.Sprintf("%s=%.3f", section.Name, section.Ms))
+	}
+	return total, strings.Join(parts, ";")
+}
+
+// Replace the pass chain of every stage; stages then only raise the resolution scale
+func applyChain(chain string) {
+	if chain == "" {
+		return
+	}
+	passes := strings.Split(chain, ",")
+	for i, name := range passes {
+		passes[i] = strings.TrimSpace(name)
+		if _, ok := passPrograms[passes[i]]; !ok {
+			panic(fmt.Errorf("unknown pass %q", passes[i]))
+		}
+	}
+	if passes[len(passes)-1] != "tonemap" {
+		passes = append(passes, "tonemap")
+	}
+	if len(passes)+1 > maxTimedPasses {
+		panic(fmt.Errorf("too many passes in chain: %d", len(passes)))
+	}
+	for i := range effectStages {
+		effectStages[i].passes = passes
+	}
+}
+
+func checkGLError() {
+	if err := gl.GetError(); err != gl.NO_ERROR {
+		fmt.Printf("OpenGL error: %d\n", err)
+	}
+}
+
+func main() {
+	chain := flag.String("chain", "", "comma-separated pass chain used by every stage (bright, blur_h, blur_v, ssao, composite, tonemap)")
+	flag.Parse()
+
+	runtime.LockOSThread()
+	window := createWindow()
+	defer glfw.Terminate()
+	initGL()
+	applyChain(*chain)
+	gl.ClearColor(0.02, 0.02, 0.03, 1.0)
+
+	_, fileName := getWindowsInfo()
+	file, err := os.Create(fileName)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	writer.Write([]string{"Time (s)", "Stage", "Megapixels", "Avg FPS", "Min FPS", "GPU Time (ms)", "Pass Times (ms)"})
+
+	stageCount, duration := testkit.Overrides(len(effectStages), stageTime, testDuration)
+	effectStages = effectStages[:stageCount]
+
+	testkit.WatchStop()
+
+	// Announced for the progress display of the runner
+	fmt.Printf("Duration: %.0fs, Stages: %d\n", duration, len(effectStages))
+
+	// Warming
+	fmt.Println("Warming up...")
+	warmUpStart := time.Now()
+	createTargets(effectStages[0].scale)
+	for time.Since(warmUpStart).Seconds() < warmUpTime && !testkit.StopRequested() {
+		drawFrame(effectStages[0], float32(time.Since(warmUpStart).Seconds()))
+		window.SwapBuffers()
+		glfw.PollEvents()
+		gpuTimer.EndFrame()
+		checkGLError()
+	}
+	gpuTimer.Reset()
+
+	// Main test
+	startTime = time.Now()
+	testStart := startTime
+	lastRecordTime := testStart
+	var frameTimes []float64
+	currentStage := 0
+
+	for !window.ShouldClose() && !testkit.StopRequested() && time.Since(testStart).Seconds() < duration {
+		frameStart := time.Now()
+
+		currentTime := float32(time.Since(startTime).Seconds())
+		drawFrame(effectStages[currentStage], currentTime)
+		window.SwapBuffers()
+		glfw.PollEvents()
+		gpuTimer.EndFrame()
+		checkGLError()
+
+		frameTime := time.Since(frameStart).Seconds()
+		frameTimes = append(frameTimes, frameTime)
+
+		timeElapsed := time.Since(testStart).Seconds()
+		newStage := int(timeElapsed / stageTime)
+		if newStage != currentStage && newStage < len(effectStages) {
+			currentStage = newStage
+			if effectStages[currentStage].scale != effectStages[currentStage-1].scale {
+				createTargets(effectStages[currentStage].scale)
+			}
+			fmt.Printf("\nStarting stage %d with %d passes at %.1fx resolution\n",
+				currentStage+1, len(effectStages[currentStage].passes), effectStages[currentStage].scale)
+		}
+
+		if time.Since(lastRecordTime).Seconds() >= 0.5 {
+			var totalFrameTime float64
+			for _, ft := range frameTimes {
+				totalFrameTime += ft
+			}
+			avgFPS := float64(len(frameTimes)) / totalFrameTime
+			minFPS := 1.0 / maxFrameTime(frameTimes)
+			megapixels := stageMegapixels(effectStages[currentStage])
+			gpuTime, breakdown := passTimeSummary()
+
+			writer.Write([]string{
+				strconv.FormatFloat(timeElapsed, 'f', 1, 64),
+				strconv.Itoa(currentStage + 1),
+				strconv.FormatFloat(megapixels, 'f', 2, 64),
+				strconv.FormatFloat(avgFPS, 'f', 1, 64),
+				strconv.FormatFloat(minFPS, 'f', 1, 64),
+				strconv.FormatFloat(gpuTime, 'f', 3, 64),
+				breakdown,
+			})
+			writer.Flush()
+
+			fmt.Printf("Time: %.1fs, Stage: %d, Megapixels: %.2f, Avg FPS: %.1f, Min FPS: %.1f, GPU: %.2f ms\n",
+				timeElapsed, currentStage+1, megapixels, avgFPS, minFPS, gpuTime)
+
+			frameTimes = nil
+			gpuTimer.Reset()
+			lastRecordTime = time.Now()
+		}
+	}
+
+	// Screenshot of the last stage for the report, drawn once more outside the measured frames
+	drawFrame(effectStages[currentStage], float32(time.Since(startTime).Seconds()))
+	testkit.SaveScreenshot(window, "postfx.png")
+
+	//notifyWindows("Post-processing Benchmark", "Test completed. Data saved in "+fileName)
+}
+
+func maxFrameTime(frameTimes []float64) float64 {
+	max := frameTimes[0]
+	for _, ft := range frameTimes {
+		if ft > max {
+			max = ft
+		}
+	}
+	return max
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"strconv"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/go-gl/mathgl/mgl32"
+
+	"moddergltest/testkit"
+)
+
+const (
+	windowWidth  = 1024
+	windowHeight = 768
+	stageTime    = 10
+	totalStages  = 6
+	testDuration = 60
+	warmUpTime   = 2
+	boxCount     = 3000
+)
+
+// Shadow-casting lights, shadow map resolution and PCF kernel width per stage
+type shadowStage struct {
+	lights  int
+	mapSize int32
+	pcf     int
+}
+
+var (
+	vao, vbo              uint32
+	depthProgram          uint32
+	shaderProgram         uint32
+	shadowFBO, shadowMaps uint32
+	shadowMapSize         int32
+	sceneVertexCount      int32
+	startTime             time.Time
+	shadowStages          = []shadowStage{
+		{1, 1024, 1},
+		{1, 2048, 3},
+		{2, 2048, 3},
+		{2, 4096, 5},
+		{4, 4096, 5},
+		{6, 4096, 7},
+	}
+	lightColors = []mgl32.Vec3{
+		{1.0, 0.9, 0.8},
+		{0.4, 0.6, 1.0},
+		{1.0, 0.4, 0.3},
+		{0.5, 1.0, 0.5},
+		{0.9, 0.5, 1.0},
+		{1.0, 1.0, 0.4},
+	}
+)
+
+func getWindowsInfo() (string, string) {
+	return "Windows", "shadows.csv"
+}
+
+func createWindow() *glfw.Window {
+	if err := glfw.Init(); err != nil {
+		panic(err)
+	}
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.Resizable, glfw.False)
+
+	// Create window
+	window, err := glfw.CreateWindow(windowWidth, windowHeight, "GLTest | Shadows", nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	window.MakeContextCurrent()
+
+	// Center
+	monitor := glfw.GetPrimaryMonitor()
+	if monitor == nil {
+		panic("Failed to get the main monitor")
+	}
+	mode := monitor.GetVideoMode()
+	if mode == nil {
+		panic("Failed to get the video mode of the monitor")
+	}
+
+	// Get coordinates
+	xPos := (mode.Width - windowWidth) / 2
+	yPos := (mode.Height - windowHeight) / 2
+	window.SetPos(xPos, yPos)
+
+	return window
+}
+
+func compileShader(shader uint32, source string) {
+	csource, free := gl.Strs(source + "\x00")
+	defer free()
+	gl.ShaderSource(shader, 1, csource, nil)
+	gl.CompileShader(shader)
+
+	var status int32
+	gl.GetShaderiv(shader, gl.COMPILE_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &logLength)
+		log := make([]byte, logLength)
+		gl.GetShaderInfoLog(shader, logLength, nil, &log[0])
+		panic(fmt.Errorf("failed to compile shader: %v", string(log)))
+	}
+}
+
+func notifyWindows(title, message string) {
+	user32 := syscall.NewLazyDLL("user32.dll")
+	messageBox := user32.NewProc("MessageBoxW")
+	titlePtr, _ := syscall.UTF16PtrFromString(title)
+	messagePtr, _ := syscall.UTF16PtrFromString(message)
+	messageBox.Call(0, uintptr(unsafe.Pointer(messagePtr)), uintptr(unsafe.Pointer(titlePtr)), 0)
+}
+
+func linkProgram(vertexSource, fragmentSource string) uint32 {
+	vertexShader := gl.CreateShader(gl.VERTEX_SHADER)
+	compileShader(vertexShader, vertexSource)
+	fragmentShader := gl.CreateShader(gl.FRAGMENT_SHADER)
+	compileShader(fragmentShader, fragmentSource)
+
+	program := gl.CreateProgram()
+	gl.AttachShader(program, vertexShader)
+	gl.AttachShader(program, fragmentShader)
+	gl.LinkProgram(program)
+
+	gl.DeleteShader(vertexShader)
+	gl.DeleteShader(fragmentShader)
+	return program
+}
+
+// Append an axis-aligned box as 12 triangles with flat normals (position + normal per vertex)
+func appendBox(vertices []float32, center, size mgl32.Vec3) []float32 {
+	axes := []mgl32.Vec3{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	for a := 0; a < 3; a++ {
+		u := axes[(a+1)%3]
+		v := axes[(a+2)%3]
+		for _, sign := range []float32{1, -1} {
+			normal := axes[a].Mul(sign)
+			corners := [][2]float32{{-1, -1}, {1, -1}, {1, 1}, {-1, -1}, {1, 1}, {-1, 1}}
+			for _, c := range corners {
+				p := normal.Add(u.Mul(c[0])).Add(v.Mul(c[1]))
+				pos := mgl32.Vec3{center[0] + p[0]*size[0]*0.5, center[1] + p[1]*size[1]*0.5, center[2] + p[2]*size[2]*0.5}
+				vertices = append(vertices, pos[0], pos[1], pos[2], normal[0], normal[1], normal[2])
+			}
+		}
+	}
+	return vertices
+}
+
+// Procedural city: a ground slab with towers of random footprint and height
+func createScene() []float32 {
+	rng := rand.New(rand.NewSource(27))
+	vertices := make([]float32, 0, (boxCount+1)*36*6)
+	vertices = appendBox(vertices, mgl32.Vec3{0, -0.5, 0}, mgl32.Vec3{80, 1, 80})
+	for i := 0; i < boxCount; i++ {
+		x := (rng.Float32() - 0.5) * 70
+		z := (rng.Float32() - 0.5) * 70
+		w := 0.5 + rng.Float32()*1.5
+		d := 0.5 + rng.Float32()*1.5
+		h := 0.5 + rng.Float32()*rng.Float32()*10
+		vertices = appendBox(vertices, mgl32.Vec3{x, h * 0.5, z}, mgl32.Vec3{w, h, d})
+	}
+	return vertices
+}
+
+func initGL() {
+	if err := gl.Init(); err != nil {
+		panic(err)
+	}
+
+	depthProgram = linkProgram(`#version 410 core
+		layout (location = 0) in vec3 position;
+		uniform mat4 lightViewProj;
+		void main() {
+			gl_Position = lightViewProj * vec4(position, 1.0);
+		}`, `#version 410 core
+		void main() {
+		}`)
+
+	shaderProgram = linkProgram(`#version 410 core
+		layout (location = 0) in vec3 position;
+		layout (location = 1) in vec3 normal;
+		uniform mat4 viewProj;
+		out vec3 fragPos;
+		out vec3 fragNormal;
+
+		void main() {
+			gl_Position = viewProj * vec4(position, 1.0);
+			fragPos = position;
+			fragNormal = normal;
+		}`, `#version 410 core
+		in vec3 fragPos;
+		in vec3 fragNormal;
+		uniform sampler2DArrayShadow shadowMaps;
+		uniform mat4 lightViewProj[6];
+		uniform vec3 lightPos[6];
+		uniform vec3 lightColor[6];
+		uniform int lightCount;
+		uniform int pcfRadius;
+		out vec4 FragColor;
+
+		// Percentage-closer filtering over a (2r+1)^2 kernel on top of the hardware 2x2 compare
+		float shadowFactor(int light, vec3 normal, vec3 lightDir) {
+			vec4 lightSpace = lightViewProj[light] * vec4(fragPos, 1.0);
+			vec3 coords = lightSpace.xyz / lightSpace.w * 0.5 + 0.5;
+			if (coords.z > 1.0 || any(lessThan(coords.xy, vec2(0.0))) || any(greaterThan(coords.xy, vec2(1.0)))) {
+				return 1.0;
+			}
+			float bias = max(0.002 * (1.0 - dot(normal, lightDir)), 0.0005);
+			vec2 texel = 1.0 / vec2(textureSize(shadowMaps, 0).xy);
+			float lit = 0.0;
+			for (int x = -pcfRadius; x <= pcfRadius; ++x) {
+				for (int y = -pcfRadius; y <= pcfRadius; ++y) {
+					lit += texture(shadowMaps, vec4(coords.xy + vec2(x, y) * texel, float(light), coords.z - bias));
+				}
+			}
+			float taps = float((2 * pcfRadius + 1) * (2 * pcfRadius + 1));
+			return lit / taps;
+		}
+
+		void main() {
+			vec3 normal = normalize(fragNormal);
+			vec3 color = vec3(0.05, 0.05, 0.06);
+			for (int i = 0; i < lightCount; ++i) {
+				vec3 toLight = lightPos[i] - fragPos;
+				vec3 lightDir = normalize(toLight);
+				float diff = max(dot(normal, lightDir), 0.0);
+				float attenuation = 1.0 / (1.0 + 0.0005 * dot(toLight, toLight));
+				color += lightColor[i] * diff * attenuation * shadowFactor(i, normal, lightDir);
+			}
+			FragColor = vec4(pow(color, vec3(1.0 / 2.2)), 1.0);
+		}`)
+
+	scene := createScene()
+	sceneVertexCount = int32(len(scene) / 6)
+
+	gl.GenVertexArrays(1, &vao)
+	gl.BindVertexArray(vao)
+	gl.GenBuffers(1, &vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(scene)*4, gl.Ptr(scene), gl.STATIC_DRAW)
+	gl.EnableVertexAttribArray(0)
+	gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 6*4, gl.PtrOffset(0))
+	gl.EnableVertexAttribArray(1)
+	gl.VertexAttribPointer(1, 3, gl.FLOAT, false, 6*4, gl.PtrOffset(3*4))
+
+	gl.GenFramebuffers(1, &shadowFBO)
+}
+
+// (Re)create the shadow map array with one layer per light, clamped to what the driver supports
+func createShadowMaps(size int32, layers int) {
+	if shadowMaps != 0 {
+		gl.DeleteTextures(1, &shadowMaps)
+	}
+
+	var maxSize int32
+	gl.GetIntegerv(gl.MAX_TEXTURE_SIZE, &maxSize)
+	if size > maxSize {
+		size = maxSize
+	}
+	shadowMapSize = size
+
+	gl.GenTextures(1, &shadowMaps)
+	gl.BindTexture(gl.TEXTURE_2D_ARRAY, shadowMaps)
+	gl.TexImage3D(gl.TEXTURE_2D_ARRAY, 0, gl.DEPTH_COMPONENT32F, size, size, int32(layers), 0, gl.DEPTH_COMPONENT, gl.FLOAT, nil)
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_COMPARE_MODE, gl.COMPARE_REF_TO_TEXTURE)
+	gl.TexParameteri(gl.TEXTURE_2D_ARRAY, gl.TEXTURE_COMPARE_FUNC, gl.LEQUAL)
+}
+
+// Spot lights circling the scene at different heights and speeds
+func lightTransforms(count int, currentTime float32) ([]mgl32.Vec3, []mgl32.Mat4) {
+	positions := make([]mgl32.Vec3, count)
+	viewProjs := make([]mgl32.Mat4, count)
+	projection := mgl32.Perspective(mgl32.DegToRad(90.0), 1.0, 1.0, 150.0)
+	for i := 0; i < count; i++ {
+		angle := float64(currentTime)*(0.15+0.05*float64(i)) + float64(i)*2*math.Pi/float64(count)
+		radius := float32(25 + 5*(i%2))
+		positions[i] = mgl32.Vec3{radius * float32(math.Cos(angle)), 30 + float32(i)*3, radius * float32(math.Sin(angle))}
+		view := mgl32.LookAtV(positions[i], mgl32.Vec3{0, 0, 0}, mgl32.Vec3{0, 1, 0})
+		viewProjs[i] = projection.Mul4(view)
+	}
+	return positions, viewProjs
+}
+
+func drawShadows(stage shadowStage, currentTime float32) {
+	positions, viewProjs := lightTransforms(stage.lights, currentTime)
+
+	// Depth-only passes, one shadow map layer per light
+	gl.BindFramebuffer(gl.FRAMEBUFFER, shadowFBO)
+	gl.DrawBuffer(gl.NONE)
+	gl.ReadBuffer(gl.NONE)
+	gl.Viewport(0, 0, shadowMapSize, shadowMapSize)
+	gl.Enable(gl.DEPTH_TEST)
+	gl.Enable(gl.POLYGON_OFFSET_FILL)
+	gl.PolygonOffset(2.0, 4.0)
+	gl.UseProgram(depthProgram)
+	gl.BindVertexArray(vao)
+	lightViewProjLoc := gl.GetUniformLocation(depthProgram, gl.Str("lightViewProj\x00"))
+	for i := 0; i < stage.lights; i++ {
+		gl.FramebufferTextureLayer(gl.FRAMEBUFFER, gl.DEPTH_ATTACHMENT, shadowMaps, 0, int32(i))
+		gl.Clear(gl.DEPTH_BUFFER_BIT)
+		gl.UniformMatrix4fv(lightViewProjLoc, 1, false, &viewProjs[i][0])
+		gl.DrawArrays(gl.TRIANGLES, 0, sceneVertexCount)
+	}
+	gl.Disable(gl.POLYGON_OFFSET_FILL)
+
+	// Lit pass sampling every shadow map
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	gl.Viewport(0, 0, windowWidth, windowHeight)
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	gl.UseProgram(shaderProgram)
+
+	angle := float64(currentTime) * 0.05
+	eye := mgl32.Vec3{45 * float32(math.Cos(angle)), 25, 45 * float32(math.Sin(angle))}
+	projection := mgl32.Perspective(mgl32.DegToRad(45.0), float32(windowWidth)/windowHeight, 0.1, 200.0)
+	view := mgl32.LookAtV(eye, mgl32.Vec3{0, 0, 0}, mgl32.Vec3{0, 1, 0})
+	viewProj := projection.Mul4(view)
+	gl.UniformMatrix4fv(gl.GetUniformLocation(shaderProgram, gl.Str("viewProj\x00")), 1, false, &viewProj[0])
+
+	for i := 0; i < stage.lights; i++ {
+		index := strconv.Itoa(i)
+		gl.UniformMatrix4fv(gl.GetUniformLocation(shaderProgram, gl.Str("lightViewProj["+index+"]\x00")), 1, false, &viewProjs[i][0])
+		gl.Uniform3fv(gl.GetUniformLocation(shaderProgram, gl.Str("lightPos["+index+"]\x00")), 1, &positions[i][0])
+		gl.Uniform3fv(gl.GetUniformLocation(shaderProgram, gl.Str("lightColor["+index+"]\x00")), 1, &lightColors[i][0])
+	}
+	gl.Uniform1i(gl.GetUniformLocation(shaderProgram, gl.Str("lightCount\x00")), int32(stage.lights))
+	gl.Uniform1i(gl.GetUniformLocation(shaderProgram, gl.Str("pcfRadius\x00")), int32(stage.pcf/2))
+
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D_ARRAY, shadowMaps)
+	gl.Uniform1i(gl.GetUniformLocation(shaderProgram, gl.Str("shadowMaps\x00")), 0)
+
+	gl.DrawArrays(gl.TRIANGLES, 0, sceneVertexCount)
+}
+
+// Shadow map texels rendered per frame in millions
+func shadowMegatexels(stage shadowStage) float64 {
+	return float64(stage.lights) * float64(shadowMapSize) * float64(shadowMapSize) / 1e6
+}
+
+func checkGLError() {
+	if err := gl.GetError(); err != gl.NO_ERROR {
+		fmt.Printf("OpenGL error: %d\n", err)
+	}
+}
+
+func main() {
+	flag.Parse()
+
+	runtime.LockOSThread()
+	window := createWindow()
+	defer glfw.Terminate()
+	initGL()
+	gl.ClearColor(0.1, 0.1, 0.1, 1.0)
+
+	_, fileName := getWindowsInfo()
+	file, err := os.Create(fileName)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+	defer writer.Flush()
+
+	writer.Write([]string{"Time (s)", "Stage", "Shadow Megatexels", "Avg FPS", "Min FPS", "Lights", "Map Size", "PCF Kernel"})
+
+	stageCount, duration := testkit.Overrides(len(shadowStages), stageTime, testDuration)
+	shadowStages = shadowStages[:stageCount]
+
+	testkit.WatchStop()
+
+	// Announced for the progress display of the runner
+	fmt.Printf("Duration: %.0fs, Stages: %d\n", duration, len(shadowStages))
+
+	// Warming
+	fmt.Println("Warming up...")
+	warmUpStart := time.Now()
+	createShadowMaps(shadowStages[0].mapSize, shadowStages[0].lights)
+	for time.Since(warmUpStart).Seconds() < warmUpTime && !testkit.StopRequested() {
+		drawShadows(shadowStages[0], float32(time.Since(warmUpStart).Seconds()))
+		window.SwapBuffers()
+		glfw.PollEvents()
+		checkGLError()
+	}
+
+	// Main test
+	startTime = time.Now()
+	testStart := startTime
+	lastRecordTime := testStart
+	var frameTimes []float64
+	currentStage := 0
+
+	for !window.ShouldClose() && !testkit.StopRequested() && time.Since(testStart).Seconds() < duration {
+		frameStart := time.Now()
+
+		currentTime := float32(time.Since(startTime).Seconds())
+		drawShadows(shadowStages[currentStage], currentTime)
+		window.SwapBuffers()
+		glfw.PollEvents()
+		checkGLError()
+
+		frameTime := time.Since(frameStart).Seconds()
+		frameTimes = append(frameTimes, frameTime)
+
+		timeElapsed := time.Since(testStart).Seconds()
+		newStage := int(timeElapsed / stageTime)
+		if newStage != currentStage && newStage < len(shadowStages) {
+			currentStage = newStage
+			createShadowMaps(shadowStages[currentStage].mapSize, shadowStages[currentStage].lights)
+			fmt.Printf("\nStarting stage %d with %d lights, %dx%d shadow maps, %dx%d PCF\n",
+				currentStage+1, shadowStages[currentStage].lights, shadowMapSize, shadowMapSize,
+				shadowStages[currentStage].pcf, shadowStages[currentStage].pcf)
+		}
+
+		if time.Since(lastRecordTime).Seconds() >= 0.5 {
+			var totalFrameTime float64
+			for _, ft := range frameTimes {
+				totalFrameTime += ft
+			}
+			avgFPS := float64(len(frameTimes)) / totalFrameTime
+			minFPS := 1.0 / maxFrameTime(frameTimes)
+			stage := shadowStages[currentStage]
+			megatexels := shadowMegatexels(stage)
+
+			writer.Write([]string{
+				strconv.FormatFloat(timeElapsed, 'f', 1, 64),
+				strconv.Itoa(currentStage + 1),
+				strconv.FormatFloat(megatexels, 'f', 2, 64),
+				strconv.FormatFloat(avgFPS, 'f', 1, 64),
+				strconv.FormatFloat(minFPS, 'f', 1, 64),
+				strconv.Itoa(stage.lights),
+				strconv.Itoa(int(shadowMapSize)),
+				strconv.Itoa(stage.pcf),
+			})
+			writer.Flush()
+
+			fmt.Printf("Time: %.1fs, Stage: %d, Shadow Megatexels: %.2f, Avg FPS: %.1f, Min FPS: %.1f\n",
+				timeElapsed, currentStage+1, megatexels, avgFPS, minFPS)
+
+			frameTimes = nil
+			lastRecordTime = time.Now()
+		}
+	}
+
+	// Screenshot of the last stage for the report, drawn once more outside the measured frames
+	drawShadows(shadowStages[currentStage], float32(time.Since(startTime).Seconds()))
+	testkit.SaveScreenshot(window, "shadows.png")
+
+	//notifyWindows("Shadow Mapping Benchmark", "Test completed. Data saved in "+fileName)
+}
+
+func maxFrameTime(frameTimes []float64) float64 {
+	max := frameTimes[0]
+	for _, ft := range frameTimes {
+		if ft > max {
+			max = ft
+		}
+	}
+	return max
+}
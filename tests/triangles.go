@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/csv"
+	"flag"
 	"fmt"
 	"math/rand"
 	"os"
@@ -14,6 +15,8 @@ import (
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/glfw/v3.3/glfw"
 	"github.com/go-gl/mathgl/mgl32"
+
+	"moddergltest/testkit"
 )
 
 const (
@@ -29,6 +32,13 @@ var (
 	shaderProgram uint32
 	startTime     time.Time
 	particleCounts = []int{10000, 50000, 100000, 500000, 1000000, 10000000}
+
+	// GPU time of the draw, read back only once the driver has it so the query never stalls
+	gpuTimer *testkit.GPUTimer
+
+	// The geometry goes to the GPU again every frame; what that costs on the CPU
+	uploadBytes   float64
+	uploadSeconds float64
 )
 
 func getWindowsInfo() (string, string) {
@@ -156,16 +166,21 @@ func initGL() {
 	gl.GenVertexArrays(1, &vao)
 	gl.GenBuffers(1, &vbo)
 	gl.GenBuffers(1, &ebo)
+	gpuTimer = testkit.NewGPUTimer(1)
 }
 
 func drawGeometry(vertices []float32, indices []uint32, currentTime float32) {
+    gpuTimer.Begin("draw")
     gl.BindVertexArray(vao)
 
+    uploadStart := time.Now()
     gl.BindBuffer(gl.ARRAY_BUFFER, vbo)
     gl.BufferData(gl.ARRAY_BUFFER, len(vertices)*4, gl.Ptr(vertices), gl.STATIC_DRAW)
 
     gl.BindBuffer(gl.ELEMENT_ARRAY_BUFFER, ebo)
     gl.BufferData(gl.ELEMENT_ARRAY_BUFFER, len(indices)*4, gl.Ptr(indices), gl.STATIC_DRAW)
+    uploadSeconds += time.Since(uploadStart).Seconds()
+    uploadBytes += float64(len(vertices)*4 + len(indices)*4)
 
     gl.EnableVertexAttribArray(0)
     gl.VertexAttribPointer(0, 3, gl.FLOAT, false, 3*4, gl.PtrOffset(0))
@@ -189,6 +204,7 @@ func drawGeometry(vertices []float32, indices []uint32, currentTime float32) {
     gl.DrawElements(gl.TRIANGLES, int32(len(indices)), gl.UNSIGNED_INT, gl.PtrOffset(0))
 
     gl.DisableVertexAttribArray(0)
+    gpuTimer.End()
 }
 
 func checkGLError() {
@@ -198,6 +214,8 @@ func checkGLError() {
 }
 
 func main() {
+	flag.Parse()
+
 	runtime.LockOSThread()
 	window := createWindow()
 	defer glfw.Terminate()
@@ -213,24 +231,37 @@ func main() {
 	writer := csv.NewWriter(file)
 	defer writer.Flush()
 
-	writer.Write([]string{"Time (s)", "Stage", "Points", "Avg FPS", "Min FPS"})
+	// CPU time is the frame up to SwapBuffers, what the test and the driver did before waiting
+	// for the GPU or the display; upload is the part of it spent in BufferData
+	writer.Write([]string{"Time (s)", "Stage", "Points", "Avg FPS", "Min FPS",
+		"GPU Time (ms)", "CPU Time (ms)", "Upload (MB)", "Upload Time (ms)"})
+
+	stageCount, duration := testkit.Overrides(len(particleCounts), stageTime, testDuration)
+	particleCounts = particleCounts[:stageCount]
+
+	testkit.WatchStop()
+
+	// Announced for the progress display of the runner
+	fmt.Printf("Duration: %.0fs, Stages: %d\n", duration, len(particleCounts))
 
 	startTime = time.Now()
 	testStart := startTime
 	lastRecordTime := testStart
-	var frameTimes []float64
+	var frameTimes, cpuTimes []float64
 	currentStage := 0
 
 	vertices, indices := createGeometry(particleCounts[currentStage])
 
-	for !window.ShouldClose() && time.Since(testStart).Seconds() < testDuration {
+	for !window.ShouldClose() && !testkit.StopRequested() && time.Since(testStart).Seconds() < duration {
 		frameStart := time.Now()
 
 		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
 		currentTime := float32(time.Since(startTime).Seconds())
 		drawGeometry(vertices, indices, currentTime)
+		cpuTimes = append(cpuTimes, time.Since(frameStart).Seconds())
 		window.SwapBuffers()
 		glfw.PollEvents()
+		gpuTimer.EndFrame()
 		checkGLError()
 
 		frameTime := time.Since(frameStart).Seconds()
@@ -251,6 +282,12 @@ func main() {
 			}
 			avgFPS := float64(len(frameTimes)) / totalFrameTime
 			minFPS := 1.0 / maxFrameTime(frameTimes)
+			var totalCPUTime float64
+			for _, ct := range cpuTimes {
+				totalCPUTime += ct
+			}
+			frames := float64(len(frameTimes))
+			gpuTime, _ := gpuTimer.Mean()
 
 			writer.Write([]string{
 				strconv.FormatFloat(timeElapsed, 'f', 1, 64),
@@ -258,17 +295,28 @@ func main() {
 				strconv.Itoa(particleCounts[currentStage]),
 				strconv.FormatFloat(avgFPS, 'f', 1, 64),
 				strconv.FormatFloat(minFPS, 'f', 1, 64),
+				strconv.FormatFloat(gpuTime, 'f', 3, 64),
+				strconv.FormatFloat(totalCPUTime/frames*1000, 'f', 3, 64),
+				strconv.FormatFloat(uploadBytes/frames/(1024*1024), 'f', 2, 64),
+				strconv.FormatFloat(uploadSeconds/frames*1000, 'f', 3, 64),
 			})
 			writer.Flush()
 
 			fmt.Printf("Time: %.1fs, Stage: %d, Points: %d, Avg FPS: %.1f, Min FPS: %.1f\n",
 				timeElapsed, currentStage+1, particleCounts[currentStage], avgFPS, minFPS)
 
-			frameTimes = nil
+			frameTimes, cpuTimes = nil, nil
+			gpuTimer.Reset()
+			uploadBytes, uploadSeconds = 0, 0
 			lastRecordTime = time.Now()
 		}
 	}
 
+	// Screenshot of the last stage for the report, drawn once more outside the measured frames
+	gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
+	drawGeometry(vertices, indices, float32(time.Since(startTime).Seconds()))
+	testkit.SaveScreenshot(window, "triangles.png")
+
 	//notifyWindows("Geometry Benchmark", "Тест завершен. Данные сохранены в "+fileName)
 }
 
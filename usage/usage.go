@@ -0,0 +1,106 @@
+// Package usage follows what a test process takes of the machine: CPU time in user and kernel
+// mode, resident memory, context switches and page faults, by stage of the test.
+package usage
+
+import "time"
+
+// Usage is a reading of the counters of a process since it started. Counters a system does
+// not keep are zero: Windows has no context switches per process and does not split page
+// faults into minor and major, they all count as minor.
+type Usage struct {
+	UserSec             float64
+	SysSec              float64
+	RSSMB               float64 // resident now
+	PeakRSSMB           float64 // highest resident since the process started
+	VoluntarySwitches   float64 // the process waited, e.g. for the driver
+	InvoluntarySwitches float64 // the scheduler took the CPU away
+	MinorFaults         float64
+	MajorFaults         float64 // had to read from disk
+}
+
+// Stage is what a process used over one stage of a test. Counters are the differences over the
+// stage, PeakRSSMB the highest resident memory seen in it.
+type Stage struct {
+	Stage   int
+	Seconds float64 // wall time the counters cover
+	Usage
+}
+
+// Reading the process and the clock, replaced in tests
+var (
+	readProcess = Of
+	now         = time.Now
+)
+
+// Tracker reads a process at the start of every stage and at every sample of it
+type Tracker struct {
+	pid     int
+	stage   int
+	started time.Time
+	start   Usage // at the start of the current stage, valid when ok
+	ok      bool
+	peakMB  float64 // highest resident memory seen in the current stage
+	last    Usage   // latest reading, valid when read
+	lastAt  time.Time
+	read    bool
+	stages  []Stage
+}
+
+// NewTracker follows the process with the given ID
+func NewTracker(pid int) *Tracker {
+	return &Tracker{pid: pid}
+}
+
+// StartStage closes the current stage and starts the given one
+func (t *Tracker) StartStage(stage int) {
+	t.close()
+	t.stage, t.started = stage, now()
+	t.start, t.ok = readProcess(t.pid)
+	t.peakMB = t.start.RSSMB
+	if t.ok {
+		t.last, t.lastAt, t.read = t.start, t.started, true
+	}
+}
+
+// Sample reads the process at a sample of the given stage, starting it when it is new
+func (t *Tracker) Sample(stage int) {
+	if stage != t.stage || t.started.IsZero() {
+		t.StartStage(stage)
+	}
+	if reading, ok := readProcess(t.pid); ok {
+		t.last, t.lastAt, t.read = reading, now(), true
+		t.peakMB = max(t.peakMB, reading.RSSMB)
+	}
+}
+
+// Last is the latest reading of the process, false before the first one
+func (t *Tracker) Last() (Usage, bool) {
+	return t.last, t.read
+}
+
+// Stages closes the current stage and returns every stage with readings over some time
+func (t *Tracker) Stages() []Stage {
+	t.close()
+	return t.stages
+}
+
+func (t *Tracker) close() {
+	if !t.ok || !t.lastAt.After(t.started) {
+		return
+	}
+	t.stages = append(t.stages, Stage{
+		Stage:   t.stage,
+		Seconds: t.lastAt.Sub(t.started).Seconds(),
+		Usage: Usage{
+			UserSec:             t.last.UserSec - t.start.UserSec,
+			SysSec:              t.last.SysSec - t.start.SysSec,
+			RSSMB:               t.last.RSSMB,
+			PeakRSSMB:           t.peakMB,
+			VoluntarySwitches:   t.last.VoluntarySwitches - t.start.VoluntarySwitches,
+			InvoluntarySwitches: t.last.InvoluntarySwitches - t.start.InvoluntarySwitches,
+			MinorFaults:         t.last.MinorFaults - t.start.MinorFaults,
+			MajorFaults:         t.last.MajorFaults - t.start.MajorFaults,
+		},
+	})
+	t.ok = false
+}
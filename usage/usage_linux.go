@@ -0,0 +1,68 @@
+package usage
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Clock ticks per second of /proc/<pid>/stat
+const userHZ = 100
+
+// Of reads the counters of a running process from /proc/<pid>/stat and /proc/<pid>/status
+func Of(pid int) (Usage, bool) {
+	dir := filepath.Join("/proc", strconv.Itoa(pid))
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return Usage{}, false
+	}
+	// The command name in parentheses may hold spaces, the fields after it do not:
+	// state ppid pgrp session tty_nr tpgid flags minflt cminflt majflt cmajflt utime stime
+	end := strings.LastIndexByte(string(stat), ')')
+	if end < 0 {
+		return Usage{}, false
+	}
+	fields := strings.Fields(string(stat[end+1:]))
+	// An exited process that was not waited for yet has no memory left to read
+	if len(fields) < 13 || fields[0] == "Z" {
+		return Usage{}, false
+	}
+	number := func(i int) float64 {
+		value, _ := strconv.ParseFloat(fields[i], 64)
+		return value
+	}
+	u := Usage{
+		MinorFaults: number(7),
+		MajorFaults: number(9),
+		UserSec:     number(11) / userHZ,
+		SysSec:      number(12) / userHZ,
+	}
+
+	status, err := os.ReadFile(filepath.Join(dir, "status"))
+	if err != nil {
+		return u, true
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		// Sizes are in kB
+		number, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), " kB"), 64)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "VmRSS":
+			u.RSSMB = number / 1024
+		case "VmHWM":
+			u.PeakRSSMB = number / 1024
+		case "voluntary_ctxt_switches":
+			u.VoluntarySwitches = number
+		case "nonvoluntary_ctxt_switches":
+			u.InvoluntarySwitches = number
+		}
+	}
+	return u, true
+}
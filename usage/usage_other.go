@@ -0,0 +1,8 @@
+//go:build !linux && !windows
+
+package usage
+
+// Of has no source of process counters on this system
+func Of(pid int) (Usage, bool) {
+	return Usage{}, false
+}
@@ -0,0 +1,88 @@
+package usage
+
+import (
+	"testing"
+	"time"
+)
+
+// Replaces the process with the given readings, one per read, and moves the clock on by half a
+// second at every reading of it
+func useReadings(t *testing.T, readings ...Usage) {
+	t.Helper()
+	savedRead, savedNow := readProcess, now
+	clock := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	readProcess = func(pid int) (Usage, bool) {
+		if len(readings) == 0 {
+			return Usage{}, false
+		}
+		reading := readings[0]
+		readings = readings[1:]
+		return reading, true
+	}
+	now = func() time.Time {
+		clock = clock.Add(500 * time.Millisecond)
+		return clock
+	}
+	t.Cleanup(func() { readProcess, now = savedRead, savedNow })
+}
+
+func TestTrackerStages(t *testing.T) {
+	useReadings(t,
+		// Stage 1: its start and two samples, memory peaking in between
+		Usage{UserSec: 1, SysSec: 0.5, RSSMB: 100, PeakRSSMB: 100, VoluntarySwitches: 10, InvoluntarySwitches: 2, MinorFaults: 1000, MajorFaults: 3},
+		Usage{UserSec: 1.5, SysSec: 0.75, RSSMB: 180, PeakRSSMB: 180, VoluntarySwitches: 40, InvoluntarySwitches: 4, MinorFaults: 1500, MajorFaults: 3},
+		Usage{UserSec: 2, SysSec: 1, RSSMB: 150, PeakRSSMB: 180, VoluntarySwitches: 70, InvoluntarySwitches: 5, MinorFaults: 1600, MajorFaults: 5},
+		// Stage 2: its start and one sample, below the peak of the process
+		Usage{UserSec: 2.25, SysSec: 1, RSSMB: 140, PeakRSSMB: 180, VoluntarySwitches: 75, InvoluntarySwitches: 5, MinorFaults: 1650, MajorFaults: 5},
+		Usage{UserSec: 3.25, SysSec: 1.5, RSSMB: 160, PeakRSSMB: 180, VoluntarySwitches: 95, InvoluntarySwitches: 9, MinorFaults: 1700, MajorFaults: 5},
+	)
+	tracker := NewTracker(42)
+	tracker.StartStage(1)
+	tracker.Sample(1)
+	tracker.Sample(1)
+	tracker.Sample(2) // starts stage 2, which closes stage 1
+
+	want := []Stage{
+		{Stage: 1, Seconds: 1, Usage: Usage{UserSec: 1, SysSec: 0.5, RSSMB: 150, PeakRSSMB: 180,
+			VoluntarySwitches: 60, InvoluntarySwitches: 3, MinorFaults: 600, MajorFaults: 2}},
+		{Stage: 2, Seconds: 0.5, Usage: Usage{UserSec: 1, SysSec: 0.5, RSSMB: 160, PeakRSSMB: 160,
+			VoluntarySwitches: 20, InvoluntarySwitches: 4, MinorFaults: 50}},
+	}
+	stages := tracker.Stages()
+	if len(stages) != len(want) {
+		t.Fatalf("%d stages, want %d: %+v", len(stages), len(want), stages)
+	}
+	for i := range want {
+		if stages[i] != want[i] {
+			t.Errorf("stage %d\n%+v\nwant\n%+v", want[i].Stage, stages[i], want[i])
+		}
+	}
+	if last, ok := tracker.Last(); !ok || last.UserSec != 3.25 {
+		t.Errorf("Last() = %+v, %v, want the latest reading", last, ok)
+	}
+	// Closing again adds nothing
+	if len(tracker.Stages()) != 2 {
+		t.Errorf("stages %+v after closing twice", tracker.Stages())
+	}
+}
+
+func TestTrackerUnreadable(t *testing.T) {
+	cases := []struct {
+		name     string
+		readings []Usage
+	}{
+		{"process gone before the stage", nil},
+		{"no sample after the start of the stage", []Usage{{UserSec: 1}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			useReadings(t, c.readings...)
+			tracker := NewTracker(42)
+			tracker.StartStage(1)
+			tracker.Sample(1)
+			if stages := tracker.Stages(); len(stages) != 0 {
+				t.Errorf("stages %+v, want none", stages)
+			}
+		})
+	}
+}
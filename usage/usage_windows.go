@@ -0,0 +1,53 @@
+package usage
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// Access enough to read times and memory counters of another process
+const processQueryLimitedInformation = 0x1000
+
+// PROCESS_MEMORY_COUNTERS of psapi
+type processMemoryCounters struct {
+	cb                         uint32
+	PageFaultCount             uint32
+	PeakWorkingSetSize         uintptr
+	WorkingSetSize             uintptr
+	QuotaPeakPagedPoolUsage    uintptr
+	QuotaPagedPoolUsage        uintptr
+	QuotaPeakNonPagedPoolUsage uintptr
+	QuotaNonPagedPoolUsage     uintptr
+	PagefileUsage              uintptr
+	PeakPagefileUsage          uintptr
+}
+
+var getProcessMemoryInfo = syscall.NewLazyDLL("psapi.dll").NewProc("GetProcessMemoryInfo")
+
+// Of reads the CPU times and the working set of a running process
+func Of(pid int) (Usage, bool) {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return Usage{}, false
+	}
+	defer syscall.CloseHandle(handle)
+
+	var creation, exit, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(handle, &creation, &exit, &kernel, &user); err != nil {
+		return Usage{}, false
+	}
+	// Filetimes count 100 ns
+	u := Usage{
+		UserSec: float64(int64(user.HighDateTime)<<32|int64(user.LowDateTime)) / 1e7,
+		SysSec:  float64(int64(kernel.HighDateTime)<<32|int64(kernel.LowDateTime)) / 1e7,
+	}
+
+	var counters processMemoryCounters
+	counters.cb = uint32(unsafe.Sizeof(counters))
+	if ret, _, _ := getProcessMemoryInfo.Call(uintptr(handle), uintptr(unsafe.Pointer(&counters)), uintptr(counters.cb)); ret != 0 {
+		u.RSSMB = float64(counters.WorkingSetSize) / (1024 * 1024)
+		u.PeakRSSMB = float64(counters.PeakWorkingSetSize) / (1024 * 1024)
+		u.MinorFaults = float64(counters.PageFaultCount)
+	}
+	return u, true
+}